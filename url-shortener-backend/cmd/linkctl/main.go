@@ -0,0 +1,221 @@
+// Command linkctl exports and imports links through the API, for moving them
+// between environments or in from other shorteners.
+//
+//	LINKCTL_API_KEY=usk_... go run ./cmd/linkctl export -format ndjson -o links.ndjson
+//	LINKCTL_API_URL=https://new.example.com LINKCTL_API_KEY=usk_... go run ./cmd/linkctl import links.ndjson
+//
+// Authenticate with an API key (LINKCTL_API_KEY, scopes links:read and
+// links:write) or an access token (LINKCTL_TOKEN).
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"url-shortener/internal/linkfile"
+	"url-shortener/internal/model"
+)
+
+const pollInterval = time.Second
+
+type client struct {
+	baseURL string
+	apiKey  string
+	token   string
+	http    *http.Client
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	c := &client{
+		baseURL: strings.TrimRight(getEnv("LINKCTL_API_URL", "http://localhost:2345"), "/"),
+		apiKey:  os.Getenv("LINKCTL_API_KEY"),
+		token:   os.Getenv("LINKCTL_TOKEN"),
+		http:    &http.Client{},
+	}
+	if c.apiKey == "" && c.token == "" {
+		fail("set LINKCTL_API_KEY or LINKCTL_TOKEN")
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = c.export(os.Args[2:])
+	case "import":
+		err = c.importFile(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fail(err.Error())
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage:
+  linkctl export [-format csv|ndjson] [-o file]
+  linkctl import [-format csv|ndjson] [-on-conflict skip|rename] [-no-wait] file`)
+	os.Exit(2)
+}
+
+func fail(message string) {
+	fmt.Fprintln(os.Stderr, "linkctl:", message)
+	os.Exit(1)
+}
+
+// export streams the caller's links to a file or stdout
+func (c *client) export(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", linkfile.FormatCSV, "csv or ndjson")
+	output := flags.String("o", "", "output file (default stdout)")
+	flags.Parse(args)
+
+	resp, err := c.do(http.MethodGet, "/api/urls/export?format="+url.QueryEscape(*format), "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	out := os.Stdout
+	if *output != "" {
+		if out, err = os.Create(*output); err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+	n, err := io.Copy(out, resp.Body)
+	if err != nil {
+		return err
+	}
+	if *output != "" {
+		fmt.Fprintf(os.Stderr, "Exported %d bytes to %s\n", n, *output)
+	}
+	return nil
+}
+
+// importFile uploads a file and, unless -no-wait is given, follows the job
+// until it finishes
+func (c *client) importFile(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "", "csv or ndjson (default from the file extension)")
+	onConflict := flags.String("on-conflict", "skip", "skip or rename links whose short code is taken")
+	noWait := flags.Bool("no-wait", false, "print the job and exit without waiting for it")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		usage()
+	}
+	path := flags.Arg(0)
+
+	if *format == "" {
+		*format = filepath.Ext(path)
+	}
+	parsed, err := linkfile.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	query := url.Values{"format": {parsed}, "on_conflict": {*onConflict}}
+	resp, err := c.do(http.MethodPost, "/api/urls/imports?"+query.Encode(), linkfile.ContentType(parsed), file)
+	if err != nil {
+		return err
+	}
+	job, err := decodeJob(resp)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Started import %d (%d bytes)\n", job.ID, job.FileSize)
+	if *noWait {
+		return json.NewEncoder(os.Stdout).Encode(job)
+	}
+
+	for job.Status == model.ImportStatusPending || job.Status == model.ImportStatusRunning {
+		time.Sleep(pollInterval)
+		resp, err := c.do(http.MethodGet, fmt.Sprintf("/api/urls/imports/%d", job.ID), "", nil)
+		if err != nil {
+			return err
+		}
+		if job, err = decodeJob(resp); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "\r%-9s %3d%%  %d rows", job.Status, job.Progress, job.Rows)
+	}
+	fmt.Fprintln(os.Stderr)
+
+	for _, rowErr := range job.Errors {
+		fmt.Fprintf(os.Stderr, "line %d %s: %s (%s)\n", rowErr.Line, rowErr.ShortCode, rowErr.Error, rowErr.ErrorCode)
+	}
+	if shown := len(job.Errors); shown < job.Conflicts+job.Failed {
+		fmt.Fprintf(os.Stderr, "... and %d more\n", job.Conflicts+job.Failed-shown)
+	}
+	fmt.Fprintf(os.Stderr, "Imported %d, renamed %d, conflicts %d, failed %d\n", job.Imported, job.Renamed, job.Conflicts, job.Failed)
+
+	if job.Status == model.ImportStatusFailed {
+		return errors.New("import failed: " + job.Error)
+	}
+	return nil
+}
+
+// do sends an authenticated request and turns error responses into errors
+func (c *client) do(method, path, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) != nil || apiErr.Error == "" {
+			apiErr.Error = resp.Status
+		}
+		return nil, fmt.Errorf("%s %s: %s", method, path, apiErr.Error)
+	}
+	return resp, nil
+}
+
+func decodeJob(resp *http.Response) (*model.ImportJob, error) {
+	defer resp.Body.Close()
+	var job model.ImportJob
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,34 @@
+// Command mockoidc is a minimal OpenID Connect provider for trying out and
+// testing the sign-in flow locally. It signs in whoever asks, so never
+// expose it outside a development machine.
+//
+//	go run ./cmd/mockoidc
+//	OIDC_PROVIDERS=mock OIDC_MOCK_ISSUER=http://localhost:9999 OIDC_MOCK_CLIENT_ID=url-shortener go run ./cmd/server
+//
+// Adding login_hint=<email> to the authorization request skips the login form.
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"url-shortener/internal/mockoidc"
+)
+
+func main() {
+	addr := getEnv("MOCK_OIDC_ADDR", ":9999")
+	p, err := mockoidc.New(getEnv("MOCK_OIDC_ISSUER", "http://localhost:9999"), getEnv("MOCK_OIDC_CLIENT_ID", "url-shortener"))
+	if err != nil {
+		log.Fatal("Failed to generate signing key:", err)
+	}
+
+	log.Printf("Mock OIDC issuer %s (client %s) listening on %s", p.Issuer(), p.ClientID(), addr)
+	log.Fatal(http.ListenAndServe(addr, p))
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
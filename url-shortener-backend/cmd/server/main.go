@@ -1,13 +1,23 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 	"url-shortener/config"
 	_ "url-shortener/docs" // Import generated docs
+	"url-shortener/internal/cache"
 	"url-shortener/internal/handler"
+	"url-shortener/internal/loginguard"
+	"url-shortener/internal/mailer"
 	"url-shortener/internal/middleware"
+	"url-shortener/internal/model"
 	"url-shortener/internal/repository"
 	"url-shortener/internal/service"
 
@@ -36,21 +46,100 @@ import (
 // @name                        Authorization
 // @description                 Type "Bearer" followed by a space and JWT token.
 
+// @securityDefinitions.apikey  ApiKeyAuth
+// @in                          header
+// @name                        X-API-Key
+// @description                 Personal API key (usk_...) with the scope the endpoint needs.
+
 func main() {
 	// Initialize database
 	db := config.InitDB()
 
+	// Token signing keys (HS256 secret, or RS256/EdDSA keys from JWT_KEY_DIR)
+	jwtConfig := config.LoadJWTConfig()
+	if err := middleware.InitJWT(jwtConfig); err != nil {
+		log.Fatal("Failed to initialize JWT signing:", err)
+	}
+	go middleware.WatchJWTKeys(context.Background(), jwtConfig.ReloadInterval)
+
 	// Initialize repositories
 	urlRepo := repository.NewURLRepository(db)
+
+	// Serve redirect lookups through a read-through cache
+	cacheConfig := config.LoadCacheConfig()
+	if urlCache := newURLCache(cacheConfig); urlCache != nil {
+		urlRepo = repository.NewCachedURLRepository(urlRepo, urlCache, cacheConfig.TTL, cacheConfig.NegativeTTL)
+	}
 	userRepo := repository.NewUserRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	userTokenRepo := repository.NewUserTokenRepository(db)
+	recoveryCodeRepo := repository.NewRecoveryCodeRepository(db)
+	loginLockoutRepo := repository.NewLoginLockoutRepository(db)
+	userIdentityRepo := repository.NewUserIdentityRepository(db)
+	linkClaimRepo := repository.NewLinkClaimRepository(db)
+	importJobRepo := repository.NewImportJobRepository(db)
+	analyticsRepo := repository.NewAnalyticsRepository(db)
 
 	// Initialize services
-	urlService := service.NewURLService(urlRepo)
+	// Click counts are aggregated in memory and written in batches
+	clickAggregator := service.NewClickAggregator(urlRepo, config.LoadClickCounterConfig())
+	clickAggregator.Start()
+
+	urlConfig := config.LoadURLConfig()
+	anonymousConfig := config.LoadAnonymousConfig(jwtConfig)
+	urlService := service.NewURLService(urlRepo, linkClaimRepo, urlConfig, anonymousConfig, clickAggregator)
 	userService := service.NewUserService(userRepo)
+	// Disabled and deleted users lose access without waiting for their tokens to expire
+	middleware.CheckUserStatus(userService)
+	tokenService := service.NewTokenService(refreshTokenRepo, userRepo, middleware.JWTIssuer{})
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, userRepo)
+	accountConfig := config.LoadAccountConfig()
+	accountService := service.NewAccountService(userRepo, userTokenRepo, tokenService, newMailer(config.LoadMailConfig()), accountConfig)
+	mfaService := service.NewMFAService(userRepo, recoveryCodeRepo, middleware.JWTIssuer{}, accountConfig)
+	profileService := service.NewProfileService(userRepo, urlRepo, tokenService, accountService)
+	oidcConfig := config.LoadOIDCConfig()
+	if oidcConfig.StateSecret == "" {
+		oidcConfig.StateSecret = jwtConfig.Secret
+	}
+	oidcService := service.NewOIDCService(userRepo, userIdentityRepo, userTokenRepo, oidcConfig)
+	anonymousIdentities := service.NewAnonymousIdentityService(anonymousConfig)
+	loginGuardConfig := config.LoadLoginGuardConfig()
+	loginGuard := service.NewLoginGuard(newLoginGuardStore(loginGuardConfig), loginLockoutRepo, loginGuardConfig)
+
+	// Make sure the configured admin account exists
+	if err := userService.BootstrapAdmin(config.LoadAdminConfig()); err != nil {
+		log.Fatal("Failed to bootstrap admin account:", err)
+	}
+	// Click events are queued and inserted in batches by a single writer
+	analyticsConfig := config.LoadAnalyticsConfig()
+	clickEventWriter := service.NewClickEventWriter(analyticsRepo, analyticsConfig)
+	clickEventWriter.Start()
+	analyticsService := service.NewAnalyticsService(analyticsRepo, urlRepo, analyticsConfig, clickEventWriter)
+
+	// Imports run in this process, so jobs left unfinished by the last run will never complete
+	importService := service.NewImportService(importJobRepo, urlService, config.LoadImportConfig())
+	if n, err := importService.FailInterrupted(); err != nil {
+		log.Println("Failed to mark interrupted imports:", err)
+	} else if n > 0 {
+		log.Printf("Marked %d interrupted import(s) as failed", n)
+	}
+
+	// Mark (and optionally purge) links past their expiry date or click budget,
+	// and drop expired refresh tokens
+	expirySweeper := service.NewExpirySweeper(urlService, tokenService, urlConfig.ExpirySweepInterval, urlConfig.ExpiredLinkRetention)
+	go expirySweeper.Run(context.Background())
 
 	// Initialize handlers
-	urlHandler := handler.NewURLHandler(urlService)
-	authHandler := handler.NewAuthHandler(userService, urlService)
+	urlHandler := handler.NewURLHandler(urlService, analyticsService, anonymousIdentities, loginGuard)
+	analyticsHandler := handler.NewAnalyticsHandler(analyticsService)
+	authHandler := handler.NewAuthHandler(userService, urlService, tokenService, accountService, mfaService, loginGuard)
+	mfaHandler := handler.NewMFAHandler(mfaService)
+	profileHandler := handler.NewProfileHandler(profileService, tokenService)
+	oidcHandler := handler.NewOIDCHandler(oidcService, authHandler)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+	adminHandler := handler.NewAdminHandler(urlService, userService, tokenService, loginGuard)
+	importHandler := handler.NewImportHandler(importService)
 
 	// Setup router
 	r := gin.Default()
@@ -64,7 +153,7 @@ func main() {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     allowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Link-Password", "X-API-Key", "X-Anonymous-Token"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * 3600,
@@ -73,10 +162,18 @@ func main() {
 	// Swagger documentation
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Public keys for services that verify our tokens
+	r.GET("/.well-known/jwks.json", handler.JWKS)
+
 	// Public routes (no auth required)
 	r.GET("/:code", urlHandler.RedirectURL) // Redirect route
+	r.POST("/:code", urlHandler.UnlockURL)  // Unlock form for password-protected links
 	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
+		c.JSON(200, gin.H{
+			"status":        "ok",
+			"click_counter": clickAggregator.Stats(),
+			"click_events":  clickEventWriter.Stats(),
+		})
 	})
 
 	// API routes
@@ -87,21 +184,90 @@ func main() {
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/login/mfa", authHandler.LoginMFA)
 			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/logout", authHandler.Logout)
+			auth.POST("/password/forgot", authHandler.ForgotPassword)
+			auth.POST("/password/reset", authHandler.ResetPassword)
+			auth.POST("/email/verify", authHandler.VerifyEmail)
+			auth.GET("/oidc/providers", oidcHandler.ListOIDCProviders)
+			auth.GET("/oidc/:provider/login", oidcHandler.OIDCLogin)
+			auth.GET("/oidc/:provider/callback", oidcHandler.OIDCCallback)
+			auth.POST("/oidc/exchange", oidcHandler.OIDCExchange)
 
 			// Protected: requires JWT authentication
 			authProtected := auth.Group("")
 			authProtected.Use(middleware.RequireJWT())
 			{
-				authProtected.POST("/claim-links", authHandler.ClaimLinks)
+				authProtected.GET("/claim-links", middleware.AnonymousIdentity(anonymousIdentities), authHandler.PreviewClaimLinks)
+				authProtected.POST("/claim-links", middleware.AnonymousIdentity(anonymousIdentities), authHandler.ClaimLinks)
+				authProtected.POST("/logout-all", authHandler.LogoutAll)
+				authProtected.POST("/email/resend", authHandler.ResendVerification)
+				authProtected.GET("/mfa", mfaHandler.GetMFAStatus)
+				authProtected.POST("/mfa/totp/setup", mfaHandler.SetupTOTP)
+				authProtected.POST("/mfa/totp/confirm", mfaHandler.ConfirmTOTP)
+				authProtected.POST("/mfa/totp/disable", mfaHandler.DisableTOTP)
+				authProtected.POST("/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
 			}
 		}
 
+		// Account self-service (JWT only)
+		me := api.Group("/me")
+		me.Use(middleware.RequireJWT())
+		{
+			me.GET("", profileHandler.GetMe)
+			me.PATCH("", profileHandler.UpdateMe)
+			me.DELETE("", profileHandler.DeleteMe)
+			me.POST("/password", profileHandler.ChangePassword)
+			me.GET("/export", profileHandler.ExportMe)
+		}
+
+		// API key management (JWT only - an API key cannot mint more keys)
+		keys := api.Group("/keys")
+		keys.Use(middleware.RequireJWT())
+		{
+			keys.POST("", apiKeyHandler.CreateAPIKey)
+			keys.GET("", apiKeyHandler.ListAPIKeys)
+			keys.DELETE("/:id", apiKeyHandler.RevokeAPIKey)
+		}
+
+		// Admin API (JWT + admin role, checked against the database on every request)
+		admin := api.Group("/admin")
+		admin.Use(middleware.RequireJWT(), middleware.RequireRole(userService, model.RoleAdmin))
+		{
+			admin.GET("/urls", adminHandler.ListURLs)
+			admin.POST("/urls/:code/takedown", adminHandler.TakedownURL)
+			admin.DELETE("/urls/:code/takedown", adminHandler.LiftTakedown)
+			admin.GET("/users", adminHandler.ListUsers)
+			admin.PATCH("/users/:id", adminHandler.UpdateUser)
+			admin.GET("/lockouts", adminHandler.ListLockouts)
+			admin.GET("/claims", adminHandler.ListLinkClaims)
+		}
+
+		// apiKey accepts an API key with the given scope in place of a JWT
+		apiKey := func(scope string) gin.HandlerFunc {
+			return middleware.APIKeyAuth(apiKeyService, scope)
+		}
+
+		// Link creation may require a verified email address
+		verifiedEmail := func(c *gin.Context) { c.Next() }
+		if accountConfig.RequireEmailVerification {
+			verifiedEmail = middleware.RequireVerifiedEmail(userService)
+		}
+
 		// URL routes with optional JWT authentication
 		// Creates link as authenticated user if logged in, or as anonymous if not
-		api.POST("/shorten", middleware.OptionalJWT(), urlHandler.CreateShortURL)
-		api.GET("/urls", middleware.OptionalJWT(), urlHandler.ListURLs)
-		api.GET("/urls/:code", urlHandler.GetURLInfo)
+		api.POST("/shorten/batch", apiKey(service.ScopeLinksWrite), middleware.RequireJWT(), verifiedEmail, urlHandler.CreateShortURLBatch)
+		api.POST("/shorten", apiKey(service.ScopeLinksWrite), middleware.OptionalJWT(), verifiedEmail, middleware.AnonymousIdentity(anonymousIdentities), urlHandler.CreateShortURL)
+		api.GET("/urls", apiKey(service.ScopeLinksRead), middleware.OptionalJWT(), middleware.AnonymousIdentity(anonymousIdentities), urlHandler.ListURLs)
+		api.GET("/urls/export", apiKey(service.ScopeLinksRead), middleware.RequireJWT(), urlHandler.ExportURLs)
+		api.POST("/urls/imports", apiKey(service.ScopeLinksWrite), middleware.RequireJWT(), verifiedEmail, importHandler.StartImport)
+		api.GET("/urls/imports/:id", apiKey(service.ScopeLinksRead), middleware.RequireJWT(), importHandler.GetImport)
+		api.GET("/urls/:code", apiKey(service.ScopeLinksRead), middleware.OptionalJWT(), urlHandler.GetURLInfo)
+		api.PATCH("/urls/:code", apiKey(service.ScopeLinksWrite), middleware.RequireJWT(), urlHandler.UpdateURL)
+		api.DELETE("/urls/:code", apiKey(service.ScopeLinksWrite), middleware.RequireJWT(), urlHandler.DeleteURL)
+		api.POST("/urls/:code/restore", apiKey(service.ScopeLinksWrite), middleware.RequireJWT(), urlHandler.RestoreURL)
+		api.GET("/urls/:code/clicks", apiKey(service.ScopeAnalyticsRead), middleware.RequireJWT(), analyticsHandler.GetClickStats)
 	}
 
 	// Get port from environment or default to 2345
@@ -115,9 +281,81 @@ func main() {
 	log.Println("👤 Anonymous users: Create links without auth")
 	log.Println("🔐 Registered users: Use JWT Bearer token")
 	log.Println("🎫 Login/Register returns: access_token (15min) + refresh_token (7days)")
-	log.Println("🔗 Claim links: POST /api/auth/claim-links with anonymous_id")
+	log.Println("🔗 Claim links: POST /api/auth/claim-links with the anon_token cookie")
+
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: r,
+	}
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+
+	// Wait for an interrupt, then stop accepting requests and flush pending clicks and click events
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	log.Println("Shutting down server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Println("Server forced to shutdown:", err)
+	}
+
+	clickAggregator.Stop()
+	clickEventWriter.Stop()
+	log.Println("Server stopped")
+}
+
+// newURLCache builds the redirect lookup cache, or returns nil when caching is disabled
+func newURLCache(cfg config.CacheConfig) cache.Cache {
+	switch cfg.Backend {
+	case config.CacheBackendNone:
+		log.Println("URL cache disabled")
+		return nil
+	case config.CacheBackendRedis:
+		c, err := cache.NewRedisCache(cfg.RedisURL, cfg.RedisPrefix)
+		if err != nil {
+			log.Fatal("Failed to connect to Redis cache:", err)
+		}
+		log.Println("URL cache: Redis")
+		return c
+	default:
+		log.Printf("URL cache: in-memory LRU (%d entries)", cfg.Size)
+		return cache.NewMemoryCache(cfg.Size)
+	}
+}
+
+// newLoginGuardStore builds the store for failed sign-in counters
+func newLoginGuardStore(cfg config.LoginGuardConfig) loginguard.Store {
+	if cfg.Backend == config.LoginGuardBackendRedis {
+		store, err := loginguard.NewRedisStore(cfg.RedisURL, cfg.RedisPrefix)
+		if err != nil {
+			log.Fatal("Failed to connect to Redis for the login guard:", err)
+		}
+		log.Println("Login guard: Redis")
+		return store
+	}
+
+	log.Println("Login guard: in-memory (set LOGIN_GUARD_BACKEND=redis when running several instances)")
+	return loginguard.NewMemoryStore()
+}
+
+// newMailer builds the outgoing mail backend
+func newMailer(cfg config.MailConfig) mailer.Mailer {
+	if cfg.Backend == config.MailBackendSMTP {
+		log.Printf("Mail: SMTP via %s:%d", cfg.SMTPHost, cfg.SMTPPort)
+		return mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From)
+	}
 
-	if err := r.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	m, err := mailer.NewFileMailer(cfg.OutboxDir, cfg.From)
+	if err != nil {
+		log.Fatal("Failed to create mail outbox:", err)
 	}
+	log.Printf("Mail: writing messages to %s (set MAIL_BACKEND=smtp to send them)", cfg.OutboxDir)
+	return m
 }
@@ -0,0 +1,17 @@
+package config
+
+// AdminConfig describes the administrator account bootstrapped at startup
+type AdminConfig struct {
+	Email    string // Existing user with this email is promoted to admin
+	Username string // Used when the account has to be created
+	Password string // Creates the account if no user has Email; leave empty to only promote
+}
+
+// LoadAdminConfig reads admin bootstrap settings from the environment
+func LoadAdminConfig() AdminConfig {
+	return AdminConfig{
+		Email:    getEnv("ADMIN_EMAIL", ""),
+		Username: getEnv("ADMIN_USERNAME", "admin"),
+		Password: getEnv("ADMIN_PASSWORD", ""),
+	}
+}
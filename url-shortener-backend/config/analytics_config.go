@@ -0,0 +1,54 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"time"
+)
+
+// AnalyticsConfig holds settings for per-click analytics
+type AnalyticsConfig struct {
+	IPHashSalt string // Mixed into visitor IP hashes so raw addresses are never stored
+
+	// Click events are queued by redirects and inserted in batches
+	QueueSize     int           // Events waiting to be written; further events are dropped
+	BatchSize     int           // Events inserted per statement
+	FlushInterval time.Duration // Queued events are written at least this often
+}
+
+// LoadAnalyticsConfig reads analytics settings from the environment
+func LoadAnalyticsConfig() AnalyticsConfig {
+	salt := getEnv("ANALYTICS_IP_SALT", "")
+	if salt == "" {
+		// Without a fixed salt, hashes only stay comparable until the next restart
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			log.Fatal("Failed to generate analytics salt:", err)
+		}
+		salt = hex.EncodeToString(buf)
+		log.Println("ANALYTICS_IP_SALT not set, using a random salt for this process")
+	}
+
+	cfg := AnalyticsConfig{
+		IPHashSalt:    salt,
+		QueueSize:     getEnvInt("ANALYTICS_QUEUE_SIZE", 10000),
+		BatchSize:     getEnvInt("ANALYTICS_BATCH_SIZE", 500),
+		FlushInterval: getEnvDuration("ANALYTICS_FLUSH_INTERVAL", time.Second),
+	}
+
+	if cfg.QueueSize <= 0 {
+		log.Printf("Invalid ANALYTICS_QUEUE_SIZE, using default %d", 10000)
+		cfg.QueueSize = 10000
+	}
+	if cfg.BatchSize <= 0 {
+		log.Printf("Invalid ANALYTICS_BATCH_SIZE, using default %d", 500)
+		cfg.BatchSize = 500
+	}
+	if cfg.FlushInterval <= 0 {
+		log.Printf("Invalid ANALYTICS_FLUSH_INTERVAL, using default %s", time.Second)
+		cfg.FlushInterval = time.Second
+	}
+
+	return cfg
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"time"
+)
+
+// AnonymousConfig holds the settings for visitors who create links without an account
+type AnonymousConfig struct {
+	Secret      string        // Signs anonymous identity tokens and keys creator IP hashes; defaults to JWT_SECRET
+	IdentityTTL time.Duration // How long an anonymous identity token stays valid
+
+	// Creation quotas, counted over QuotaWindow; 0 disables a quota
+	QuotaWindow   time.Duration
+	MaxLinksPerID int // Links one anonymous identity may create
+	MaxLinksPerIP int // Anonymous links one client IP may create
+
+	LinkTTL    time.Duration // Unclaimed anonymous links expire this long after creation; 0 keeps them
+	PurgeAfter time.Duration // Expired unclaimed links are deleted this long after expiring
+}
+
+// LoadAnonymousConfig reads anonymous visitor settings from the environment.
+// The secret defaults to the JWT secret, but never to the public development
+// one: anyone could forge identity tokens with it.
+func LoadAnonymousConfig(jwtConfig JWTConfig) AnonymousConfig {
+	secret := getEnv("ANON_ID_SECRET", "")
+	if secret == "" && jwtConfig.DevSecret {
+		// Identity tokens then only stay valid until the next restart
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			log.Fatal("Failed to generate anonymous identity secret:", err)
+		}
+		secret = hex.EncodeToString(buf)
+		log.Println("ANON_ID_SECRET not set with the development JWT secret, using a random secret for this process")
+	}
+	if secret == "" {
+		secret = jwtConfig.Secret
+	}
+
+	cfg := AnonymousConfig{
+		Secret:      secret,
+		IdentityTTL: getEnvDuration("ANON_ID_TTL", 365*24*time.Hour),
+
+		QuotaWindow:   getEnvDuration("ANON_QUOTA_WINDOW", 24*time.Hour),
+		MaxLinksPerID: getEnvInt("ANON_MAX_LINKS_PER_ID", 20),
+		MaxLinksPerIP: getEnvInt("ANON_MAX_LINKS_PER_IP", 50),
+
+		LinkTTL:    getEnvDuration("ANON_LINK_TTL", 30*24*time.Hour),
+		PurgeAfter: getEnvDuration("ANON_PURGE_AFTER", 7*24*time.Hour),
+	}
+
+	if cfg.IdentityTTL <= 0 {
+		log.Printf("Invalid ANON_ID_TTL, using default %s", 365*24*time.Hour)
+		cfg.IdentityTTL = 365 * 24 * time.Hour
+	}
+	if cfg.QuotaWindow <= 0 {
+		log.Printf("Invalid ANON_QUOTA_WINDOW, using default %s", 24*time.Hour)
+		cfg.QuotaWindow = 24 * time.Hour
+	}
+	if cfg.MaxLinksPerID < 0 {
+		log.Printf("Invalid ANON_MAX_LINKS_PER_ID, using default %d", 20)
+		cfg.MaxLinksPerID = 20
+	}
+	if cfg.MaxLinksPerIP < 0 {
+		log.Printf("Invalid ANON_MAX_LINKS_PER_IP, using default %d", 50)
+		cfg.MaxLinksPerIP = 50
+	}
+	if cfg.LinkTTL < 0 {
+		log.Printf("Invalid ANON_LINK_TTL, using default %s", 30*24*time.Hour)
+		cfg.LinkTTL = 30 * 24 * time.Hour
+	}
+	// A negative delay would delete links before they expire
+	if cfg.PurgeAfter < 0 {
+		log.Printf("Invalid ANON_PURGE_AFTER, using default %s", 7*24*time.Hour)
+		cfg.PurgeAfter = 7 * 24 * time.Hour
+	}
+
+	return cfg
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadAnonymousConfigRejectsNegativeDurations(t *testing.T) {
+	t.Setenv("ANON_ID_SECRET", "test-secret")
+	t.Setenv("ANON_ID_TTL", "-1h")
+	t.Setenv("ANON_QUOTA_WINDOW", "0s")
+	t.Setenv("ANON_MAX_LINKS_PER_ID", "-1")
+	t.Setenv("ANON_LINK_TTL", "-24h")
+	t.Setenv("ANON_PURGE_AFTER", "-168h")
+
+	cfg := LoadAnonymousConfig(JWTConfig{Secret: "jwt-secret"})
+	if cfg.IdentityTTL != 365*24*time.Hour {
+		t.Errorf("IdentityTTL = %s", cfg.IdentityTTL)
+	}
+	if cfg.QuotaWindow != 24*time.Hour {
+		t.Errorf("QuotaWindow = %s", cfg.QuotaWindow)
+	}
+	if cfg.MaxLinksPerID != 20 {
+		t.Errorf("MaxLinksPerID = %d", cfg.MaxLinksPerID)
+	}
+	if cfg.LinkTTL != 30*24*time.Hour {
+		t.Errorf("LinkTTL = %s", cfg.LinkTTL)
+	}
+	if cfg.PurgeAfter != 7*24*time.Hour {
+		t.Errorf("PurgeAfter = %s", cfg.PurgeAfter)
+	}
+}
+
+func TestLoadAnonymousConfigKeepsZeroTTLAndPurgeDelay(t *testing.T) {
+	t.Setenv("ANON_ID_SECRET", "test-secret")
+	t.Setenv("ANON_LINK_TTL", "0s")
+	t.Setenv("ANON_PURGE_AFTER", "0s")
+	t.Setenv("ANON_MAX_LINKS_PER_IP", "0")
+
+	cfg := LoadAnonymousConfig(JWTConfig{Secret: "jwt-secret"})
+	if cfg.LinkTTL != 0 || cfg.PurgeAfter != 0 || cfg.MaxLinksPerIP != 0 {
+		t.Errorf("LinkTTL = %s, PurgeAfter = %s, MaxLinksPerIP = %d, want all 0", cfg.LinkTTL, cfg.PurgeAfter, cfg.MaxLinksPerIP)
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import "time"
+
+// Supported cache backends
+const (
+	CacheBackendNone   = "none"
+	CacheBackendMemory = "memory"
+	CacheBackendRedis  = "redis"
+)
+
+// CacheConfig holds settings for the redirect lookup cache
+type CacheConfig struct {
+	Backend     string        // none, memory or redis
+	Size        int           // Max entries of the in-memory cache
+	TTL         time.Duration // Lifetime of cached links
+	NegativeTTL time.Duration // Lifetime of cached "not found" answers
+	RedisURL    string
+	RedisPrefix string
+}
+
+// LoadCacheConfig reads cache settings from the environment
+func LoadCacheConfig() CacheConfig {
+	return CacheConfig{
+		Backend:     getEnv("CACHE_BACKEND", CacheBackendMemory),
+		Size:        getEnvInt("CACHE_SIZE", 10000),
+		TTL:         getEnvDuration("CACHE_TTL", 5*time.Minute),
+		NegativeTTL: getEnvDuration("CACHE_NEGATIVE_TTL", 30*time.Second),
+		RedisURL:    getEnv("REDIS_URL", "redis://localhost:6379/0"),
+		RedisPrefix: getEnv("REDIS_PREFIX", "url-shortener:"),
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 	"url-shortener/internal/model"
 
 	"gorm.io/driver/postgres"
@@ -42,17 +45,93 @@ func InitDB() *gorm.DB {
 	}
 
 	// Auto migrate models
-	if err := db.AutoMigrate(&model.User{}, &model.URL{}); err != nil {
+	if err := db.AutoMigrate(&model.User{}, &model.URL{}, &model.ClickEvent{}, &model.RefreshToken{}, &model.APIKey{}, &model.UserToken{}, &model.RecoveryCode{}, &model.LoginLockout{}, &model.UserIdentity{}, &model.LinkClaim{}, &model.ImportJob{}); err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
+	if err := backfillURLDomains(db); err != nil {
+		log.Fatal("Failed to backfill URL domains:", err)
+	}
 
 	log.Println("Database initialized successfully")
 	return db
 }
 
+// backfillURLDomains fills the domain column of links created before it existed
+func backfillURLDomains(db *gorm.DB) error {
+	var urls []model.URL
+	return db.Unscoped().
+		Select("id", "original_url").
+		Where("domain = '' OR domain IS NULL").
+		FindInBatches(&urls, 500, func(tx *gorm.DB, batch int) error {
+			for _, u := range urls {
+				err := tx.Unscoped().Model(&model.URL{}).
+					Where("id = ?", u.ID).
+					UpdateColumn("domain", model.DomainOf(u.OriginalURL)).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid value for %s: %q, using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid value for %s: %q, using default %t", key, value, defaultValue)
+		return defaultValue
+	}
+	return b
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid value for %s: %q, using default %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
+
+// getEnvList reads a comma-separated list, ignoring empty entries
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// ImportConfig holds the limits for importing links from files
+type ImportConfig struct {
+	MaxFileSize       int64  // Largest upload accepted, in bytes
+	MaxRows           int    // A longer file fails its job; rows before the limit stay imported. 0 = unlimited
+	MaxErrors         int    // Row errors kept on a job; later ones are only counted
+	Workers           int    // Imports processed at the same time; others wait as pending
+	MaxPendingPerUser int    // Unfinished imports one user may have; each keeps its file on disk. 0 = unlimited
+	MaxPending        int    // Unfinished imports of all users together. 0 = unlimited
+	UploadDir         string // Uploaded files wait here until their job has run
+}
+
+// LoadImportConfig reads link import settings from the environment
+func LoadImportConfig() ImportConfig {
+	workers := getEnvInt("IMPORT_WORKERS", 2)
+	if workers < 1 {
+		workers = 1
+	}
+	cfg := ImportConfig{
+		MaxFileSize:       int64(getEnvInt("IMPORT_MAX_FILE_SIZE", 50<<20)),
+		MaxRows:           getEnvInt("IMPORT_MAX_ROWS", 100000),
+		MaxErrors:         getEnvInt("IMPORT_MAX_ERRORS", 1000),
+		Workers:           workers,
+		MaxPendingPerUser: getEnvInt("IMPORT_MAX_PENDING_PER_USER", 2),
+		MaxPending:        getEnvInt("IMPORT_MAX_PENDING", 20),
+		UploadDir:         getEnv("IMPORT_UPLOAD_DIR", filepath.Join(os.TempDir(), "url-shortener-imports")),
+	}
+
+	if cfg.MaxPendingPerUser < 0 {
+		log.Printf("Invalid IMPORT_MAX_PENDING_PER_USER, using default %d", 2)
+		cfg.MaxPendingPerUser = 2
+	}
+	if cfg.MaxPending < 0 {
+		log.Printf("Invalid IMPORT_MAX_PENDING, using default %d", 20)
+		cfg.MaxPending = 20
+	}
+	return cfg
+}
@@ -0,0 +1,24 @@
+package config
+
+import "testing"
+
+func TestLoadImportConfigPendingLimits(t *testing.T) {
+	t.Setenv("IMPORT_MAX_PENDING_PER_USER", "-1")
+	t.Setenv("IMPORT_MAX_PENDING", "-5")
+
+	cfg := LoadImportConfig()
+	if cfg.MaxPendingPerUser != 2 {
+		t.Errorf("MaxPendingPerUser = %d, want the default 2", cfg.MaxPendingPerUser)
+	}
+	if cfg.MaxPending != 20 {
+		t.Errorf("MaxPending = %d, want the default 20", cfg.MaxPending)
+	}
+
+	// 0 turns a limit off
+	t.Setenv("IMPORT_MAX_PENDING_PER_USER", "0")
+	t.Setenv("IMPORT_MAX_PENDING", "0")
+	cfg = LoadImportConfig()
+	if cfg.MaxPendingPerUser != 0 || cfg.MaxPending != 0 {
+		t.Errorf("MaxPendingPerUser = %d, MaxPending = %d, want 0", cfg.MaxPendingPerUser, cfg.MaxPending)
+	}
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"log"
+	"time"
+)
+
+// devJWTSecret is only used when JWT_SECRET is not set and JWT_ALLOW_DEV_SECRET
+// is true. The secrets of link unlock tokens, anonymous identities and OIDC
+// state derive from it, so it must never reach production.
+const devJWTSecret = "your-secret-key-change-this-in-production"
+
+// JWTConfig holds token signing settings
+type JWTConfig struct {
+	Secret    string // HS256 secret, used for user tokens when no key directory is set
+	DevSecret bool   // Secret is the public development default
+	Issuer    string
+	Audience  string
+
+	// Asymmetric signing: every PEM file in KeyDir is a key named by its file name (kid).
+	// Private keys sign and verify, public keys only verify. A kid that is a
+	// date or time (2026-10-01, 2026-10-01T12:00:00Z) is also when the key starts signing.
+	KeyDir         string
+	SigningKeyID   string        // Pins the signing key; by default the newest active key signs
+	ReloadInterval time.Duration // How often KeyDir is re-read
+}
+
+// LoadJWTConfig reads token signing settings from the environment
+func LoadJWTConfig() JWTConfig {
+	cfg := JWTConfig{
+		Secret:         getEnv("JWT_SECRET", ""),
+		Issuer:         getEnv("JWT_ISSUER", "url-shortener"),
+		Audience:       getEnv("JWT_AUDIENCE", "url-shortener-api"),
+		KeyDir:         getEnv("JWT_KEY_DIR", ""),
+		SigningKeyID:   getEnv("JWT_SIGNING_KID", ""),
+		ReloadInterval: getEnvDuration("JWT_KEY_RELOAD_INTERVAL", time.Minute),
+	}
+
+	if cfg.Secret == "" {
+		// Link unlock tokens are signed with the secret even when KeyDir is set
+		if !getEnvBool("JWT_ALLOW_DEV_SECRET", false) {
+			log.Fatal("JWT_SECRET is not set; set JWT_ALLOW_DEV_SECRET=true to use the development default locally")
+		}
+		log.Println("JWT_SECRET not set, using the development default - never do this in production")
+		cfg.Secret = devJWTSecret
+		cfg.DevSecret = true
+	}
+	if cfg.ReloadInterval <= 0 {
+		log.Printf("Invalid JWT_KEY_RELOAD_INTERVAL, using default %s", time.Minute)
+		cfg.ReloadInterval = time.Minute
+	}
+
+	return cfg
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"log"
+	"time"
+)
+
+// Supported login guard backends
+const (
+	LoginGuardBackendMemory = "memory"
+	LoginGuardBackendRedis  = "redis"
+)
+
+// LoginGuardConfig holds the brute-force protection settings for sign-in.
+// After the free attempts, each failure delays the next attempt by a
+// doubling backoff; reaching the max attempts locks the key out.
+type LoginGuardConfig struct {
+	Backend             string // memory or redis (shares REDIS_URL with the cache)
+	RedisURL            string
+	RedisPrefix         string
+	AccountFreeAttempts int           // Failures per account before backoff starts
+	AccountMaxAttempts  int           // Failures per account that trigger a lockout
+	IPFreeAttempts      int           // Failures per client IP before backoff starts
+	IPMaxAttempts       int           // Failures per client IP that trigger a lockout
+	BackoffBase         time.Duration // Delay after the first failure past the free attempts
+	BackoffMax          time.Duration
+	LockoutDuration     time.Duration // First lockout; each further failure doubles it
+	LockoutMax          time.Duration
+	FailureWindow       time.Duration // Failures are forgotten after this long without another
+}
+
+// LoadLoginGuardConfig reads brute-force protection settings from the environment
+func LoadLoginGuardConfig() LoginGuardConfig {
+	cfg := LoginGuardConfig{
+		Backend:             getEnv("LOGIN_GUARD_BACKEND", LoginGuardBackendMemory),
+		RedisURL:            getEnv("REDIS_URL", "redis://localhost:6379/0"),
+		RedisPrefix:         getEnv("REDIS_PREFIX", "url-shortener:"),
+		AccountFreeAttempts: getEnvInt("LOGIN_ACCOUNT_FREE_ATTEMPTS", 3),
+		AccountMaxAttempts:  getEnvInt("LOGIN_ACCOUNT_MAX_ATTEMPTS", 10),
+		IPFreeAttempts:      getEnvInt("LOGIN_IP_FREE_ATTEMPTS", 10),
+		IPMaxAttempts:       getEnvInt("LOGIN_IP_MAX_ATTEMPTS", 50),
+		BackoffBase:         getEnvDuration("LOGIN_BACKOFF_BASE", time.Second),
+		BackoffMax:          getEnvDuration("LOGIN_BACKOFF_MAX", 5*time.Minute),
+		LockoutDuration:     getEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		LockoutMax:          getEnvDuration("LOGIN_LOCKOUT_MAX", 24*time.Hour),
+		FailureWindow:       getEnvDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+	}
+
+	cfg.AccountFreeAttempts, cfg.AccountMaxAttempts = loadAttemptLimits("LOGIN_ACCOUNT", cfg.AccountFreeAttempts, cfg.AccountMaxAttempts, 3, 10)
+	cfg.IPFreeAttempts, cfg.IPMaxAttempts = loadAttemptLimits("LOGIN_IP", cfg.IPFreeAttempts, cfg.IPMaxAttempts, 10, 50)
+
+	if cfg.BackoffBase <= 0 {
+		log.Printf("Invalid LOGIN_BACKOFF_BASE, using default %s", time.Second)
+		cfg.BackoffBase = time.Second
+	}
+	if cfg.BackoffMax < cfg.BackoffBase {
+		log.Printf("Invalid LOGIN_BACKOFF_MAX, using LOGIN_BACKOFF_BASE %s", cfg.BackoffBase)
+		cfg.BackoffMax = cfg.BackoffBase
+	}
+	if cfg.LockoutDuration <= 0 {
+		log.Printf("Invalid LOGIN_LOCKOUT_DURATION, using default %s", 15*time.Minute)
+		cfg.LockoutDuration = 15 * time.Minute
+	}
+	if cfg.LockoutMax < cfg.LockoutDuration {
+		log.Printf("Invalid LOGIN_LOCKOUT_MAX, using LOGIN_LOCKOUT_DURATION %s", cfg.LockoutDuration)
+		cfg.LockoutMax = cfg.LockoutDuration
+	}
+	if cfg.FailureWindow <= 0 {
+		log.Printf("Invalid LOGIN_FAILURE_WINDOW, using default %s", time.Hour)
+		cfg.FailureWindow = time.Hour
+	}
+
+	return cfg
+}
+
+// loadAttemptLimits checks the free and max attempts read for one counter.
+// Backoff must start before the lockout, so free attempts at or above the
+// max fall back to the default, kept below the max.
+func loadAttemptLimits(prefix string, free, max, defaultFree, defaultMax int) (int, int) {
+	if max <= 0 {
+		log.Printf("Invalid %s_MAX_ATTEMPTS, using default %d", prefix, defaultMax)
+		max = defaultMax
+	}
+	if free < 0 || free >= max {
+		fallback := min(defaultFree, max-1)
+		log.Printf("Invalid %s_FREE_ATTEMPTS (must be below %s_MAX_ATTEMPTS), using %d", prefix, prefix, fallback)
+		free = fallback
+	}
+	return free, max
+}
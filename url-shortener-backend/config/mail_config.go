@@ -0,0 +1,53 @@
+package config
+
+import "time"
+
+// Supported mail backends
+const (
+	MailBackendFile = "file"
+	MailBackendSMTP = "smtp"
+)
+
+// MailConfig holds outgoing email settings
+type MailConfig struct {
+	Backend      string // file or smtp
+	From         string
+	OutboxDir    string // Where the file backend writes .eml files
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// LoadMailConfig reads mail settings from the environment
+func LoadMailConfig() MailConfig {
+	return MailConfig{
+		Backend:      getEnv("MAIL_BACKEND", MailBackendFile),
+		From:         getEnv("MAIL_FROM", "URL Shortener <no-reply@localhost>"),
+		OutboxDir:    getEnv("MAIL_OUTBOX_DIR", "./outbox"),
+		SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:     getEnvInt("SMTP_PORT", 587),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+	}
+}
+
+// AccountConfig holds settings for account recovery and email verification
+type AccountConfig struct {
+	AppURL                   string        // Frontend base URL used in emailed links
+	PasswordResetTTL         time.Duration // Lifetime of password reset links
+	EmailVerificationTTL     time.Duration // Lifetime of email verification links
+	RequireEmailVerification bool          // Users must verify their email before creating links
+	MFAIssuer                string        // Account name prefix shown in authenticator apps
+}
+
+// LoadAccountConfig reads account settings from the environment
+func LoadAccountConfig() AccountConfig {
+	return AccountConfig{
+		AppURL:                   getEnv("APP_URL", "http://localhost:3000"),
+		PasswordResetTTL:         getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+		EmailVerificationTTL:     getEnvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		RequireEmailVerification: getEnvBool("REQUIRE_EMAIL_VERIFICATION", false),
+		MFAIssuer:                getEnv("MFA_ISSUER", "URL Shortener"),
+	}
+}
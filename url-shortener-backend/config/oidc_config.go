@@ -0,0 +1,69 @@
+package config
+
+import (
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// oidcProviderName keeps provider names usable in URLs and env var names
+var oidcProviderName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// OIDCProviderConfig describes one OpenID Connect identity provider
+type OIDCProviderConfig struct {
+	Name         string // Used in URLs: /api/auth/oidc/<name>/login
+	DisplayName  string // Shown on the login button
+	Issuer       string // Discovery document is read from <issuer>/.well-known/openid-configuration
+	ClientID     string
+	ClientSecret string // Optional for public clients, which rely on PKCE alone
+	Scopes       []string
+}
+
+// OIDCConfig holds the settings for signing in with external identity providers
+type OIDCConfig struct {
+	Providers       []OIDCProviderConfig
+	CallbackBaseURL string        // Public URL of this API; callbacks go to <base>/api/auth/oidc/<name>/callback
+	AppURL          string        // Frontend the browser is sent back to after the callback
+	StateSecret     string        // Signs the login state cookie; defaults to JWT_SECRET
+	StateTTL        time.Duration // How long the user has to finish signing in at the provider
+	AllowSignup     bool          // Create accounts for verified emails that have none
+}
+
+// LoadOIDCConfig reads provider settings from the environment. OIDC_PROVIDERS
+// lists the provider names; each reads OIDC_<NAME>_ISSUER, _CLIENT_ID,
+// _CLIENT_SECRET, _SCOPES and _DISPLAY_NAME.
+func LoadOIDCConfig() OIDCConfig {
+	cfg := OIDCConfig{
+		CallbackBaseURL: strings.TrimRight(getEnv("OIDC_CALLBACK_BASE_URL", "http://localhost:8080"), "/"),
+		AppURL:          strings.TrimRight(getEnv("APP_URL", "http://localhost:3000"), "/"),
+		StateSecret:     getEnv("OIDC_STATE_SECRET", ""),
+		StateTTL:        getEnvDuration("OIDC_STATE_TTL", 10*time.Minute),
+		AllowSignup:     getEnvBool("OIDC_ALLOW_SIGNUP", true),
+	}
+
+	for _, name := range getEnvList("OIDC_PROVIDERS", nil) {
+		name = strings.ToLower(name)
+		if !oidcProviderName.MatchString(name) {
+			log.Printf("OIDC: ignoring provider %q, names may only contain a-z, 0-9 and -", name)
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		provider := OIDCProviderConfig{
+			Name:         name,
+			DisplayName:  getEnv(prefix+"DISPLAY_NAME", name),
+			Issuer:       strings.TrimRight(getEnv(prefix+"ISSUER", ""), "/"),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:       getEnvList(prefix+"SCOPES", []string{"openid", "email", "profile"}),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			log.Printf("OIDC: ignoring provider %q, %sISSUER and %sCLIENT_ID are required", name, prefix, prefix)
+			continue
+		}
+		cfg.Providers = append(cfg.Providers, provider)
+	}
+
+	return cfg
+}
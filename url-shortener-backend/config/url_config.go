@@ -0,0 +1,92 @@
+package config
+
+import (
+	"log"
+	"time"
+)
+
+// URLConfig holds the rules applied when creating short links
+type URLConfig struct {
+	AliasMinLength  int
+	AliasMaxLength  int
+	AliasCharset    string
+	ReservedAliases []string
+
+	DefaultRedirectType int // Status used for links without their own redirect type
+	BatchMaxItems       int // Most links one batch create request may contain
+
+	ExpirySweepInterval  time.Duration // How often expired links are marked
+	ExpiredLinkRetention time.Duration // Expired links are purged after this long; 0 keeps them forever
+}
+
+// ClickCounterConfig tunes the in-process click count aggregator
+type ClickCounterConfig struct {
+	FlushInterval  time.Duration // Pending counts are written at least this often
+	FlushThreshold int           // Flush early once this many distinct codes are pending
+	QueueSize      int           // Buffered clicks waiting to be aggregated
+	EnqueueTimeout time.Duration // How long a redirect waits for queue space before the click is dropped
+}
+
+// defaultReservedAliases collide with routes served by the backend and the frontend
+var defaultReservedAliases = []string{
+	"api", "swagger", "health", "login", "register", "admin", "static", "assets", "favicon.ico",
+}
+
+// LoadURLConfig reads short link settings from the environment
+func LoadURLConfig() URLConfig {
+	cfg := URLConfig{
+		AliasMinLength:  getEnvInt("ALIAS_MIN_LENGTH", 3),
+		AliasMaxLength:  getEnvInt("ALIAS_MAX_LENGTH", 32),
+		AliasCharset:    getEnv("ALIAS_CHARSET", "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_"),
+		ReservedAliases: append(append([]string{}, defaultReservedAliases...), getEnvList("RESERVED_ALIASES", nil)...),
+
+		DefaultRedirectType: loadDefaultRedirectType(),
+		BatchMaxItems:       getEnvInt("SHORTEN_BATCH_MAX_ITEMS", 100),
+
+		ExpirySweepInterval:  getEnvDuration("EXPIRY_SWEEP_INTERVAL", time.Minute),
+		ExpiredLinkRetention: getEnvDuration("EXPIRED_LINK_RETENTION", 0),
+	}
+
+	if cfg.ExpirySweepInterval <= 0 {
+		log.Printf("Invalid EXPIRY_SWEEP_INTERVAL, using default %s", time.Minute)
+		cfg.ExpirySweepInterval = time.Minute
+	}
+
+	return cfg
+}
+
+func loadDefaultRedirectType() int {
+	status := getEnvInt("DEFAULT_REDIRECT_TYPE", 302)
+	switch status {
+	case 301, 302, 307, 308:
+		return status
+	default:
+		log.Printf("Invalid DEFAULT_REDIRECT_TYPE %d, using 302", status)
+		return 302
+	}
+}
+
+// LoadClickCounterConfig reads click aggregation settings from the environment
+func LoadClickCounterConfig() ClickCounterConfig {
+	cfg := ClickCounterConfig{
+		FlushInterval:  getEnvDuration("CLICK_FLUSH_INTERVAL", 2*time.Second),
+		FlushThreshold: getEnvInt("CLICK_FLUSH_THRESHOLD", 500),
+		QueueSize:      getEnvInt("CLICK_QUEUE_SIZE", 10000),
+		EnqueueTimeout: getEnvDuration("CLICK_ENQUEUE_TIMEOUT", 10*time.Millisecond),
+	}
+
+	if cfg.FlushInterval <= 0 {
+		log.Printf("Invalid CLICK_FLUSH_INTERVAL, using default %s", 2*time.Second)
+		cfg.FlushInterval = 2 * time.Second
+	}
+	if cfg.FlushThreshold <= 0 {
+		log.Printf("Invalid CLICK_FLUSH_THRESHOLD, using default %d", 500)
+		cfg.FlushThreshold = 500
+	}
+	if cfg.QueueSize <= 0 {
+		log.Printf("Invalid CLICK_QUEUE_SIZE, using default %d", 10000)
+		cfg.QueueSize = 10000
+	}
+
+	return cfg
+}
@@ -23,85 +23,90 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/auth/claim-links": {
-            "post": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Transfer ownership of anonymous links to logged-in user",
-                "consumes": [
-                    "application/json"
-                ],
+        "/.well-known/jwks.json": {
+            "get": {
+                "description": "Public keys that verify access and refresh tokens, identified by the kid token header. Empty when tokens are signed with a shared HS256 secret.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "auth"
                 ],
-                "summary": "Claim anonymous links",
-                "parameters": [
-                    {
-                        "description": "Anonymous ID from cookie/localStorage",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handler.ClaimLinksRequest"
-                        }
-                    }
-                ],
+                "summary": "JSON Web Key Set",
                 "responses": {
                     "200": {
-                        "description": "Links claimed successfully",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "$ref": "#/definitions/middleware.JWKS"
                         }
                     }
                 }
             }
         },
-        "/api/auth/login": {
-            "post": {
-                "description": "Authenticate user with username and password",
-                "consumes": [
-                    "application/json"
+        "/api/admin/claims": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Get a page of the audit trail of anonymous links claimed by users, newest first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "User login",
+                "summary": "List link claims (admin)",
                 "parameters": [
                     {
-                        "description": "Login credentials",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handler.LoginRequest"
-                        }
+                        "type": "integer",
+                        "description": "Filter by claiming user",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by anonymous identity",
+                        "name": "anonymous_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by short code",
+                        "name": "short_code",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size (max 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of claims to skip",
+                        "name": "offset",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handler.AuthResponse"
+                            "$ref": "#/definitions/service.LinkClaimPage"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
@@ -109,39 +114,58 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/auth/refresh": {
-            "post": {
-                "description": "Get new access token and refresh token using valid refresh token",
-                "consumes": [
-                    "application/json"
+        "/api/admin/lockouts": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Get a page of lockouts caused by repeated failed sign-ins, newest first. Subjects are emails (or mfa:\u003cuser id\u003e for the two-factor step) and client IPs.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Refresh access token",
+                "summary": "List login lockouts (admin)",
                 "parameters": [
                     {
-                        "description": "Refresh token",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handler.RefreshRequest"
-                        }
+                        "type": "string",
+                        "description": "Filter by scope: account or ip",
+                        "name": "scope",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size (max 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of lockouts to skip",
+                        "name": "offset",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handler.RefreshResponse"
+                            "$ref": "#/definitions/service.LoginLockoutPage"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
@@ -149,35 +173,91 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/auth/register": {
-            "post": {
-                "description": "Create a new user account",
-                "consumes": [
-                    "application/json"
+        "/api/admin/urls": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Get a page of every user's and anonymous visitor's links, with the same paging, sorting and filters as GET /api/urls",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Register new user",
+                "summary": "List all URLs (admin)",
                 "parameters": [
                     {
-                        "description": "Registration details",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handler.RegisterRequest"
-                        }
+                        "type": "integer",
+                        "description": "Only links of this user",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size (max 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "created",
+                        "description": "Sort key: created, updated or clicks",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "desc",
+                        "description": "Sort order: asc or desc",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter: active, disabled, expired, protected, deleted or taken_down",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Destination domain, including its subdomains",
+                        "name": "domain",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after (RFC3339)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created before (RFC3339)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Search in short code and destination URL",
+                        "name": "q",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handler.AuthResponse"
+                            "$ref": "#/definitions/service.URLPage"
                         }
                     },
                     "400": {
@@ -185,13 +265,24 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/api/shorten": {
+        "/api/admin/urls/{code}/takedown": {
             "post": {
-                "description": "Shorten a long URL (works for both authenticated and anonymous users)",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Block a link for every visitor; redirects return 410 until the takedown is lifted. The owner cannot undo it.",
                 "consumes": [
                     "application/json"
                 ],
@@ -199,25 +290,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "urls"
+                    "admin"
                 ],
-                "summary": "Create short URL",
+                "summary": "Take down a link (admin)",
                 "parameters": [
                     {
-                        "description": "URL to shorten with optional anonymous_id",
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason shown to the owner",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.CreateURLRequest"
+                            "$ref": "#/definitions/handler.TakedownRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handler.CreateURLResponse"
+                            "$ref": "#/definitions/model.URL"
                         }
                     },
                     "400": {
@@ -225,55 +323,35 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
-                    }
-                }
-            }
-        },
-        "/api/urls": {
-            "get": {
-                "description": "Get list of shortened URLs (user's own or all if admin)",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "urls"
-                ],
-                "summary": "List all URLs",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Anonymous ID to filter links",
-                        "name": "anonymous_id",
-                        "in": "query"
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "Returns total count and array of URLs",
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/api/urls/{code}": {
-            "get": {
-                "description": "Get detailed information about a shortened URL",
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Let a taken-down link redirect again",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "urls"
+                    "admin"
                 ],
-                "summary": "Get URL information",
+                "summary": "Lift a takedown (admin)",
                 "parameters": [
                     {
                         "type": "string",
@@ -290,6 +368,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.URL"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -299,59 +383,3415 @@ const docTemplate = `{
                 }
             }
         },
-        "/{code}": {
+        "/api/admin/users": {
             "get": {
-                "description": "Redirect to the original URL using short code",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a page of users, newest first",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "urls"
+                    "admin"
                 ],
-                "summary": "Redirect to original URL",
+                "summary": "List users (admin)",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Short code",
-                        "name": "code",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
+                        "description": "Search in username and email",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by role: user or admin",
+                        "name": "role",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Filter by disabled state",
+                        "name": "disabled",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size (max 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of users to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.UserPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/users/{id}": {
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Disabling an account blocks sign-in, refresh and API keys and revokes its sessions. Access tokens already issued expire within 15 minutes.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Change a user's role or disable the account (admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.UpdateUserAccessRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/claim-links": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List the links created under this client's anonymous identity (anon_token cookie or X-Anonymous-Token header) that can be claimed",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Preview claimable links",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Anonymous token, for clients without the anon_token cookie",
+                        "name": "X-Anonymous-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.ClaimPreview"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Transfer ownership of links created under this client's anonymous identity (anon_token cookie or X-Anonymous-Token header) to the logged-in user: the listed short_codes, or all of them when none are given. The claim is atomic and recorded in the audit trail; repeating it is safe and reports the links as already_claimed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Claim anonymous links",
+                "parameters": [
+                    {
+                        "description": "Optional short codes to claim, and anonymous_id checked against the anonymous token",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ClaimLinksRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Anonymous token, for clients without the anon_token cookie",
+                        "name": "X-Anonymous-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ClaimLinksResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/email/resend": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Email a new verification link to the authenticated user. Earlier links stop working.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Resend verification email",
+                "responses": {
+                    "202": {
+                        "description": "Verification email sent",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/email/verify": {
+            "post": {
+                "description": "Confirm the account's email address with the token from the verification email",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Verify email address",
+                "parameters": [
+                    {
+                        "description": "Verification token",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.VerifyEmailRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Email verified",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/login": {
+            "post": {
+                "description": "Authenticate user with username and password. If the account has two-factor authentication enabled, the response is an MFAChallengeResponse instead; exchange its mfa_token at /api/auth/login/mfa. Repeated failures for an account or client IP are slowed down and then locked out; the Retry-After header says how many seconds to wait.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "User login",
+                "parameters": [
+                    {
+                        "description": "Login credentials",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.AuthResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/login/mfa": {
+            "post": {
+                "description": "Exchange the mfa_token from /api/auth/login and a code from the authenticator app (or an unused recovery code) for access and refresh tokens",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Complete two-factor login",
+                "parameters": [
+                    {
+                        "description": "Challenge token and code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.LoginMFARequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.AuthResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/logout": {
+            "post": {
+                "description": "Revoke the session the refresh token belongs to. Access tokens already issued stay valid until they expire.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Log out",
+                "parameters": [
+                    {
+                        "description": "Refresh token of the session",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Logged out",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/logout-all": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revoke every refresh token of the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Log out everywhere",
+                "responses": {
+                    "200": {
+                        "description": "Number of revoked tokens",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/mfa": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Report whether TOTP two-factor authentication is enabled and how many recovery codes are unused",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mfa"
+                ],
+                "summary": "Two-factor status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.MFAStatus"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/mfa/recovery-codes": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace all recovery codes with 10 new ones. The old codes stop working.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mfa"
+                ],
+                "summary": "Regenerate recovery codes",
+                "parameters": [
+                    {
+                        "description": "Code from the authenticator app",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.MFACodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.RecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/mfa/totp/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Enable two-factor authentication with a code from the newly added authenticator app. Returns 10 recovery codes that are only shown once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mfa"
+                ],
+                "summary": "Confirm TOTP setup",
+                "parameters": [
+                    {
+                        "description": "Code from the authenticator app",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.MFACodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.RecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/mfa/totp/disable": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Turn two-factor authentication off. Requires the password and a current code or recovery code. Remaining recovery codes are deleted.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mfa"
+                ],
+                "summary": "Disable TOTP",
+                "parameters": [
+                    {
+                        "description": "Password and code",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.DisableTOTPRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Two-factor authentication disabled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/mfa/totp/setup": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Generate a new TOTP secret. Scan the QR code (or enter the secret) in an authenticator app, then confirm with a code. Calling this again replaces an unconfirmed secret.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mfa"
+                ],
+                "summary": "Start TOTP setup",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.TOTPEnrollment"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/oidc/exchange": {
+            "post": {
+                "description": "Exchange the single-use code from the callback redirect (valid for 2 minutes) for access and refresh tokens. Accounts with two-factor authentication get an MFAChallengeResponse instead, like /api/auth/login.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "Finish provider sign-in",
+                "parameters": [
+                    {
+                        "description": "Code from the callback redirect",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.OIDCExchangeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.AuthResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/oidc/providers": {
+            "get": {
+                "description": "Get the external OpenID Connect providers users can sign in with",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "List sign-in providers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/service.OIDCProvider"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/oidc/{provider}/callback": {
+            "get": {
+                "description": "The provider redirects here after login. The user is matched by linked identity or verified email (or created), then the browser is sent to APP_URL/oidc/callback?code=... on success or APP_URL/login?oidc_error=... on failure.",
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "Provider callback",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider name",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization code",
+                        "name": "code",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "State from the login request",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Redirect to the frontend"
+                    }
+                }
+            }
+        },
+        "/api/auth/oidc/{provider}/login": {
+            "get": {
+                "description": "Open this URL in the browser. It redirects to the provider's login page (authorization code flow with PKCE) and sets a short-lived state cookie.",
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "Sign in with a provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider name",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Redirect to the provider"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/password/forgot": {
+            "post": {
+                "description": "Email a single-use password reset link. Always returns 202, whether or not an account uses the address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Request a password reset",
+                "parameters": [
+                    {
+                        "description": "Account email",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ForgotPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Reset link sent if the account exists",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/password/reset": {
+            "post": {
+                "description": "Set a new password with the token from the reset email. The token works once, and every session of the account is signed out.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Reset password",
+                "parameters": [
+                    {
+                        "description": "Reset token and new password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ResetPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Password changed",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/refresh": {
+            "post": {
+                "description": "Exchange a refresh token for a new access token and refresh token. The presented refresh token is revoked; presenting it again revokes the whole session.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Refresh access token",
+                "parameters": [
+                    {
+                        "description": "Refresh token",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.RefreshResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/register": {
+            "post": {
+                "description": "Create a new user account. A verification link is emailed to the address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Register new user",
+                "parameters": [
+                    {
+                        "description": "Registration details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.RegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handler.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/keys": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List the authenticated user's API keys, including revoked ones. Keys themselves are never returned, only their prefix.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "List API keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.APIKey"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a personal API key for scripts and integrations. Scopes: links:read, links:write, analytics:read. The key is only returned in this response. Send it as \"X-API-Key: \u003ckey\u003e\" or \"Authorization: Bearer \u003ckey\u003e\".",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Create API key",
+                "parameters": [
+                    {
+                        "description": "Key name and scopes",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.CreateAPIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handler.CreateAPIKeyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revoke one of the authenticated user's API keys. It stops working immediately.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Revoke API key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "API key revoked",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/me": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the authenticated user's profile",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "Get own profile",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Permanently delete the account, its sessions, API keys and recovery codes. link_policy decides what happens to the user's links: delete removes them with their click history, orphan keeps them working without an owner.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "Delete own account",
+                "parameters": [
+                    {
+                        "description": "Password and link policy",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.DeleteAccountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.AccountDeletion"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Change the username and/or email address. Changing the email needs current_password and sends a new verification link; the new address is unverified until it is followed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "Update own profile",
+                "parameters": [
+                    {
+                        "description": "Fields to change",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.UpdateProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/me/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Download the profile and every link of the authenticated user (including deleted links) as JSON",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "Export own data",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.AccountExport"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/me/password": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Set a new password. Every session of the user is revoked and a new one is started for this client.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "Change password",
+                "parameters": [
+                    {
+                        "description": "Current and new password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ChangePasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/shorten": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Shorten a long URL (works for both authenticated and anonymous users)\nAn optional custom_alias is used as the short code instead of a generated one\nAnonymous visitors are identified by the signed anon_token cookie or X-Anonymous-Token header; a new identity is issued when neither is sent",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Create short URL",
+                "parameters": [
+                    {
+                        "description": "URL to shorten with optional custom_alias",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.CreateURLRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Anonymous token from an earlier response",
+                        "name": "X-Anonymous-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handler.CreateURLResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid anonymous token",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "anonymous_id does not match the anonymous token",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Custom alias already taken",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Anonymous link quota reached",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/shorten/batch": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates up to SHORTEN_BATCH_MAX_ITEMS links in one request, each validated like POST /api/shorten.\nIn atomic mode (the default) nothing is created unless every item is valid, and the links are inserted in one transaction.\nIn partial mode the valid items are created and the rest are reported.\nEvery item gets a result in request order; failed items carry an error_code:\ninvalid_url, invalid_alias, alias_reserved, alias_taken, duplicate_alias, invalid_expiry,\ninvalid_redirect_type, invalid_tags, batch_aborted (atomic mode only) or internal_error.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Create short URLs in bulk",
+                "parameters": [
+                    {
+                        "description": "Links to create",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.BatchCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Every item was created",
+                        "schema": {
+                            "$ref": "#/definitions/handler.BatchCreateResponse"
+                        }
+                    },
+                    "207": {
+                        "description": "Partial mode - some items failed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.BatchCreateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Atomic mode - nothing was created",
+                        "schema": {
+                            "$ref": "#/definitions/handler.BatchCreateResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Email not verified",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get a page of the caller's shortened URLs, newest first by default. Pass next_cursor back as cursor to fetch the following page.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "List URLs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Optional - must match the anonymous token",
+                        "name": "anonymous_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Anonymous token, for clients without the anon_token cookie",
+                        "name": "X-Anonymous-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size (max 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "created",
+                        "description": "Sort key: created, updated or clicks",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "desc",
+                        "description": "Sort order: asc or desc",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter: active, disabled, expired, protected, deleted or taken_down",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Destination domain, including its subdomains",
+                        "name": "domain",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after (RFC3339)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created before (RFC3339)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Search in short code and destination URL",
+                        "name": "q",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.URLPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid anonymous token",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "anonymous_id does not match the anonymous token",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Streams all of the caller's links, with click counts and metadata, as CSV or NDJSON.\nThe file can be imported again with POST /api/urls/imports. Link passwords are never exported;\npassword-protected links are marked with password_protected.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Export links",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "csv (default) or ndjson",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls/imports": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Uploads a CSV or NDJSON file in the format written by GET /api/urls/export and imports it in the background.\nSend the file as the \"file\" part of a multipart form, or as the raw request body.\nThe format is taken from the format parameter, the file name or the Content-Type.\nShort codes are kept when free. Taken codes are reported as conflicts (on_conflict=skip, the default)\nor replaced by a random code (on_conflict=rename). Poll GET /api/urls/imports/{id} for progress.",
+                "consumes": [
+                    "multipart/form-data",
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Import links from a file",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV or NDJSON file",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "csv or ndjson",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "skip",
+                            "rename"
+                        ],
+                        "type": "string",
+                        "description": "skip or rename",
+                        "name": "on_conflict",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/model.ImportJob"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "File larger than IMPORT_MAX_FILE_SIZE",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many unfinished imports of the user or the server",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls/imports/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the status and progress of one of the caller's imports, with the rows that were not imported",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Get import progress",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Import ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ImportJob"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls/{code}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get detailed information about a shortened URL.\nThe destination and domain of a password-protected link are only shown to its owner.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Get URL information",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.URL"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Soft-delete a short URL owned by the authenticated user; the code stays reserved and can be restored",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Delete short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Link deleted",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Change the destination, expiry, password or enabled state of a short URL owned by the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Update short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to update",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.UpdateURLRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.URL"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls/{code}/clicks": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Time-bucketed click counts and breakdowns by referrer domain, browser, OS and device type for a short URL owned by the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Get click analytics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "day",
+                        "description": "Bucket size: hour, day or week",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start of range (RFC3339), defaults to 30 days before 'to'",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of range (RFC3339), defaults to now",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Max entries per breakdown",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.ClickStats"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/urls/{code}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Restore a deleted short URL owned by the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Restore short URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.URL"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/{code}": {
+            "get": {
+                "description": "Redirect to the original URL using short code.\nThe status is the link's redirect_type (301, 302, 307 or 308) or the server default.\nPassword-protected links show an unlock form to browsers; API clients send the password\nin the X-Link-Password header or the password query parameter.\nRepeated wrong passwords for a link or from a client IP are slowed down and then locked out;\nthe Retry-After header says how many seconds to wait.",
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Redirect to original URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected links",
+                        "name": "X-Link-Password",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Password for protected links",
+                        "name": "password",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
                     "301": {
                         "description": "Moved Permanently"
                     },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
-                        }
-                    }
+                    "302": {
+                        "description": "Found"
+                    },
+                    "307": {
+                        "description": "Temporary Redirect"
+                    },
+                    "308": {
+                        "description": "Permanent Redirect"
+                    },
+                    "401": {
+                        "description": "Link is password protected",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "Link deleted, disabled or expired",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many wrong passwords",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Submit the unlock form of a password-protected link and get redirected to the original URL",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "tags": [
+                    "urls"
+                ],
+                "summary": "Unlock password-protected URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short code",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Link password",
+                        "name": "password",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "303": {
+                        "description": "See Other"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handler.AuthResponse": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                },
+                "email": {
+                    "type": "string",
+                    "example": "john@example.com"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Login successful"
+                },
+                "refresh_token": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                },
+                "username": {
+                    "type": "string",
+                    "example": "john_doe"
+                }
+            }
+        },
+        "handler.BatchCreateRequest": {
+            "type": "object",
+            "required": [
+                "items"
+            ],
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.BatchItemRequest"
+                    }
+                },
+                "mode": {
+                    "description": "Defaults to atomic",
+                    "type": "string",
+                    "enum": [
+                        "atomic",
+                        "partial"
+                    ],
+                    "example": "atomic"
+                }
+            }
+        },
+        "handler.BatchCreateResponse": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "failed": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "mode": {
+                    "type": "string",
+                    "example": "atomic"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.BatchItemResponse"
+                    }
+                }
+            }
+        },
+        "handler.BatchItemRequest": {
+            "type": "object",
+            "properties": {
+                "custom_alias": {
+                    "type": "string",
+                    "example": "spring-sale"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "example": "2025-12-31T23:59:59Z"
+                },
+                "max_clicks": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "password": {
+                    "type": "string",
+                    "example": "s3cret"
+                },
+                "redirect_type": {
+                    "type": "integer",
+                    "enum": [
+                        301,
+                        302,
+                        307,
+                        308
+                    ],
+                    "example": 302
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "spring",
+                        "email"
+                    ]
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/very/long/path"
+                }
+            }
+        },
+        "handler.BatchItemResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "custom alias is already taken"
+                },
+                "error_code": {
+                    "type": "string",
+                    "example": "alias_taken"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "example": "2025-12-31T23:59:59Z"
+                },
+                "index": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "original_url": {
+                    "type": "string",
+                    "example": "https://example.com/very/long/path"
+                },
+                "redirect_type": {
+                    "type": "integer",
+                    "example": 302
+                },
+                "short_code": {
+                    "type": "string",
+                    "example": "abc12345"
+                },
+                "short_url": {
+                    "type": "string",
+                    "example": "https://url.naammmdz.id.vn/abc12345"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "created",
+                        "failed"
+                    ],
+                    "example": "created"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "spring",
+                        "email"
+                    ]
+                }
+            }
+        },
+        "handler.ChangePasswordRequest": {
+            "type": "object",
+            "required": [
+                "current_password",
+                "new_password"
+            ],
+            "properties": {
+                "current_password": {
+                    "type": "string",
+                    "example": "password123"
+                },
+                "device_label": {
+                    "description": "Optional - labels the new session; derived from the User-Agent when empty",
+                    "type": "string",
+                    "example": "Work laptop"
+                },
+                "new_password": {
+                    "type": "string",
+                    "minLength": 6,
+                    "example": "newpassword123"
+                }
+            }
+        },
+        "handler.ClaimLinksRequest": {
+            "type": "object",
+            "properties": {
+                "anonymous_id": {
+                    "description": "Optional - must match the anonymous token, which is what selects the links",
+                    "type": "string",
+                    "example": "anon_3f2b9c0e5d7a41c88e6f0b2d4a9c1e57"
+                },
+                "short_codes": {
+                    "description": "Optional - claim only these links; every claimable link when empty",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "abc12345",
+                        "xyz67890"
+                    ]
+                }
+            }
+        },
+        "handler.ClaimLinksResponse": {
+            "type": "object",
+            "properties": {
+                "already_claimed": {
+                    "description": "Requested codes this user claimed before",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "abc12345"
+                    ]
+                },
+                "claimed": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "links": {
+                    "description": "The links claimed by this request",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.URL"
+                    }
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Links claimed successfully"
+                },
+                "not_claimable": {
+                    "description": "Requested codes that do not belong to the anonymous identity",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "xyz67890"
+                    ]
+                },
+                "user_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "handler.CreateAPIKeyRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "expires_at": {
+                    "description": "Optional",
+                    "type": "string",
+                    "example": "2026-12-31T23:59:59Z"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "CI pipeline"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "links:read",
+                        "links:write"
+                    ]
+                }
+            }
+        },
+        "handler.CreateAPIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "example": "2025-12-18T10:00:00Z"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "example": "2026-12-18T10:00:00Z"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "key": {
+                    "description": "Shown only once",
+                    "type": "string",
+                    "example": "usk_Xb3kq9Zp_2hG8sQmV0cLr5TnWy7KdB4fJ1aXeP6uZ"
+                },
+                "last_used_at": {
+                    "type": "string",
+                    "example": "2025-12-18T10:00:00Z"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "CI pipeline"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "usk_Xb3kq9Zp"
+                },
+                "revoked_at": {
+                    "type": "string",
+                    "example": "2025-12-19T10:00:00Z"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "links:read",
+                        "links:write"
+                    ]
+                }
+            }
+        },
+        "handler.CreateURLRequest": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "anonymous_id": {
+                    "description": "Optional - must match the anonymous token",
+                    "type": "string",
+                    "example": "anon_3f2b9c0e5d7a41c88e6f0b2d4a9c1e57"
+                },
+                "custom_alias": {
+                    "type": "string",
+                    "example": "spring-sale"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "example": "2025-12-31T23:59:59Z"
+                },
+                "max_clicks": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "password": {
+                    "type": "string",
+                    "example": "s3cret"
+                },
+                "redirect_type": {
+                    "type": "integer",
+                    "enum": [
+                        301,
+                        302,
+                        307,
+                        308
+                    ],
+                    "example": 302
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "spring",
+                        "email"
+                    ]
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/very/long/path"
+                }
+            }
+        },
+        "handler.CreateURLResponse": {
+            "type": "object",
+            "properties": {
+                "anonymous_id": {
+                    "type": "string",
+                    "example": "anon_3f2b9c0e5d7a41c88e6f0b2d4a9c1e57"
+                },
+                "anonymous_token": {
+                    "description": "New identities only; browsers also get it as a cookie",
+                    "type": "string",
+                    "example": "eyJpZCI6ImFub25fM2YyYjljMGU1ZDdh...Xy3Q"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "example": "2025-12-31T23:59:59Z"
+                },
+                "max_clicks": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "original_url": {
+                    "type": "string",
+                    "example": "https://example.com/very/long/path"
+                },
+                "password_protected": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "redirect_type": {
+                    "type": "integer",
+                    "example": 302
+                },
+                "short_code": {
+                    "type": "string",
+                    "example": "abc12345"
+                },
+                "short_url": {
+                    "type": "string",
+                    "example": "https://url.naammmdz.id.vn/abc12345"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "spring",
+                        "email"
+                    ]
+                }
+            }
+        },
+        "handler.DeleteAccountRequest": {
+            "type": "object",
+            "required": [
+                "link_policy",
+                "password"
+            ],
+            "properties": {
+                "link_policy": {
+                    "description": "delete removes the links and their click history; orphan keeps them working without an owner",
+                    "type": "string",
+                    "example": "orphan"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "password123"
+                }
+            }
+        },
+        "handler.DisableTOTPRequest": {
+            "type": "object",
+            "required": [
+                "code",
+                "password"
+            ],
+            "properties": {
+                "code": {
+                    "description": "A 6-digit code from the authenticator app, or a recovery code",
+                    "type": "string",
+                    "example": "123456"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "password123"
+                }
+            }
+        },
+        "handler.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Invalid URL format"
+                }
+            }
+        },
+        "handler.ForgotPasswordRequest": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "john@example.com"
+                }
+            }
+        },
+        "handler.LoginMFARequest": {
+            "type": "object",
+            "required": [
+                "code",
+                "mfa_token"
+            ],
+            "properties": {
+                "code": {
+                    "description": "A 6-digit code from the authenticator app, or a recovery code",
+                    "type": "string",
+                    "example": "123456"
+                },
+                "device_label": {
+                    "description": "Optional - labels the login session; derived from the User-Agent when empty",
+                    "type": "string",
+                    "example": "Work laptop"
+                },
+                "mfa_token": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                }
+            }
+        },
+        "handler.LoginRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
+            "properties": {
+                "device_label": {
+                    "description": "Optional - labels the login session; derived from the User-Agent when empty",
+                    "type": "string",
+                    "example": "Work laptop"
+                },
+                "email": {
+                    "type": "string",
+                    "example": "john@example.com"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "password123"
+                }
+            }
+        },
+        "handler.MFACodeRequest": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "description": "A 6-digit code from the authenticator app, or a recovery code",
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "handler.OIDCExchangeRequest": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "V1StGXR8_Z5jdHi6B-myTV1StGXR8_Z5"
+                },
+                "device_label": {
+                    "description": "Optional - labels the login session; derived from the User-Agent when empty",
+                    "type": "string",
+                    "example": "Work laptop"
+                }
+            }
+        },
+        "handler.RecoveryCodesResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "Two-factor authentication enabled"
+                },
+                "recovery_codes": {
+                    "description": "Shown only once, each works once",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "k7m2p-x9q4r",
+                        "hn3tw-8cv5j"
+                    ]
+                }
+            }
+        },
+        "handler.RefreshRequest": {
+            "type": "object",
+            "required": [
+                "refresh_token"
+            ],
+            "properties": {
+                "refresh_token": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                }
+            }
+        },
+        "handler.RefreshResponse": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Token refreshed successfully"
+                },
+                "refresh_token": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                }
+            }
+        },
+        "handler.RegisterRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "password",
+                "username"
+            ],
+            "properties": {
+                "device_label": {
+                    "description": "Optional - labels the login session; derived from the User-Agent when empty",
+                    "type": "string",
+                    "example": "Work laptop"
+                },
+                "email": {
+                    "type": "string",
+                    "example": "john@example.com"
+                },
+                "password": {
+                    "type": "string",
+                    "minLength": 6,
+                    "example": "password123"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "john_doe"
+                }
+            }
+        },
+        "handler.ResetPasswordRequest": {
+            "type": "object",
+            "required": [
+                "new_password",
+                "token"
+            ],
+            "properties": {
+                "new_password": {
+                    "type": "string",
+                    "minLength": 6,
+                    "example": "newpassword123"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "Vd3kq9ZpX1mN0bQeR7tY2uI8oP4aS6dF"
+                }
+            }
+        },
+        "handler.TakedownRequest": {
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "example": "Phishing"
+                }
+            }
+        },
+        "handler.UpdateProfileRequest": {
+            "type": "object",
+            "properties": {
+                "current_password": {
+                    "description": "Required when changing the email address",
+                    "type": "string",
+                    "example": "password123"
+                },
+                "email": {
+                    "description": "Must be verified again",
+                    "type": "string",
+                    "example": "john@example.com"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "john_doe"
+                }
+            }
+        },
+        "handler.UpdateURLRequest": {
+            "type": "object",
+            "properties": {
+                "disabled": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "expires_at": {
+                    "type": "string",
+                    "example": "2025-12-31T23:59:59Z"
+                },
+                "max_clicks": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "password": {
+                    "description": "Empty string removes the password",
+                    "type": "string",
+                    "example": "s3cret"
+                },
+                "redirect_type": {
+                    "description": "0 switches back to the server default",
+                    "type": "integer",
+                    "example": 307
+                },
+                "tags": {
+                    "description": "Replaces all tags; an empty list removes them",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "spring",
+                        "email"
+                    ]
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/new/destination"
+                }
+            }
+        },
+        "handler.UpdateUserAccessRequest": {
+            "type": "object",
+            "properties": {
+                "disabled": {
+                    "description": "Disabling also signs the user out everywhere",
+                    "type": "boolean",
+                    "example": false
+                },
+                "role": {
+                    "description": "user or admin",
+                    "type": "string",
+                    "example": "admin"
+                }
+            }
+        },
+        "handler.VerifyEmailRequest": {
+            "type": "object",
+            "required": [
+                "token"
+            ],
+            "properties": {
+                "token": {
+                    "type": "string",
+                    "example": "Vd3kq9ZpX1mN0bQeR7tY2uI8oP4aS6dF"
+                }
+            }
+        },
+        "middleware.JWK": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string",
+                    "example": "RS256"
+                },
+                "crv": {
+                    "description": "Ed25519 curve",
+                    "type": "string"
+                },
+                "e": {
+                    "description": "RSA exponent",
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string",
+                    "example": "2026-10-01"
+                },
+                "kty": {
+                    "type": "string",
+                    "example": "RSA"
+                },
+                "n": {
+                    "description": "RSA modulus",
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string",
+                    "example": "sig"
+                },
+                "x": {
+                    "description": "Ed25519 public key",
+                    "type": "string"
+                }
+            }
+        },
+        "middleware.JWKS": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/middleware.JWK"
+                    }
+                }
+            }
+        },
+        "model.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "example": "2025-12-18T10:00:00Z"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "example": "2026-12-18T10:00:00Z"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "last_used_at": {
+                    "type": "string",
+                    "example": "2025-12-18T10:00:00Z"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "CI pipeline"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "usk_Xb3kq9Zp"
+                },
+                "revoked_at": {
+                    "type": "string",
+                    "example": "2025-12-19T10:00:00Z"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "links:read",
+                        "links:write"
+                    ]
+                }
+            }
+        },
+        "model.ImportJob": {
+            "type": "object",
+            "properties": {
+                "bytes_read": {
+                    "description": "Progress through the file",
+                    "type": "integer",
+                    "example": 524288
+                },
+                "conflicts": {
+                    "description": "Rows skipped because their short code was taken",
+                    "type": "integer",
+                    "example": 5
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2026-10-17T12:00:00Z"
+                },
+                "error": {
+                    "description": "Why a failed job stopped",
+                    "type": "string",
+                    "example": "file is not CSV"
+                },
+                "errors": {
+                    "description": "Conflicts and failures, up to IMPORT_MAX_ERRORS",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ImportRowError"
+                    }
+                },
+                "failed": {
+                    "description": "Rows that could not be imported, conflicts excluded",
+                    "type": "integer",
+                    "example": 2
+                },
+                "file_size": {
+                    "description": "Bytes uploaded",
+                    "type": "integer",
+                    "example": 1048576
+                },
+                "finished_at": {
+                    "type": "string",
+                    "example": "2026-10-17T12:00:09Z"
+                },
+                "format": {
+                    "type": "string",
+                    "example": "csv"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "imported": {
+                    "description": "Links created with their original short code",
+                    "type": "integer",
+                    "example": 4990
+                },
+                "on_conflict": {
+                    "description": "skip or rename",
+                    "type": "string",
+                    "example": "skip"
+                },
+                "progress": {
+                    "description": "Percent of the file processed",
+                    "type": "integer",
+                    "example": 50
+                },
+                "renamed": {
+                    "description": "Links created with a new code because theirs was taken",
+                    "type": "integer",
+                    "example": 3
+                },
+                "rows": {
+                    "description": "Rows processed so far",
+                    "type": "integer",
+                    "example": 5000
+                },
+                "started_at": {
+                    "type": "string",
+                    "example": "2026-10-17T12:00:01Z"
+                },
+                "status": {
+                    "description": "pending, running, completed or failed",
+                    "type": "string",
+                    "example": "running"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "2026-10-17T12:00:05Z"
+                },
+                "user_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "model.ImportRowError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "custom alias is already taken"
+                },
+                "error_code": {
+                    "type": "string",
+                    "example": "alias_taken"
+                },
+                "line": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "short_code": {
+                    "type": "string",
+                    "example": "spring-sale"
+                }
+            }
+        },
+        "model.LinkClaim": {
+            "type": "object",
+            "properties": {
+                "anonymous_id": {
+                    "type": "string",
+                    "example": "anon_3f2b9c0e5d7a41c88e6f0b2d4a9c1e57"
+                },
+                "client_ip": {
+                    "description": "IP of the claim request",
+                    "type": "string",
+                    "example": "203.0.113.7"
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2026-10-17T12:00:00Z"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "short_code": {
+                    "type": "string",
+                    "example": "abc12345"
+                },
+                "user_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "model.LoginLockout": {
+            "type": "object",
+            "properties": {
+                "client_ip": {
+                    "description": "IP of the attempt that triggered the lockout",
+                    "type": "string",
+                    "example": "203.0.113.7"
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2026-10-17T12:00:00Z"
+                },
+                "failures": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "locked_until": {
+                    "type": "string",
+                    "example": "2026-10-17T12:15:00Z"
+                },
+                "scope": {
+                    "description": "account or ip",
+                    "type": "string",
+                    "example": "account"
+                },
+                "subject": {
+                    "description": "Email (or MFA user) or client IP",
+                    "type": "string",
+                    "example": "john@example.com"
+                }
+            }
+        },
+        "model.URL": {
+            "type": "object",
+            "properties": {
+                "anonymous_id": {
+                    "description": "Nullable - for anonymous users",
+                    "type": "string",
+                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                },
+                "anonymous_ttl": {
+                    "description": "ExpiresAt is the anonymous link TTL - claiming the link removes it",
+                    "type": "boolean",
+                    "example": true
+                },
+                "clicks": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2025-12-18T10:00:00Z"
+                },
+                "deleted_at": {
+                    "description": "Soft delete - code stays reserved",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "disabled": {
+                    "description": "Paused by the owner - redirects return 410",
+                    "type": "boolean",
+                    "example": false
+                },
+                "domain": {
+                    "description": "Lower-cased host of OriginalURL, kept in sync by BeforeSave",
+                    "type": "string",
+                    "example": "example.com"
+                },
+                "expired_at": {
+                    "description": "Set by the expiry sweeper once the link has expired",
+                    "type": "string",
+                    "example": "2026-01-01T00:00:00Z"
+                },
+                "expires_at": {
+                    "description": "Nullable - link stops working after this time",
+                    "type": "string",
+                    "example": "2025-12-31T23:59:59Z"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "max_clicks": {
+                    "description": "Nullable - link stops working after this many clicks",
+                    "type": "integer",
+                    "example": 100
+                },
+                "original_url": {
+                    "type": "string",
+                    "example": "https://example.com/very/long/path"
+                },
+                "password_protected": {
+                    "description": "Visitors must unlock the link with its password",
+                    "type": "boolean",
+                    "example": false
+                },
+                "redirect_type": {
+                    "description": "301, 302, 307 or 308 - 0 uses the server default",
+                    "type": "integer",
+                    "example": 302
+                },
+                "short_code": {
+                    "type": "string",
+                    "example": "abc12345"
+                },
+                "tags": {
+                    "description": "Lower-cased labels chosen by the owner",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "spring",
+                        "email"
+                    ]
+                },
+                "takedown_reason": {
+                    "type": "string",
+                    "example": "Phishing"
+                },
+                "taken_down_at": {
+                    "description": "Set when an admin takes the link down - redirects return 410",
+                    "type": "string",
+                    "example": "2026-01-01T00:00:00Z"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "2025-12-18T10:00:00Z"
+                },
+                "user_id": {
+                    "description": "Nullable - for logged-in users",
+                    "type": "integer",
+                    "example": 1
                 }
             }
-        }
-    },
-    "definitions": {
-        "handler.AuthResponse": {
+        },
+        "model.User": {
             "type": "object",
             "properties": {
-                "access_token": {
+                "created_at": {
                     "type": "string",
-                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                    "example": "2025-12-18T10:00:00Z"
+                },
+                "disabled": {
+                    "description": "Disabled by an admin - cannot sign in",
+                    "type": "boolean",
+                    "example": false
                 },
                 "email": {
                     "type": "string",
                     "example": "john@example.com"
                 },
+                "email_verified_at": {
+                    "description": "Nil until the user follows the verification email",
+                    "type": "string",
+                    "example": "2025-12-18T10:05:00Z"
+                },
                 "id": {
                     "type": "integer",
                     "example": 1
                 },
-                "message": {
+                "role": {
+                    "description": "user or admin",
                     "type": "string",
-                    "example": "Login successful"
+                    "example": "user"
                 },
-                "refresh_token": {
+                "totp_enabled": {
+                    "description": "Login requires a TOTP or recovery code",
+                    "type": "boolean",
+                    "example": false
+                },
+                "updated_at": {
                     "type": "string",
-                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                    "example": "2025-12-18T10:00:00Z"
                 },
                 "username": {
                     "type": "string",
@@ -359,174 +3799,260 @@ const docTemplate = `{
                 }
             }
         },
-        "handler.ClaimLinksRequest": {
+        "repository.BucketCount": {
             "type": "object",
-            "required": [
-                "anonymous_id"
-            ],
             "properties": {
-                "anonymous_id": {
+                "bucket": {
                     "type": "string",
-                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                    "example": "2025-12-18T00:00:00Z"
+                },
+                "clicks": {
+                    "type": "integer",
+                    "example": 12
                 }
             }
         },
-        "handler.CreateURLRequest": {
+        "repository.ValueCount": {
             "type": "object",
-            "required": [
-                "url"
-            ],
             "properties": {
-                "anonymous_id": {
+                "clicks": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "value": {
                     "type": "string",
-                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                    "example": "google.com"
+                }
+            }
+        },
+        "service.AccountDeletion": {
+            "type": "object",
+            "properties": {
+                "link_policy": {
+                    "type": "string",
+                    "example": "orphan"
                 },
-                "url": {
+                "links": {
+                    "type": "integer",
+                    "example": 12
+                }
+            }
+        },
+        "service.AccountExport": {
+            "type": "object",
+            "properties": {
+                "exported_at": {
                     "type": "string",
-                    "example": "https://example.com/very/long/path"
+                    "example": "2026-10-17T12:00:00Z"
+                },
+                "links": {
+                    "description": "Including deleted links",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.URL"
+                    }
+                },
+                "profile": {
+                    "$ref": "#/definitions/model.User"
                 }
             }
         },
-        "handler.CreateURLResponse": {
+        "service.ClaimPreview": {
             "type": "object",
             "properties": {
-                "anonymous_id": {
+                "count": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "links": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.URL"
+                    }
+                }
+            }
+        },
+        "service.ClickStats": {
+            "type": "object",
+            "properties": {
+                "browsers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.ValueCount"
+                    }
+                },
+                "devices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.ValueCount"
+                    }
+                },
+                "from": {
                     "type": "string",
-                    "example": "550e8400-e29b-41d4-a716-446655440000"
+                    "example": "2025-11-18T00:00:00Z"
                 },
-                "original_url": {
+                "interval": {
                     "type": "string",
-                    "example": "https://example.com/very/long/path"
+                    "example": "day"
+                },
+                "os": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.ValueCount"
+                    }
+                },
+                "referrers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.ValueCount"
+                    }
                 },
                 "short_code": {
                     "type": "string",
                     "example": "abc12345"
                 },
-                "short_url": {
+                "timeline": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repository.BucketCount"
+                    }
+                },
+                "to": {
                     "type": "string",
-                    "example": "http://localhost:8080/abc12345"
+                    "example": "2025-12-18T00:00:00Z"
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 42
                 }
             }
         },
-        "handler.ErrorResponse": {
+        "service.LinkClaimPage": {
             "type": "object",
             "properties": {
-                "error": {
-                    "type": "string",
-                    "example": "Invalid URL format"
+                "claims": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.LinkClaim"
+                    }
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 12
                 }
             }
         },
-        "handler.LoginRequest": {
+        "service.LoginLockoutPage": {
             "type": "object",
-            "required": [
-                "password",
-                "username"
-            ],
             "properties": {
-                "password": {
-                    "type": "string",
-                    "example": "password123"
+                "lockouts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.LoginLockout"
+                    }
                 },
-                "username": {
-                    "type": "string",
-                    "example": "john_doe"
+                "total": {
+                    "type": "integer",
+                    "example": 3
                 }
             }
         },
-        "handler.RefreshRequest": {
+        "service.MFAStatus": {
             "type": "object",
-            "required": [
-                "refresh_token"
-            ],
             "properties": {
-                "refresh_token": {
-                    "type": "string",
-                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                "recovery_codes_left": {
+                    "type": "integer",
+                    "example": 9
+                },
+                "totp_enabled": {
+                    "type": "boolean",
+                    "example": true
                 }
             }
         },
-        "handler.RefreshResponse": {
+        "service.OIDCProvider": {
             "type": "object",
             "properties": {
-                "access_token": {
+                "display_name": {
                     "type": "string",
-                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                    "example": "Company SSO"
                 },
-                "message": {
+                "login_url": {
                     "type": "string",
-                    "example": "Token refreshed successfully"
+                    "example": "/api/auth/oidc/company/login"
                 },
-                "refresh_token": {
+                "name": {
                     "type": "string",
-                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                    "example": "company"
                 }
             }
         },
-        "handler.RegisterRequest": {
+        "service.TOTPEnrollment": {
             "type": "object",
-            "required": [
-                "email",
-                "password",
-                "username"
-            ],
             "properties": {
-                "email": {
+                "otpauth_uri": {
+                    "description": "otpauth:// URI encoded in the QR code",
                     "type": "string",
-                    "example": "john@example.com"
+                    "example": "otpauth://totp/URL%20Shortener:john@example.com?..."
                 },
-                "password": {
+                "qr_code": {
+                    "description": "PNG data URI of the QR code",
                     "type": "string",
-                    "minLength": 6,
-                    "example": "password123"
+                    "example": "data:image/png;base64,iVBORw0KGgo..."
                 },
-                "username": {
+                "secret": {
+                    "description": "Base32 secret for manual entry",
                     "type": "string",
-                    "example": "john_doe"
+                    "example": "JBSWY3DPEHPK3PXP"
                 }
             }
         },
-        "model.URL": {
+        "service.URLPage": {
             "type": "object",
             "properties": {
-                "anonymous_id": {
-                    "description": "Nullable - for anonymous users",
-                    "type": "string",
-                    "example": "550e8400-e29b-41d4-a716-446655440000"
-                },
-                "clicks": {
-                    "type": "integer",
-                    "example": 42
+                "has_more": {
+                    "type": "boolean",
+                    "example": true
                 },
-                "created_at": {
+                "next_cursor": {
                     "type": "string",
-                    "example": "2025-12-18T10:00:00Z"
+                    "example": "eyJzIjoiY3JlYXRlZCIsInQiOiIyMDI2LTEwLTE3VDEwOjAwOjAwWiIsImlkIjo0Mn0"
                 },
-                "id": {
+                "total": {
                     "type": "integer",
-                    "example": 1
-                },
-                "original_url": {
-                    "type": "string",
-                    "example": "https://example.com/very/long/path"
-                },
-                "short_code": {
-                    "type": "string",
-                    "example": "abc12345"
-                },
-                "updated_at": {
-                    "type": "string",
-                    "example": "2025-12-18T10:00:00Z"
+                    "example": 124
                 },
-                "user_id": {
-                    "description": "Nullable - for logged-in users",
+                "urls": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.URL"
+                    }
+                }
+            }
+        },
+        "service.UserPage": {
+            "type": "object",
+            "properties": {
+                "total": {
                     "type": "integer",
-                    "example": 1
+                    "example": 42
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.User"
+                    }
                 }
             }
         }
     },
     "securityDefinitions": {
+        "ApiKeyAuth": {
+            "description": "Personal API key (usk_...) with the scope the endpoint needs.",
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
         "BearerAuth": {
             "description": "Type \"Bearer\" followed by a space and JWT token.",
             "type": "apiKey",
@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestRedisCache(t *testing.T) (Cache, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	c, err := NewRedisCache("redis://"+server.Addr()+"/0", "test:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, server
+}
+
+func TestRedisCacheGetSet(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestRedisCache(t)
+
+	if _, err := c.Get(ctx, "url:abc"); !errors.Is(err, ErrMiss) {
+		t.Fatalf("Get of a missing key: err = %v, want ErrMiss", err)
+	}
+
+	if err := c.Set(ctx, "url:abc", []byte("value"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	value, err := c.Get(ctx, "url:abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(value) != "value" {
+		t.Errorf("Get = %q, want %q", value, "value")
+	}
+
+	// Keys are stored under the prefix with the given TTL
+	if !server.Exists("test:url:abc") {
+		t.Error("key is not stored under the prefix")
+	}
+	if ttl := server.TTL("test:url:abc"); ttl != time.Minute {
+		t.Errorf("TTL = %s, want 1m", ttl)
+	}
+
+	// Set replaces the value
+	if err := c.Set(ctx, "url:abc", []byte("other"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := c.Get(ctx, "url:abc"); string(value) != "other" {
+		t.Errorf("Get after replace = %q, want %q", value, "other")
+	}
+}
+
+func TestRedisCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestRedisCache(t)
+
+	if err := c.Set(ctx, "url:abc", []byte("value"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	server.FastForward(time.Minute + time.Second)
+	if _, err := c.Get(ctx, "url:abc"); !errors.Is(err, ErrMiss) {
+		t.Errorf("Get after the TTL: err = %v, want ErrMiss", err)
+	}
+}
+
+func TestRedisCacheDelete(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestRedisCache(t)
+
+	for _, key := range []string{"url:a", "url:b", "url:c"} {
+		if err := c.Set(ctx, key, []byte(key), time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Delete(ctx, "url:a", "url:b", "url:missing"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"url:a", "url:b"} {
+		if _, err := c.Get(ctx, key); !errors.Is(err, ErrMiss) {
+			t.Errorf("Get(%s) after delete: err = %v, want ErrMiss", key, err)
+		}
+	}
+	if _, err := c.Get(ctx, "url:c"); err != nil {
+		t.Errorf("key that was not deleted: %v", err)
+	}
+
+	// Deleting nothing is a no-op
+	if err := c.Delete(ctx); err != nil {
+		t.Errorf("Delete without keys: %v", err)
+	}
+}
+
+func TestRedisCachePrefixes(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	first, err := NewRedisCache("redis://"+server.Addr()+"/0", "first:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewRedisCache("redis://"+server.Addr()+"/0", "second:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := first.Set(ctx, "url:abc", []byte("first"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.Get(ctx, "url:abc"); !errors.Is(err, ErrMiss) {
+		t.Errorf("key of another prefix: err = %v, want ErrMiss", err)
+	}
+	if err := second.Delete(ctx, "url:abc"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.Get(ctx, "url:abc"); err != nil {
+		t.Errorf("delete under another prefix removed the key: %v", err)
+	}
+}
+
+func TestRedisCacheErrors(t *testing.T) {
+	if _, err := NewRedisCache("not-a-url", "test:"); err == nil {
+		t.Error("invalid URL: want an error")
+	}
+
+	// The server is checked when connecting
+	server := miniredis.RunT(t)
+	addr := server.Addr()
+	server.Close()
+	if _, err := NewRedisCache("redis://"+addr+"/0", "test:"); err == nil {
+		t.Error("unreachable server: want an error")
+	}
+
+	// Errors other than a miss are returned as they are
+	ctx := context.Background()
+	c, server := newTestRedisCache(t)
+	server.SetError("server down")
+	if _, err := c.Get(ctx, "url:abc"); err == nil || errors.Is(err, ErrMiss) {
+		t.Errorf("Get from a failing server: err = %v, want a server error", err)
+	}
+	if err := c.Set(ctx, "url:abc", []byte("value"), time.Minute); err == nil {
+		t.Error("Set on a failing server: want an error")
+	}
+}
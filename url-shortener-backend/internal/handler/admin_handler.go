@@ -0,0 +1,333 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"url-shortener/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	urlService   service.URLService
+	userService  service.UserService
+	tokenService service.TokenService
+	loginGuard   service.LoginGuard
+}
+
+func NewAdminHandler(urlService service.URLService, userService service.UserService, tokenService service.TokenService, loginGuard service.LoginGuard) *AdminHandler {
+	return &AdminHandler{
+		urlService:   urlService,
+		userService:  userService,
+		tokenService: tokenService,
+		loginGuard:   loginGuard,
+	}
+}
+
+type TakedownRequest struct {
+	Reason string `json:"reason" binding:"required" example:"Phishing"`
+}
+
+type UpdateUserAccessRequest struct {
+	Role     *string `json:"role" example:"admin"`     // user or admin
+	Disabled *bool   `json:"disabled" example:"false"` // Disabling also signs the user out everywhere
+}
+
+// ListURLs godoc
+// @Summary      List all URLs (admin)
+// @Description  Get a page of every user's and anonymous visitor's links, with the same paging, sorting and filters as GET /api/urls
+// @Tags         admin
+// @Produce      json
+// @Param        user_id query int false "Only links of this user"
+// @Param        cursor query string false "next_cursor from the previous page"
+// @Param        limit query int false "Page size (max 200)" default(50)
+// @Param        sort query string false "Sort key: created, updated or clicks" default(created)
+// @Param        order query string false "Sort order: asc or desc" default(desc)
+// @Param        status query string false "Filter: active, disabled, expired, protected, deleted or taken_down"
+// @Param        domain query string false "Destination domain, including its subdomains"
+// @Param        created_from query string false "Created at or after (RFC3339)"
+// @Param        created_to query string false "Created before (RFC3339)"
+// @Param        q query string false "Search in short code and destination URL"
+// @Success      200 {object} service.URLPage
+// @Failure      400 {object} ErrorResponse
+// @Failure      403 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/admin/urls [get]
+func (h *AdminHandler) ListURLs(c *gin.Context) {
+	query, ok := parseURLListQuery(c)
+	if !ok {
+		return
+	}
+
+	if userIDParam := c.Query("user_id"); userIDParam != "" {
+		userID, err := strconv.ParseUint(userIDParam, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "user_id must be a number"})
+			return
+		}
+		id := uint(userID)
+		query.UserID = &id
+	}
+
+	respondURLPage(c, h.urlService, query)
+}
+
+// TakedownURL godoc
+// @Summary      Take down a link (admin)
+// @Description  Block a link for every visitor; redirects return 410 until the takedown is lifted. The owner cannot undo it.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        code path string true "Short code"
+// @Param        request body TakedownRequest true "Reason shown to the owner"
+// @Success      200 {object} model.URL
+// @Failure      400 {object} ErrorResponse
+// @Failure      403 {object} ErrorResponse
+// @Failure      404 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/admin/urls/{code}/takedown [post]
+func (h *AdminHandler) TakedownURL(c *gin.Context) {
+	var req TakedownRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	urlEntry, err := h.urlService.TakedownURL(c.Param("code"), req.Reason)
+	if err != nil {
+		c.JSON(urlErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	log.Printf("Admin %d took down link %s: %s", c.MustGet("userID").(uint), urlEntry.ShortCode, req.Reason)
+	c.JSON(http.StatusOK, urlEntry)
+}
+
+// LiftTakedown godoc
+// @Summary      Lift a takedown (admin)
+// @Description  Let a taken-down link redirect again
+// @Tags         admin
+// @Produce      json
+// @Param        code path string true "Short code"
+// @Success      200 {object} model.URL
+// @Failure      403 {object} ErrorResponse
+// @Failure      404 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/admin/urls/{code}/takedown [delete]
+func (h *AdminHandler) LiftTakedown(c *gin.Context) {
+	urlEntry, err := h.urlService.LiftTakedown(c.Param("code"))
+	if err != nil {
+		c.JSON(urlErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	log.Printf("Admin %d lifted the takedown of link %s", c.MustGet("userID").(uint), urlEntry.ShortCode)
+	c.JSON(http.StatusOK, urlEntry)
+}
+
+// ListUsers godoc
+// @Summary      List users (admin)
+// @Description  Get a page of users, newest first
+// @Tags         admin
+// @Produce      json
+// @Param        q query string false "Search in username and email"
+// @Param        role query string false "Filter by role: user or admin"
+// @Param        disabled query bool false "Filter by disabled state"
+// @Param        limit query int false "Page size (max 200)" default(50)
+// @Param        offset query int false "Number of users to skip" default(0)
+// @Success      200 {object} service.UserPage
+// @Failure      400 {object} ErrorResponse
+// @Failure      403 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/admin/users [get]
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	query := service.UserListQuery{
+		Search: c.Query("q"),
+		Role:   c.Query("role"),
+	}
+
+	var err error
+	if disabled := c.Query("disabled"); disabled != "" {
+		value, err := strconv.ParseBool(disabled)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "disabled must be true or false"})
+			return
+		}
+		query.Disabled = &value
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "limit must be a number"})
+			return
+		}
+	}
+	if offset := c.Query("offset"); offset != "" {
+		if query.Offset, err = strconv.Atoi(offset); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "offset must be a number"})
+			return
+		}
+	}
+
+	page, err := h.userService.ListUsers(query)
+	if err != nil {
+		c.JSON(userErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// UpdateUser godoc
+// @Summary      Change a user's role or disable the account (admin)
+// @Description  Disabling an account blocks sign-in, refresh and API keys and revokes its sessions. Access tokens already issued expire within 15 minutes.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "User ID"
+// @Param        request body UpdateUserAccessRequest true "Fields to change"
+// @Success      200 {object} model.User
+// @Failure      400 {object} ErrorResponse
+// @Failure      403 {object} ErrorResponse
+// @Failure      404 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/admin/users/{id} [patch]
+func (h *AdminHandler) UpdateUser(c *gin.Context) {
+	actorID := c.MustGet("userID").(uint)
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: service.ErrUserNotFound.Error()})
+		return
+	}
+
+	var req UpdateUserAccessRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	user, err := h.userService.UpdateUserAccess(actorID, uint(userID), service.UpdateUserAccessInput{
+		Role:     req.Role,
+		Disabled: req.Disabled,
+	})
+	if err != nil {
+		c.JSON(userErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if user.Disabled {
+		if _, err := h.tokenService.LogoutAll(user.ID); err != nil {
+			log.Printf("Failed to revoke sessions of disabled user %d: %v", user.ID, err)
+		}
+	}
+
+	log.Printf("Admin %d updated user %d: role=%s disabled=%t", actorID, user.ID, user.Role, user.Disabled)
+	c.JSON(http.StatusOK, user)
+}
+
+// ListLockouts godoc
+// @Summary      List login lockouts (admin)
+// @Description  Get a page of lockouts caused by repeated failed sign-ins, newest first. Subjects are emails (or mfa:<user id> for the two-factor step) and client IPs.
+// @Tags         admin
+// @Produce      json
+// @Param        scope query string false "Filter by scope: account or ip"
+// @Param        limit query int false "Page size (max 200)" default(50)
+// @Param        offset query int false "Number of lockouts to skip" default(0)
+// @Success      200 {object} service.LoginLockoutPage
+// @Failure      400 {object} ErrorResponse
+// @Failure      403 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/admin/lockouts [get]
+func (h *AdminHandler) ListLockouts(c *gin.Context) {
+	var limit, offset int
+	var err error
+	if value := c.Query("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "limit must be a number"})
+			return
+		}
+	}
+	if value := c.Query("offset"); value != "" {
+		if offset, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "offset must be a number"})
+			return
+		}
+	}
+
+	page, err := h.loginGuard.ListLockouts(c.Query("scope"), limit, offset)
+	if err != nil {
+		c.JSON(userErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// ListLinkClaims godoc
+// @Summary      List link claims (admin)
+// @Description  Get a page of the audit trail of anonymous links claimed by users, newest first
+// @Tags         admin
+// @Produce      json
+// @Param        user_id query int false "Filter by claiming user"
+// @Param        anonymous_id query string false "Filter by anonymous identity"
+// @Param        short_code query string false "Filter by short code"
+// @Param        limit query int false "Page size (max 200)" default(50)
+// @Param        offset query int false "Number of claims to skip" default(0)
+// @Success      200 {object} service.LinkClaimPage
+// @Failure      400 {object} ErrorResponse
+// @Failure      403 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/admin/claims [get]
+func (h *AdminHandler) ListLinkClaims(c *gin.Context) {
+	query := service.LinkClaimQuery{
+		AnonymousID: c.Query("anonymous_id"),
+		ShortCode:   c.Query("short_code"),
+	}
+
+	var err error
+	if value := c.Query("user_id"); value != "" {
+		userID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "user_id must be a number"})
+			return
+		}
+		query.UserID = uint(userID)
+	}
+	if value := c.Query("limit"); value != "" {
+		if query.Limit, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "limit must be a number"})
+			return
+		}
+	}
+	if value := c.Query("offset"); value != "" {
+		if query.Offset, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "offset must be a number"})
+			return
+		}
+	}
+
+	page, err := h.urlService.ListLinkClaims(query)
+	if err != nil {
+		c.JSON(userErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// userErrorStatus maps user service errors to HTTP status codes
+func userErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrCannotModifySelf):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrInvalidRole),
+		errors.Is(err, service.ErrInvalidListQuery):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
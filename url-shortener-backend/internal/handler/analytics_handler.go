@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"url-shortener/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AnalyticsHandler struct {
+	service service.AnalyticsService
+}
+
+func NewAnalyticsHandler(service service.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{service: service}
+}
+
+// GetClickStats godoc
+// @Summary      Get click analytics
+// @Description  Time-bucketed click counts and breakdowns by referrer domain, browser, OS and device type for a short URL owned by the authenticated user
+// @Tags         analytics
+// @Produce      json
+// @Param        code path string true "Short code"
+// @Param        interval query string false "Bucket size: hour, day or week" default(day)
+// @Param        from query string false "Start of range (RFC3339), defaults to 30 days before 'to'"
+// @Param        to query string false "End of range (RFC3339), defaults to now"
+// @Param        limit query int false "Max entries per breakdown" default(10)
+// @Success      200 {object} service.ClickStats
+// @Failure      400 {object} ErrorResponse
+// @Failure      403 {object} ErrorResponse
+// @Failure      404 {object} ErrorResponse
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /api/urls/{code}/clicks [get]
+func (h *AnalyticsHandler) GetClickStats(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	code := c.Param("code")
+
+	query := service.ClickStatsQuery{Interval: c.Query("interval")}
+
+	var err error
+	if from := c.Query("from"); from != "" {
+		if query.From, err = time.Parse(time.RFC3339, from); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "from must be an RFC3339 timestamp"})
+			return
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		if query.To, err = time.Parse(time.RFC3339, to); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "to must be an RFC3339 timestamp"})
+			return
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "limit must be a number"})
+			return
+		}
+	}
+
+	stats, err := h.service.GetClickStats(code, userID, query)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidStatsQuery) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(urlErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"url-shortener/internal/model"
+	"url-shortener/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	service service.APIKeyService
+}
+
+func NewAPIKeyHandler(service service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required" example:"CI pipeline"`
+	Scopes    []string   `json:"scopes" binding:"required" example:"links:read,links:write"`
+	ExpiresAt *time.Time `json:"expires_at" example:"2026-12-31T23:59:59Z"` // Optional
+}
+
+type CreateAPIKeyResponse struct {
+	model.APIKey
+	Key string `json:"key" example:"usk_Xb3kq9Zp_2hG8sQmV0cLr5TnWy7KdB4fJ1aXeP6uZ"` // Shown only once
+}
+
+// CreateAPIKey godoc
+// @Summary      Create API key
+// @Description  Create a personal API key for scripts and integrations. Scopes: links:read, links:write, analytics:read. The key is only returned in this response. Send it as "X-API-Key: <key>" or "Authorization: Bearer <key>".
+// @Tags         api-keys
+// @Accept       json
+// @Produce      json
+// @Param        request body CreateAPIKeyRequest true "Key name and scopes"
+// @Success      201 {object} CreateAPIKeyResponse
+// @Failure      400 {object} ErrorResponse
+// @Failure      401 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	key, rawKey, err := h.service.CreateKey(userID, service.CreateAPIKeyInput{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		c.JSON(apiKeyErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateAPIKeyResponse{APIKey: *key, Key: rawKey})
+}
+
+// ListAPIKeys godoc
+// @Summary      List API keys
+// @Description  List the authenticated user's API keys, including revoked ones. Keys themselves are never returned, only their prefix.
+// @Tags         api-keys
+// @Produce      json
+// @Success      200 {array} model.APIKey
+// @Failure      401 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/keys [get]
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	keys, err := h.service.ListKeys(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey godoc
+// @Summary      Revoke API key
+// @Description  Revoke one of the authenticated user's API keys. It stops working immediately.
+// @Tags         api-keys
+// @Produce      json
+// @Param        id path int true "API key ID"
+// @Success      200 {object} map[string]interface{} "API key revoked"
+// @Failure      401 {object} ErrorResponse
+// @Failure      404 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	keyID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: service.ErrAPIKeyNotFound.Error()})
+		return
+	}
+
+	if err := h.service.RevokeKey(userID, uint(keyID)); err != nil {
+		c.JSON(apiKeyErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
+
+// apiKeyErrorStatus maps API key service errors to HTTP status codes
+func apiKeyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrAPIKeyNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidScope),
+		errors.Is(err, service.ErrInvalidAPIKeyInput):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
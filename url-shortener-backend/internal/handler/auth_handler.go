@@ -1,22 +1,36 @@
 package handler
 
 import (
+	"errors"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
+	"time"
 	"url-shortener/internal/middleware"
+	"url-shortener/internal/model"
 	"url-shortener/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AuthHandler struct {
-	userService service.UserService
-	urlService  service.URLService
+	userService    service.UserService
+	urlService     service.URLService
+	tokenService   service.TokenService
+	accountService service.AccountService
+	mfaService     service.MFAService
+	loginGuard     service.LoginGuard
 }
 
-func NewAuthHandler(userService service.UserService, urlService service.URLService) *AuthHandler {
+func NewAuthHandler(userService service.UserService, urlService service.URLService, tokenService service.TokenService, accountService service.AccountService, mfaService service.MFAService, loginGuard service.LoginGuard) *AuthHandler {
 	return &AuthHandler{
-		userService: userService,
-		urlService:  urlService,
+		userService:    userService,
+		urlService:     urlService,
+		tokenService:   tokenService,
+		accountService: accountService,
+		mfaService:     mfaService,
+		loginGuard:     loginGuard,
 	}
 }
 
@@ -24,11 +38,15 @@ type RegisterRequest struct {
 	Username string `json:"username" binding:"required" example:"john_doe"`
 	Email    string `json:"email" binding:"required,email" example:"john@example.com"`
 	Password string `json:"password" binding:"required,min=6" example:"password123"`
+	// Optional - labels the login session; derived from the User-Agent when empty
+	DeviceLabel string `json:"device_label" example:"Work laptop"`
 }
 
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email" example:"john@example.com"`
 	Password string `json:"password" binding:"required" example:"password123"`
+	// Optional - labels the login session; derived from the User-Agent when empty
+	DeviceLabel string `json:"device_label" example:"Work laptop"`
 }
 
 type AuthResponse struct {
@@ -40,13 +58,38 @@ type AuthResponse struct {
 	Message      string `json:"message" example:"Login successful"`
 }
 
+// MFAChallengeResponse is returned by login instead of tokens when the account
+// has two-factor authentication enabled
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required" example:"true"`
+	MFAToken    string `json:"mfa_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."` // Valid for 5 minutes
+	Message     string `json:"message" example:"Enter the code from your authenticator app"`
+}
+
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	// A 6-digit code from the authenticator app, or a recovery code
+	Code string `json:"code" binding:"required" example:"123456"`
+	// Optional - labels the login session; derived from the User-Agent when empty
+	DeviceLabel string `json:"device_label" example:"Work laptop"`
+}
+
 type ClaimLinksRequest struct {
-	AnonymousID string `json:"anonymous_id" binding:"required" example:"550e8400-e29b-41d4-a716-446655440000"`
+	// Optional - must match the anonymous token, which is what selects the links
+	AnonymousID string `json:"anonymous_id" example:"anon_3f2b9c0e5d7a41c88e6f0b2d4a9c1e57"`
+	// Optional - claim only these links; every claimable link when empty
+	ShortCodes []string `json:"short_codes" example:"abc12345,xyz67890"`
+}
+
+type ClaimLinksResponse struct {
+	Message string `json:"message" example:"Links claimed successfully"`
+	UserID  uint   `json:"user_id" example:"1"`
+	service.ClaimResult
 }
 
 // Register godoc
 // @Summary      Register new user
-// @Description  Create a new user account
+// @Description  Create a new user account. A verification link is emailed to the address.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -67,16 +110,14 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	// Generate JWT tokens
-	accessToken, err := middleware.GenerateAccessToken(user.ID, user.Username)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to generate access token"})
-		return
+	if err := h.accountService.SendVerificationEmail(user.ID); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
 	}
 
-	refreshToken, err := middleware.GenerateRefreshToken(user.ID, user.Username)
+	// Generate JWT tokens for a new session
+	tokens, err := h.tokenService.IssueTokens(user, req.DeviceLabel, c.Request.UserAgent())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to generate refresh token"})
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to generate tokens"})
 		return
 	}
 
@@ -84,21 +125,23 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		ID:           user.ID,
 		Username:     user.Username,
 		Email:        user.Email,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 		Message:      "Registration successful",
 	})
 }
 
 // Login godoc
 // @Summary      User login
-// @Description  Authenticate user with username and password
+// @Description  Authenticate user with username and password. If the account has two-factor authentication enabled, the response is an MFAChallengeResponse instead; exchange its mfa_token at /api/auth/login/mfa. Repeated failures for an account or client IP are slowed down and then locked out; the Retry-After header says how many seconds to wait.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        request body LoginRequest true "Login credentials"
 // @Success      200 {object} AuthResponse
 // @Failure      401 {object} ErrorResponse
+// @Failure      403 {object} ErrorResponse
+// @Failure      429 {object} ErrorResponse
 // @Router       /api/auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
@@ -107,22 +150,122 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
+	attempt := service.LoginAttempt{Account: req.Email, ClientIP: c.ClientIP()}
+	charge, wait := h.loginGuard.Check(ctx, attempt)
+	if wait > 0 {
+		respondTooManyAttempts(c, wait)
+		return
+	}
+
 	user, err := h.userService.Login(req.Email, req.Password)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+		status := http.StatusUnauthorized
+		if errors.Is(err, service.ErrAccountDisabled) {
+			status = http.StatusForbidden
+		}
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			if wait := h.loginGuard.RecordFailure(ctx, charge); wait > 0 {
+				setRetryAfter(c, wait)
+			}
+		} else {
+			h.loginGuard.Release(ctx, charge)
+		}
+		c.JSON(status, ErrorResponse{Error: err.Error()})
+		return
+	}
+	h.loginGuard.RecordSuccess(ctx, charge)
+
+	h.startSession(c, user, req.DeviceLabel)
+}
+
+// startSession finishes a first-factor login: it returns tokens, or an MFA
+// challenge when the account has two-factor authentication enabled
+func (h *AuthHandler) startSession(c *gin.Context, user *model.User, deviceLabel string) {
+	if user.TOTPEnabled {
+		mfaToken, err := h.mfaService.StartLoginChallenge(user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to start two-factor login"})
+			return
+		}
+		c.JSON(http.StatusOK, MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			Message:     "Enter the code from your authenticator app",
+		})
+		return
+	}
+
+	h.respondWithSession(c, user, deviceLabel)
+}
+
+// LoginMFA godoc
+// @Summary      Complete two-factor login
+// @Description  Exchange the mfa_token from /api/auth/login and a code from the authenticator app (or an unused recovery code) for access and refresh tokens
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body LoginMFARequest true "Challenge token and code"
+// @Success      200 {object} AuthResponse
+// @Failure      401 {object} ErrorResponse
+// @Failure      403 {object} ErrorResponse
+// @Failure      429 {object} ErrorResponse
+// @Router       /api/auth/login/mfa [post]
+func (h *AuthHandler) LoginMFA(c *gin.Context) {
+	var req LoginMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	// Generate JWT tokens
-	accessToken, err := middleware.GenerateAccessToken(user.ID, user.Username)
+	userID, err := h.mfaService.ChallengeUserID(req.MFAToken)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to generate access token"})
+		c.JSON(mfaErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	attempt := service.LoginAttempt{Account: service.MFAAccount(userID), ClientIP: c.ClientIP()}
+	charge, wait := h.loginGuard.Check(ctx, attempt)
+	if wait > 0 {
+		respondTooManyAttempts(c, wait)
 		return
 	}
 
-	refreshToken, err := middleware.GenerateRefreshToken(user.ID, user.Username)
+	user, err := h.mfaService.CompleteLoginChallenge(req.MFAToken, req.Code)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to generate refresh token"})
+		if errors.Is(err, service.ErrInvalidMFACode) {
+			if wait := h.loginGuard.RecordFailure(ctx, charge); wait > 0 {
+				setRetryAfter(c, wait)
+			}
+		} else {
+			h.loginGuard.Release(ctx, charge)
+		}
+		c.JSON(mfaErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+	h.loginGuard.RecordSuccess(ctx, charge)
+
+	h.respondWithSession(c, user, req.DeviceLabel)
+}
+
+// respondTooManyAttempts rejects a sign-in while the account or IP is blocked
+func respondTooManyAttempts(c *gin.Context, wait time.Duration) {
+	setRetryAfter(c, wait)
+	c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: "Too many failed login attempts, try again later"})
+}
+
+// setRetryAfter sets the Retry-After header in whole seconds, rounded up
+func setRetryAfter(c *gin.Context, wait time.Duration) {
+	seconds := int64((wait + time.Second - 1) / time.Second)
+	c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+}
+
+// respondWithSession starts a new session for a fully authenticated user
+func (h *AuthHandler) respondWithSession(c *gin.Context, user *model.User, deviceLabel string) {
+	tokens, err := h.tokenService.IssueTokens(user, deviceLabel, c.Request.UserAgent())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to generate tokens"})
 		return
 	}
 
@@ -130,21 +273,50 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		ID:           user.ID,
 		Username:     user.Username,
 		Email:        user.Email,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 		Message:      "Login successful",
 	})
 }
 
+// PreviewClaimLinks godoc
+// @Summary      Preview claimable links
+// @Description  List the links created under this client's anonymous identity (anon_token cookie or X-Anonymous-Token header) that can be claimed
+// @Tags         auth
+// @Produce      json
+// @Param        X-Anonymous-Token header string false "Anonymous token, for clients without the anon_token cookie"
+// @Success      200 {object} service.ClaimPreview
+// @Failure      400 {object} ErrorResponse
+// @Failure      401 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/auth/claim-links [get]
+func (h *AuthHandler) PreviewClaimLinks(c *gin.Context) {
+	anonymousID, ok := claimIdentity(c, "")
+	if !ok {
+		return
+	}
+
+	preview, err := h.urlService.PreviewClaim(anonymousID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
 // ClaimLinks godoc
 // @Summary      Claim anonymous links
-// @Description  Transfer ownership of anonymous links to logged-in user
+// @Description  Transfer ownership of links created under this client's anonymous identity (anon_token cookie or X-Anonymous-Token header) to the logged-in user: the listed short_codes, or all of them when none are given. The claim is atomic and recorded in the audit trail; repeating it is safe and reports the links as already_claimed.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
-// @Param        request body ClaimLinksRequest true "Anonymous ID from cookie/localStorage"
-// @Success      200 {object} map[string]interface{} "Links claimed successfully"
+// @Param        request body ClaimLinksRequest false "Optional short codes to claim, and anonymous_id checked against the anonymous token"
+// @Param        X-Anonymous-Token header string false "Anonymous token, for clients without the anon_token cookie"
+// @Success      200 {object} ClaimLinksResponse
 // @Failure      400 {object} ErrorResponse
+// @Failure      401 {object} ErrorResponse
+// @Failure      403 {object} ErrorResponse
 // @Security     BearerAuth
 // @Router       /api/auth/claim-links [post]
 func (h *AuthHandler) ClaimLinks(c *gin.Context) {
@@ -157,20 +329,52 @@ func (h *AuthHandler) ClaimLinks(c *gin.Context) {
 	userID := userIDInterface.(uint)
 
 	var req ClaimLinksRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	if err := h.urlService.ClaimAnonymousURLs(userID, req.AnonymousID); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	anonymousID, ok := claimIdentity(c, req.AnonymousID)
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Links claimed successfully",
-		"user_id": userID,
+	result, err := h.urlService.ClaimAnonymousURLs(service.ClaimInput{
+		UserID:      userID,
+		AnonymousID: anonymousID,
+		ShortCodes:  req.ShortCodes,
+		ClientIP:    c.ClientIP(),
 	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidClaim) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ClaimLinksResponse{
+		Message:     "Links claimed successfully",
+		UserID:      userID,
+		ClaimResult: *result,
+	})
+}
+
+// claimIdentity returns the request's signed anonymous identity. Only the
+// signed identity selects links; a bare ID proves nothing. On failure it
+// writes the error response and returns false.
+func claimIdentity(c *gin.Context, requested string) (string, bool) {
+	anonymousID, ok := middleware.GetAnonymousID(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "No anonymous identity to claim links from"})
+		return "", false
+	}
+	if requested != "" && requested != anonymousID {
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: service.ErrAnonymousIDMismatch.Error()})
+		return "", false
+	}
+	return anonymousID, true
 }
 
 type RefreshRequest struct {
@@ -185,7 +389,7 @@ type RefreshResponse struct {
 
 // Refresh godoc
 // @Summary      Refresh access token
-// @Description  Get new access token and refresh token using valid refresh token
+// @Description  Exchange a refresh token for a new access token and refresh token. The presented refresh token is revoked; presenting it again revokes the whole session.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -200,29 +404,203 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
-	// Validate refresh token
-	claims, err := middleware.ValidateToken(req.RefreshToken)
+	tokens, err := h.tokenService.Refresh(req.RefreshToken)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid or expired refresh token"})
+		c.JSON(tokenErrorStatus(err), ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	// Generate new tokens
-	accessToken, err := middleware.GenerateAccessToken(claims.UserID, claims.Username)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to generate access token"})
+	c.JSON(http.StatusOK, RefreshResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		Message:      "Token refreshed successfully",
+	})
+}
+
+// Logout godoc
+// @Summary      Log out
+// @Description  Revoke the session the refresh token belongs to. Access tokens already issued stay valid until they expire.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body RefreshRequest true "Refresh token of the session"
+// @Success      200 {object} map[string]interface{} "Logged out"
+// @Failure      401 {object} ErrorResponse
+// @Router       /api/auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.tokenService.Logout(req.RefreshToken); err != nil {
+		c.JSON(tokenErrorStatus(err), ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	refreshToken, err := middleware.GenerateRefreshToken(claims.UserID, claims.Username)
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// LogoutAll godoc
+// @Summary      Log out everywhere
+// @Description  Revoke every refresh token of the authenticated user
+// @Tags         auth
+// @Produce      json
+// @Success      200 {object} map[string]interface{} "Number of revoked tokens"
+// @Failure      401 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	revoked, err := h.tokenService.LogoutAll(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to generate refresh token"})
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to revoke sessions"})
 		return
 	}
 
-	c.JSON(http.StatusOK, RefreshResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		Message:      "Token refreshed successfully",
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Logged out of all sessions",
+		"revoked": revoked,
 	})
 }
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email" example:"john@example.com"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required" example:"Vd3kq9ZpX1mN0bQeR7tY2uI8oP4aS6dF"`
+	NewPassword string `json:"new_password" binding:"required,min=6" example:"newpassword123"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required" example:"Vd3kq9ZpX1mN0bQeR7tY2uI8oP4aS6dF"`
+}
+
+// ForgotPassword godoc
+// @Summary      Request a password reset
+// @Description  Email a single-use password reset link. Always returns 202, whether or not an account uses the address.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body ForgotPasswordRequest true "Account email"
+// @Success      202 {object} map[string]interface{} "Reset link sent if the account exists"
+// @Failure      400 {object} ErrorResponse
+// @Router       /api/auth/password/forgot [post]
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.accountService.RequestPasswordReset(req.Email); err != nil {
+		log.Println("Failed to start password reset:", err)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If an account uses this email, a reset link has been sent",
+	})
+}
+
+// ResetPassword godoc
+// @Summary      Reset password
+// @Description  Set a new password with the token from the reset email. The token works once, and every session of the account is signed out.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body ResetPasswordRequest true "Reset token and new password"
+// @Success      200 {object} map[string]interface{} "Password changed"
+// @Failure      400 {object} ErrorResponse
+// @Router       /api/auth/password/reset [post]
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.accountService.ResetPassword(req.Token, req.NewPassword); err != nil {
+		c.JSON(accountErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed, please log in again"})
+}
+
+// VerifyEmail godoc
+// @Summary      Verify email address
+// @Description  Confirm the account's email address with the token from the verification email
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body VerifyEmailRequest true "Verification token"
+// @Success      200 {object} map[string]interface{} "Email verified"
+// @Failure      400 {object} ErrorResponse
+// @Router       /api/auth/email/verify [post]
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.accountService.VerifyEmail(req.Token); err != nil {
+		c.JSON(accountErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// ResendVerification godoc
+// @Summary      Resend verification email
+// @Description  Email a new verification link to the authenticated user. Earlier links stop working.
+// @Tags         auth
+// @Produce      json
+// @Success      202 {object} map[string]interface{} "Verification email sent"
+// @Failure      409 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/auth/email/resend [post]
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	if err := h.accountService.SendVerificationEmail(userID); err != nil {
+		c.JSON(accountErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Verification email sent"})
+}
+
+// accountErrorStatus maps account service errors to HTTP status codes
+func accountErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidUserToken),
+		errors.Is(err, service.ErrWeakPassword):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrEmailAlreadyVerified):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrAccountDisabled):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrUserNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// tokenErrorStatus maps token service errors to HTTP status codes
+func tokenErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidRefreshToken),
+		errors.Is(err, service.ErrRefreshTokenReused):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrAccountDisabled):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRespondTooManyAttempts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		wait time.Duration
+		want string
+	}{
+		{time.Second, "1"},
+		{1500 * time.Millisecond, "2"}, // Rounded up so clients never retry early
+		{time.Millisecond, "1"},
+		{15 * time.Minute, "900"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		respondTooManyAttempts(c, tt.wait)
+
+		if w.Code != http.StatusTooManyRequests {
+			t.Errorf("wait %s: status %d, want %d", tt.wait, w.Code, http.StatusTooManyRequests)
+		}
+		if got := w.Header().Get("Retry-After"); got != tt.want {
+			t.Errorf("wait %s: Retry-After = %q, want %q", tt.wait, got, tt.want)
+		}
+	}
+}
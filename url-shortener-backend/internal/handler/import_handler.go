@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"url-shortener/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ImportHandler struct {
+	service service.ImportService
+}
+
+func NewImportHandler(service service.ImportService) *ImportHandler {
+	return &ImportHandler{service: service}
+}
+
+// StartImport godoc
+// @Summary      Import links from a file
+// @Description  Uploads a CSV or NDJSON file in the format written by GET /api/urls/export and imports it in the background.
+// @Description  Send the file as the "file" part of a multipart form, or as the raw request body.
+// @Description  The format is taken from the format parameter, the file name or the Content-Type.
+// @Description  Short codes are kept when free. Taken codes are reported as conflicts (on_conflict=skip, the default)
+// @Description  or replaced by a random code (on_conflict=rename). Poll GET /api/urls/imports/{id} for progress.
+// @Tags         urls
+// @Accept       mpfd
+// @Accept       text/csv
+// @Accept       application/x-ndjson
+// @Produce      json
+// @Param        file formData file false "CSV or NDJSON file"
+// @Param        format query string false "csv or ndjson" Enums(csv, ndjson)
+// @Param        on_conflict query string false "skip or rename" Enums(skip, rename)
+// @Success      202 {object} model.ImportJob
+// @Failure      400 {object} ErrorResponse
+// @Failure      401 {object} ErrorResponse
+// @Failure      413 {object} ErrorResponse "File larger than IMPORT_MAX_FILE_SIZE"
+// @Failure      429 {object} ErrorResponse "Too many unfinished imports of the user or the server"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /api/urls/imports [post]
+func (h *ImportHandler) StartImport(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	input := service.ImportInput{
+		UserID:     userID,
+		Format:     c.Query("format"),
+		OnConflict: c.Query("on_conflict"),
+		File:       c.Request.Body,
+	}
+
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		if input.Format == "" {
+			input.Format = c.ContentType()
+		}
+	} else {
+		// Multipart uploads are streamed: fields sent before the file are
+		// read, then the file part goes straight to the service
+		reader, err := c.Request.MultipartReader()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid multipart form"})
+			return
+		}
+		input.File = nil
+		fields := map[string]string{}
+		for input.File == nil {
+			part, err := reader.NextPart()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, ErrorResponse{Error: "file is required"})
+				return
+			}
+			switch name := part.FormName(); name {
+			case "file":
+				input.File = part
+				fields["filename"] = part.FileName()
+			case "format", "on_conflict":
+				value, _ := io.ReadAll(io.LimitReader(part, 64))
+				fields[name] = strings.TrimSpace(string(value))
+			}
+		}
+		if input.Format == "" {
+			input.Format = fields["format"]
+		}
+		if input.Format == "" {
+			input.Format = fields["filename"]
+		}
+		if input.OnConflict == "" {
+			input.OnConflict = fields["on_conflict"]
+		}
+	}
+
+	job, err := h.service.StartImport(input)
+	if err != nil {
+		status := importErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			c.JSON(status, ErrorResponse{Error: "Failed to start import"})
+			return
+		}
+		c.JSON(status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.Header("Location", "/api/urls/imports/"+strconv.FormatUint(uint64(job.ID), 10))
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetImport godoc
+// @Summary      Get import progress
+// @Description  Returns the status and progress of one of the caller's imports, with the rows that were not imported
+// @Tags         urls
+// @Produce      json
+// @Param        id path int true "Import ID"
+// @Success      200 {object} model.ImportJob
+// @Failure      401 {object} ErrorResponse
+// @Failure      404 {object} ErrorResponse
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /api/urls/imports/{id} [get]
+func (h *ImportHandler) GetImport(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: service.ErrImportNotFound.Error()})
+		return
+	}
+
+	job, err := h.service.GetImport(uint(id), userID)
+	if err != nil {
+		c.JSON(importErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+func importErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrImportNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrImportTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, service.ErrImportLimit):
+		return http.StatusTooManyRequests
+	case errors.Is(err, service.ErrInvalidImport):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"net/http"
+	"url-shortener/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS godoc
+// @Summary      JSON Web Key Set
+// @Description  Public keys that verify access and refresh tokens, identified by the kid token header. Empty when tokens are signed with a shared HS256 secret.
+// @Tags         auth
+// @Produce      json
+// @Success      200 {object} middleware.JWKS
+// @Router       /.well-known/jwks.json [get]
+func JWKS(c *gin.Context) {
+	// Verifiers may cache the set briefly; new keys are published before they sign
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, middleware.PublicJWKS())
+}
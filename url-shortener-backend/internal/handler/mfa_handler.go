@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"url-shortener/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFAHandler struct {
+	service service.MFAService
+}
+
+func NewMFAHandler(service service.MFAService) *MFAHandler {
+	return &MFAHandler{service: service}
+}
+
+type MFACodeRequest struct {
+	// A 6-digit code from the authenticator app, or a recovery code
+	Code string `json:"code" binding:"required" example:"123456"`
+}
+
+type DisableTOTPRequest struct {
+	Password string `json:"password" binding:"required" example:"password123"`
+	// A 6-digit code from the authenticator app, or a recovery code
+	Code string `json:"code" binding:"required" example:"123456"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"k7m2p-x9q4r,hn3tw-8cv5j"` // Shown only once, each works once
+	Message       string   `json:"message" example:"Two-factor authentication enabled"`
+}
+
+// GetMFAStatus godoc
+// @Summary      Two-factor status
+// @Description  Report whether TOTP two-factor authentication is enabled and how many recovery codes are unused
+// @Tags         mfa
+// @Produce      json
+// @Success      200 {object} service.MFAStatus
+// @Failure      401 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/auth/mfa [get]
+func (h *MFAHandler) GetMFAStatus(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	status, err := h.service.Status(userID)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// SetupTOTP godoc
+// @Summary      Start TOTP setup
+// @Description  Generate a new TOTP secret. Scan the QR code (or enter the secret) in an authenticator app, then confirm with a code. Calling this again replaces an unconfirmed secret.
+// @Tags         mfa
+// @Produce      json
+// @Success      200 {object} service.TOTPEnrollment
+// @Failure      401 {object} ErrorResponse
+// @Failure      409 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/auth/mfa/totp/setup [post]
+func (h *MFAHandler) SetupTOTP(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	enrollment, err := h.service.BeginTOTPEnrollment(userID)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmTOTP godoc
+// @Summary      Confirm TOTP setup
+// @Description  Enable two-factor authentication with a code from the newly added authenticator app. Returns 10 recovery codes that are only shown once.
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        request body MFACodeRequest true "Code from the authenticator app"
+// @Success      200 {object} RecoveryCodesResponse
+// @Failure      400 {object} ErrorResponse
+// @Failure      401 {object} ErrorResponse
+// @Failure      409 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/auth/mfa/totp/confirm [post]
+func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	codes, err := h.service.ConfirmTOTPEnrollment(userID, req.Code)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, RecoveryCodesResponse{
+		RecoveryCodes: codes,
+		Message:       "Two-factor authentication enabled",
+	})
+}
+
+// DisableTOTP godoc
+// @Summary      Disable TOTP
+// @Description  Turn two-factor authentication off. Requires the password and a current code or recovery code. Remaining recovery codes are deleted.
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        request body DisableTOTPRequest true "Password and code"
+// @Success      200 {object} map[string]interface{} "Two-factor authentication disabled"
+// @Failure      400 {object} ErrorResponse
+// @Failure      401 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/auth/mfa/totp/disable [post]
+func (h *MFAHandler) DisableTOTP(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var req DisableTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.service.DisableTOTP(userID, req.Password, req.Code); err != nil {
+		c.JSON(mfaErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary      Regenerate recovery codes
+// @Description  Replace all recovery codes with 10 new ones. The old codes stop working.
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        request body MFACodeRequest true "Code from the authenticator app"
+// @Success      200 {object} RecoveryCodesResponse
+// @Failure      400 {object} ErrorResponse
+// @Failure      401 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/auth/mfa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	codes, err := h.service.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, RecoveryCodesResponse{
+		RecoveryCodes: codes,
+		Message:       "Recovery codes regenerated",
+	})
+}
+
+// mfaErrorStatus maps two-factor service errors to HTTP status codes
+func mfaErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidMFAToken),
+		errors.Is(err, service.ErrInvalidMFACode),
+		errors.Is(err, service.ErrInvalidCredentials):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrMFAEnrollmentNotStarted),
+		errors.Is(err, service.ErrMFANotEnabled):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrMFAAlreadyEnabled):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrAccountDisabled):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrUserNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
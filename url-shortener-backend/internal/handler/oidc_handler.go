@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"url-shortener/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateCookie carries the state, nonce and PKCE verifier of a login in progress
+const oidcStateCookie = "oidc_state"
+
+type OIDCHandler struct {
+	service service.OIDCService
+	auth    *AuthHandler // Starts the session once the user is known
+}
+
+func NewOIDCHandler(service service.OIDCService, auth *AuthHandler) *OIDCHandler {
+	return &OIDCHandler{service: service, auth: auth}
+}
+
+type OIDCExchangeRequest struct {
+	Code string `json:"code" binding:"required" example:"V1StGXR8_Z5jdHi6B-myTV1StGXR8_Z5"`
+	// Optional - labels the login session; derived from the User-Agent when empty
+	DeviceLabel string `json:"device_label" example:"Work laptop"`
+}
+
+// ListOIDCProviders godoc
+// @Summary      List sign-in providers
+// @Description  Get the external OpenID Connect providers users can sign in with
+// @Tags         oidc
+// @Produce      json
+// @Success      200 {array} service.OIDCProvider
+// @Router       /api/auth/oidc/providers [get]
+func (h *OIDCHandler) ListOIDCProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.Providers())
+}
+
+// OIDCLogin godoc
+// @Summary      Sign in with a provider
+// @Description  Open this URL in the browser. It redirects to the provider's login page (authorization code flow with PKCE) and sets a short-lived state cookie.
+// @Tags         oidc
+// @Param        provider path string true "Provider name"
+// @Success      302 "Redirect to the provider"
+// @Failure      404 {object} ErrorResponse
+// @Failure      502 {object} ErrorResponse
+// @Router       /api/auth/oidc/{provider}/login [get]
+func (h *OIDCHandler) OIDCLogin(c *gin.Context) {
+	start, err := h.service.StartLogin(c.Param("provider"))
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownOIDCProvider) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+			return
+		}
+		log.Println("OIDC:", err)
+		c.JSON(http.StatusBadGateway, ErrorResponse{Error: "Sign-in provider is unavailable"})
+		return
+	}
+
+	// Lax so the cookie comes back on the provider's top-level redirect
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, start.State, int(time.Until(start.ExpiresAt).Seconds()), "/api/auth/oidc/", "", start.SecureCookie, true)
+	c.Redirect(http.StatusFound, start.AuthURL)
+}
+
+// OIDCCallback godoc
+// @Summary      Provider callback
+// @Description  The provider redirects here after login. The user is matched by linked identity or verified email (or created), then the browser is sent to APP_URL/oidc/callback?code=... on success or APP_URL/login?oidc_error=... on failure.
+// @Tags         oidc
+// @Param        provider path string true "Provider name"
+// @Param        code query string false "Authorization code"
+// @Param        state query string true "State from the login request"
+// @Success      302 "Redirect to the frontend"
+// @Router       /api/auth/oidc/{provider}/callback [get]
+func (h *OIDCHandler) OIDCCallback(c *gin.Context) {
+	stateCookie, _ := c.Cookie(oidcStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, "/api/auth/oidc/", "", false, true)
+
+	loginCode, err := h.service.CompleteLogin(c.Request.Context(), c.Param("provider"), stateCookie, service.OIDCCallback{
+		Code:             c.Query("code"),
+		State:            c.Query("state"),
+		Error:            c.Query("error"),
+		ErrorDescription: c.Query("error_description"),
+	})
+	if err != nil {
+		log.Printf("OIDC: %s sign-in failed: %v", c.Param("provider"), err)
+	}
+
+	c.Redirect(http.StatusFound, h.service.AppRedirect(loginCode, err))
+}
+
+// OIDCExchange godoc
+// @Summary      Finish provider sign-in
+// @Description  Exchange the single-use code from the callback redirect (valid for 2 minutes) for access and refresh tokens. Accounts with two-factor authentication get an MFAChallengeResponse instead, like /api/auth/login.
+// @Tags         oidc
+// @Accept       json
+// @Produce      json
+// @Param        request body OIDCExchangeRequest true "Code from the callback redirect"
+// @Success      200 {object} AuthResponse
+// @Failure      401 {object} ErrorResponse
+// @Failure      403 {object} ErrorResponse
+// @Router       /api/auth/oidc/exchange [post]
+func (h *OIDCHandler) OIDCExchange(c *gin.Context) {
+	var req OIDCExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	user, err := h.service.ExchangeLoginCode(req.Code)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, service.ErrInvalidLoginCode):
+			status = http.StatusUnauthorized
+		case errors.Is(err, service.ErrAccountDisabled):
+			status = http.StatusForbidden
+		}
+		c.JSON(status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	h.auth.startSession(c, user, req.DeviceLabel)
+}
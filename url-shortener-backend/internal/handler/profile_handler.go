@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"url-shortener/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProfileHandler struct {
+	service      service.ProfileService
+	tokenService service.TokenService
+}
+
+func NewProfileHandler(service service.ProfileService, tokenService service.TokenService) *ProfileHandler {
+	return &ProfileHandler{service: service, tokenService: tokenService}
+}
+
+type UpdateProfileRequest struct {
+	Username *string `json:"username" example:"john_doe"`
+	Email    *string `json:"email" binding:"omitempty,email" example:"john@example.com"` // Must be verified again
+	// Required when changing the email address
+	CurrentPassword string `json:"current_password" example:"password123"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required" example:"password123"`
+	NewPassword     string `json:"new_password" binding:"required,min=6" example:"newpassword123"`
+	// Optional - labels the new session; derived from the User-Agent when empty
+	DeviceLabel string `json:"device_label" example:"Work laptop"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required" example:"password123"`
+	// delete removes the links and their click history; orphan keeps them working without an owner
+	LinkPolicy string `json:"link_policy" binding:"required" example:"orphan"`
+}
+
+// GetMe godoc
+// @Summary      Get own profile
+// @Description  Get the authenticated user's profile
+// @Tags         me
+// @Produce      json
+// @Success      200 {object} model.User
+// @Failure      401 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/me [get]
+func (h *ProfileHandler) GetMe(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	user, err := h.service.GetProfile(userID)
+	if err != nil {
+		c.JSON(profileErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateMe godoc
+// @Summary      Update own profile
+// @Description  Change the username and/or email address. Changing the email needs current_password and sends a new verification link; the new address is unverified until it is followed.
+// @Tags         me
+// @Accept       json
+// @Produce      json
+// @Param        request body UpdateProfileRequest true "Fields to change"
+// @Success      200 {object} model.User
+// @Failure      400 {object} ErrorResponse
+// @Failure      401 {object} ErrorResponse
+// @Failure      409 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/me [patch]
+func (h *ProfileHandler) UpdateMe(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	user, err := h.service.UpdateProfile(userID, service.UpdateProfileInput{
+		Username:        req.Username,
+		Email:           req.Email,
+		CurrentPassword: req.CurrentPassword,
+	})
+	if err != nil {
+		c.JSON(profileErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ChangePassword godoc
+// @Summary      Change password
+// @Description  Set a new password. Every session of the user is revoked and a new one is started for this client.
+// @Tags         me
+// @Accept       json
+// @Produce      json
+// @Param        request body ChangePasswordRequest true "Current and new password"
+// @Success      200 {object} AuthResponse
+// @Failure      400 {object} ErrorResponse
+// @Failure      401 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/me/password [post]
+func (h *ProfileHandler) ChangePassword(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	user, err := h.service.ChangePassword(userID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		c.JSON(profileErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	tokens, err := h.tokenService.IssueTokens(user, req.DeviceLabel, c.Request.UserAgent())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Password changed, but failed to start a new session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		ID:           user.ID,
+		Username:     user.Username,
+		Email:        user.Email,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		Message:      "Password changed, other sessions were signed out",
+	})
+}
+
+// DeleteMe godoc
+// @Summary      Delete own account
+// @Description  Permanently delete the account, its sessions, API keys and recovery codes. link_policy decides what happens to the user's links: delete removes them with their click history, orphan keeps them working without an owner.
+// @Tags         me
+// @Accept       json
+// @Produce      json
+// @Param        request body DeleteAccountRequest true "Password and link policy"
+// @Success      200 {object} service.AccountDeletion
+// @Failure      400 {object} ErrorResponse
+// @Failure      401 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/me [delete]
+func (h *ProfileHandler) DeleteMe(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	result, err := h.service.DeleteAccount(userID, req.Password, req.LinkPolicy)
+	if err != nil {
+		c.JSON(profileErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExportMe godoc
+// @Summary      Export own data
+// @Description  Download the profile and every link of the authenticated user (including deleted links) as JSON
+// @Tags         me
+// @Produce      json
+// @Success      200 {object} service.AccountExport
+// @Failure      401 {object} ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/me/export [get]
+func (h *ProfileHandler) ExportMe(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	export, err := h.service.Export(userID)
+	if err != nil {
+		c.JSON(profileErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("account-%d-%s.json", userID, export.ExportedAt.Format("20060102"))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.IndentedJSON(http.StatusOK, export)
+}
+
+// profileErrorStatus maps profile service errors to HTTP status codes
+func profileErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidCredentials):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrUsernameTaken),
+		errors.Is(err, service.ErrEmailTaken):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvalidUsername),
+		errors.Is(err, service.ErrInvalidLinkPolicy),
+		errors.Is(err, service.ErrPasswordRequired),
+		errors.Is(err, service.ErrWeakPassword):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrUserNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
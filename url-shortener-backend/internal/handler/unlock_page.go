@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"html/template"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unlockPage is served to browsers that open a password-protected link
+var unlockPage = template.Must(template.New("unlock").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Protected link</title>
+<style>
+body { font-family: system-ui, sans-serif; display: flex; min-height: 100vh; margin: 0; align-items: center; justify-content: center; background: #f5f5f5; }
+form { background: #fff; padding: 2rem; border-radius: 8px; box-shadow: 0 1px 4px rgba(0,0,0,.1); width: 18rem; }
+h1 { font-size: 1.1rem; margin: 0 0 1rem; }
+input, button { width: 100%; box-sizing: border-box; padding: .6rem; margin-top: .5rem; font-size: 1rem; }
+.error { color: #c00; font-size: .9rem; }
+</style>
+</head>
+<body>
+<form method="post" action="/{{.Code}}">
+<h1>This link is password protected</h1>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<input type="password" name="password" placeholder="Password" autofocus required>
+<button type="submit">Unlock</button>
+</form>
+</body>
+</html>
+`))
+
+// renderUnlockPage answers a locked visit with the unlock form for browsers and JSON for API clients
+func renderUnlockPage(c *gin.Context, status int, code, errMsg string) {
+	if c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) != gin.MIMEHTML {
+		if errMsg == "" {
+			errMsg = "Password required to access this link"
+		}
+		c.JSON(status, ErrorResponse{Error: errMsg})
+		return
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(status)
+	if err := unlockPage.Execute(c.Writer, gin.H{"Code": code, "Error": errMsg}); err != nil {
+		_ = c.Error(err)
+	}
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+	"url-shortener/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Per-item outcomes of a batch create
+const (
+	BatchItemCreated = "created"
+	BatchItemFailed  = "failed"
+)
+
+type BatchItemRequest struct {
+	URL          string     `json:"url" example:"https://example.com/very/long/path"`
+	CustomAlias  string     `json:"custom_alias,omitempty" example:"spring-sale"`
+	Tags         []string   `json:"tags,omitempty" example:"spring,email"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty" example:"2025-12-31T23:59:59Z"`
+	MaxClicks    *int64     `json:"max_clicks,omitempty" example:"100"`
+	Password     string     `json:"password,omitempty" example:"s3cret"`
+	RedirectType int        `json:"redirect_type,omitempty" example:"302" enums:"301,302,307,308"`
+}
+
+type BatchCreateRequest struct {
+	Items []BatchItemRequest `json:"items" binding:"required"`
+	Mode  string             `json:"mode,omitempty" example:"atomic" enums:"atomic,partial"` // Defaults to atomic
+}
+
+type BatchItemResponse struct {
+	Index        int        `json:"index" example:"0"`
+	Status       string     `json:"status" example:"created" enums:"created,failed"`
+	ShortCode    string     `json:"short_code,omitempty" example:"abc12345"`
+	ShortURL     string     `json:"short_url,omitempty" example:"https://url.naammmdz.id.vn/abc12345"`
+	OriginalURL  string     `json:"original_url,omitempty" example:"https://example.com/very/long/path"`
+	Tags         []string   `json:"tags,omitempty" example:"spring,email"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty" example:"2025-12-31T23:59:59Z"`
+	RedirectType int        `json:"redirect_type,omitempty" example:"302"`
+	ErrorCode    string     `json:"error_code,omitempty" example:"alias_taken"`
+	Error        string     `json:"error,omitempty" example:"custom alias is already taken"`
+}
+
+type BatchCreateResponse struct {
+	Mode    string              `json:"mode" example:"atomic"`
+	Created int                 `json:"created" example:"2"`
+	Failed  int                 `json:"failed" example:"0"`
+	Results []BatchItemResponse `json:"results"`
+}
+
+// CreateShortURLBatch godoc
+// @Summary      Create short URLs in bulk
+// @Description  Creates up to SHORTEN_BATCH_MAX_ITEMS links in one request, each validated like POST /api/shorten.
+// @Description  In atomic mode (the default) nothing is created unless every item is valid, and the links are inserted in one transaction.
+// @Description  In partial mode the valid items are created and the rest are reported.
+// @Description  Every item gets a result in request order; failed items carry an error_code:
+// @Description  invalid_url, invalid_alias, alias_reserved, alias_taken, duplicate_alias, invalid_expiry,
+// @Description  invalid_redirect_type, invalid_tags, batch_aborted (atomic mode only) or internal_error.
+// @Tags         urls
+// @Accept       json
+// @Produce      json
+// @Param        request body BatchCreateRequest true "Links to create"
+// @Success      201 {object} BatchCreateResponse "Every item was created"
+// @Success      207 {object} BatchCreateResponse "Partial mode - some items failed"
+// @Failure      400 {object} BatchCreateResponse "Atomic mode - nothing was created"
+// @Failure      401 {object} ErrorResponse
+// @Failure      403 {object} ErrorResponse "Email not verified"
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /api/shorten/batch [post]
+func (h *URLHandler) CreateShortURLBatch(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var req BatchCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "items are required"})
+		return
+	}
+
+	items := make([]service.CreateURLInput, len(req.Items))
+	for i, item := range req.Items {
+		items[i] = service.CreateURLInput{
+			OriginalURL:  item.URL,
+			CustomAlias:  item.CustomAlias,
+			ExpiresAt:    item.ExpiresAt,
+			MaxClicks:    item.MaxClicks,
+			Password:     item.Password,
+			RedirectType: item.RedirectType,
+			Tags:         item.Tags,
+		}
+	}
+
+	results, err := h.service.CreateShortURLs(userID, items, req.Mode)
+	if err != nil {
+		status := urlErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			c.JSON(status, ErrorResponse{Error: "Failed to create short URLs"})
+			return
+		}
+		c.JSON(status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	response := BatchCreateResponse{Mode: req.Mode, Results: make([]BatchItemResponse, len(results))}
+	if response.Mode == "" {
+		response.Mode = service.BatchModeAtomic
+	}
+	baseURL := shortURLBase(c)
+	for i, result := range results {
+		if result.Err != nil {
+			code := service.LinkErrorCode(result.Err)
+			message := result.Err.Error()
+			if code == service.ErrorCodeInternal {
+				message = "Failed to create short URL"
+			}
+			response.Results[i] = BatchItemResponse{Index: result.Index, Status: BatchItemFailed, ErrorCode: code, Error: message}
+			response.Failed++
+			continue
+		}
+
+		response.Results[i] = BatchItemResponse{
+			Index:        result.Index,
+			Status:       BatchItemCreated,
+			ShortCode:    result.URL.ShortCode,
+			ShortURL:     baseURL + "/" + result.URL.ShortCode,
+			OriginalURL:  result.URL.OriginalURL,
+			Tags:         result.URL.Tags,
+			ExpiresAt:    result.URL.ExpiresAt,
+			RedirectType: result.URL.RedirectType,
+		}
+		response.Created++
+	}
+
+	status := http.StatusCreated
+	switch {
+	case response.Failed > 0 && response.Created == 0 && response.Mode == service.BatchModeAtomic:
+		status = http.StatusBadRequest
+	case response.Failed > 0:
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, response)
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"url-shortener/config"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// exportLinks is more than one export batch, so the file is streamed in parts
+const exportLinks = 1201
+
+// flushRecorder counts how often the response is flushed while it is written
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (r *flushRecorder) Flush() {
+	r.flushes++
+	r.ResponseRecorder.Flush()
+}
+
+// newExportTest serves the export route for user 1, who owns exportLinks
+// links; user 2 owns one more
+func newExportTest(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&model.URL{}, &model.LinkClaim{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	owner, other := uint(1), uint(2)
+	links := make([]model.URL, 0, exportLinks+1)
+	for i := 0; i < exportLinks; i++ {
+		links = append(links, model.URL{
+			UserID:      &owner,
+			ShortCode:   "code" + strconv.Itoa(i),
+			OriginalURL: "https://example.com/" + strconv.Itoa(i),
+			Clicks:      int64(i),
+		})
+	}
+	links[0].Protected, links[0].Password = true, "$2a$10$hash"
+	links = append(links, model.URL{UserID: &other, ShortCode: "other", OriginalURL: "https://example.com/other"})
+	if err := db.CreateInBatches(links, 200).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	urls := service.NewURLService(repository.NewURLRepository(db), repository.NewLinkClaimRepository(db),
+		config.LoadURLConfig(), config.AnonymousConfig{}, noClicks{})
+	h := NewURLHandler(urls, noAnalytics{}, nil, nil)
+
+	router := gin.New()
+	router.GET("/api/urls/export", func(c *gin.Context) { c.Set("userID", owner) }, h.ExportURLs)
+	return router
+}
+
+func export(router *gin.Engine, query string) *flushRecorder {
+	w := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/urls/export"+query, nil))
+	return w
+}
+
+func TestExportURLsCSV(t *testing.T) {
+	w := export(newExportTest(t), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %q", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := w.Header().Get("Content-Disposition"); !strings.HasPrefix(got, `attachment; filename="links-`) || !strings.HasSuffix(got, `.csv"`) {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+
+	// Every batch is flushed as soon as it is written
+	if w.flushes < 3 {
+		t.Errorf("response flushed %d times, want once per batch", w.flushes)
+	}
+
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != exportLinks+1 {
+		t.Fatalf("%d rows, want a header and %d links", len(rows), exportLinks)
+	}
+	if rows[0][0] != "short_code" || rows[0][1] != "original_url" {
+		t.Errorf("header = %v", rows[0])
+	}
+
+	// Oldest first, only the caller's links, protected links marked without the hash
+	for i, row := range rows[1:] {
+		if row[0] != "code"+strconv.Itoa(i) || row[2] != strconv.Itoa(i) {
+			t.Fatalf("row %d = %v", i+1, row)
+		}
+	}
+	if rows[1][8] != "true" || strings.Contains(w.Body.String(), "$2a$") {
+		t.Errorf("protected link row = %v", rows[1])
+	}
+}
+
+func TestExportURLsNDJSON(t *testing.T) {
+	w := export(newExportTest(t), "?format=ndjson")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %q", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	lines := 0
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var record struct {
+			ShortCode string `json:"short_code"`
+			Protected bool   `json:"password_protected"`
+			Password  string `json:"password"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("line %d: %v", lines+1, err)
+		}
+		if record.ShortCode != "code"+strconv.Itoa(lines) || record.Password != "" {
+			t.Fatalf("line %d = %+v", lines+1, record)
+		}
+		lines++
+	}
+	if lines != exportLinks {
+		t.Errorf("%d lines, want %d", lines, exportLinks)
+	}
+}
+
+func TestExportURLsInvalidFormat(t *testing.T) {
+	w := export(newExportTest(t), "?format=xml")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
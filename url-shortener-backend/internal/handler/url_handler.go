@@ -1,34 +1,66 @@
 package handler
 
 import (
-	"math/rand"
+	"errors"
+	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
+	"url-shortener/internal/linkfile"
+	"url-shortener/internal/middleware"
 	"url-shortener/internal/model"
 	"url-shortener/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
+// linkPasswordHeader lets API clients unlock password-protected links
+const linkPasswordHeader = "X-Link-Password"
+
 type URLHandler struct {
-	service service.URLService
+	service    service.URLService
+	analytics  service.AnalyticsService
+	identities service.AnonymousIdentityService
+	loginGuard service.LoginGuard
 }
 
-func NewURLHandler(service service.URLService) *URLHandler {
-	return &URLHandler{service: service}
+func NewURLHandler(service service.URLService, analytics service.AnalyticsService, identities service.AnonymousIdentityService, loginGuard service.LoginGuard) *URLHandler {
+	return &URLHandler{service: service, analytics: analytics, identities: identities, loginGuard: loginGuard}
 }
 
 type CreateURLRequest struct {
-	URL         string  `json:"url" binding:"required" example:"https://example.com/very/long/path"`
-	AnonymousID *string `json:"anonymous_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`
+	URL          string     `json:"url" binding:"required" example:"https://example.com/very/long/path"`
+	CustomAlias  string     `json:"custom_alias,omitempty" example:"spring-sale"`
+	AnonymousID  *string    `json:"anonymous_id,omitempty" example:"anon_3f2b9c0e5d7a41c88e6f0b2d4a9c1e57"` // Optional - must match the anonymous token
+	ExpiresAt    *time.Time `json:"expires_at,omitempty" example:"2025-12-31T23:59:59Z"`
+	MaxClicks    *int64     `json:"max_clicks,omitempty" example:"100"`
+	Password     string     `json:"password,omitempty" example:"s3cret"`
+	RedirectType int        `json:"redirect_type,omitempty" example:"302" enums:"301,302,307,308"`
+	Tags         []string   `json:"tags,omitempty" example:"spring,email"`
 }
 
 type CreateURLResponse struct {
-	ShortCode   string `json:"short_code" example:"abc12345"`
-	ShortURL    string `json:"short_url" example:"https://url.naammmdz.id.vn/abc12345"`
-	OriginalURL string `json:"original_url" example:"https://example.com/very/long/path"`
-	AnonymousID string `json:"anonymous_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`
+	ShortCode      string     `json:"short_code" example:"abc12345"`
+	ShortURL       string     `json:"short_url" example:"https://url.naammmdz.id.vn/abc12345"`
+	OriginalURL    string     `json:"original_url" example:"https://example.com/very/long/path"`
+	AnonymousID    string     `json:"anonymous_id,omitempty" example:"anon_3f2b9c0e5d7a41c88e6f0b2d4a9c1e57"`
+	AnonymousToken string     `json:"anonymous_token,omitempty" example:"eyJpZCI6ImFub25fM2YyYjljMGU1ZDdh...Xy3Q"` // New identities only; browsers also get it as a cookie
+	ExpiresAt      *time.Time `json:"expires_at,omitempty" example:"2025-12-31T23:59:59Z"`
+	MaxClicks      *int64     `json:"max_clicks,omitempty" example:"100"`
+	Protected      bool       `json:"password_protected,omitempty" example:"false"`
+	RedirectType   int        `json:"redirect_type,omitempty" example:"302"`
+	Tags           []string   `json:"tags,omitempty" example:"spring,email"`
+}
+
+type UpdateURLRequest struct {
+	URL          *string    `json:"url,omitempty" example:"https://example.com/new/destination"`
+	Disabled     *bool      `json:"disabled,omitempty" example:"false"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty" example:"2025-12-31T23:59:59Z"`
+	MaxClicks    *int64     `json:"max_clicks,omitempty" example:"100"`
+	Password     *string    `json:"password,omitempty" example:"s3cret"`   // Empty string removes the password
+	RedirectType *int       `json:"redirect_type,omitempty" example:"307"` // 0 switches back to the server default
+	Tags         *[]string  `json:"tags,omitempty" example:"spring,email"` // Replaces all tags; an empty list removes them
 }
 
 type ErrorResponse struct {
@@ -38,12 +70,20 @@ type ErrorResponse struct {
 // CreateShortURL godoc
 // @Summary      Create short URL
 // @Description  Shorten a long URL (works for both authenticated and anonymous users)
+// @Description  An optional custom_alias is used as the short code instead of a generated one
+// @Description  Anonymous visitors are identified by the signed anon_token cookie or X-Anonymous-Token header; a new identity is issued when neither is sent
 // @Tags         urls
 // @Accept       json
 // @Produce      json
-// @Param        request body CreateURLRequest true "URL to shorten with optional anonymous_id"
+// @Param        request body CreateURLRequest true "URL to shorten with optional custom_alias"
+// @Param        X-Anonymous-Token header string false "Anonymous token from an earlier response"
 // @Success      201 {object} CreateURLResponse
 // @Failure      400 {object} ErrorResponse
+// @Failure      401 {object} ErrorResponse "Invalid anonymous token"
+// @Failure      403 {object} ErrorResponse "anonymous_id does not match the anonymous token"
+// @Failure      409 {object} ErrorResponse "Custom alias already taken"
+// @Failure      429 {object} ErrorResponse "Anonymous link quota reached"
+// @Security     ApiKeyAuth
 // @Router       /api/shorten [post]
 func (h *URLHandler) CreateShortURL(c *gin.Context) {
 	var req CreateURLRequest
@@ -59,52 +99,66 @@ func (h *URLHandler) CreateShortURL(c *gin.Context) {
 		userID = &id
 	}
 
-	// If not authenticated, use anonymous ID from request or generate new one
+	// If not authenticated, use the signed anonymous identity or issue a new one
 	var anonymousID *string
-	var isNewAnonymousID bool
+	var newIdentity *service.AnonymousIdentity
 	if userID == nil {
-		if req.AnonymousID != nil && *req.AnonymousID != "" {
-			anonymousID = req.AnonymousID
-			isNewAnonymousID = false
+		if id, ok := middleware.GetAnonymousID(c); ok {
+			anonymousID = &id
 		} else {
-			// Generate new anonymous ID (UUID v4)
-			newID := generateAnonymousID()
-			anonymousID = &newID
-			isNewAnonymousID = true
+			identity, err := h.identities.Issue()
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create anonymous identity"})
+				return
+			}
+			newIdentity = identity
+			anonymousID = &identity.ID
 		}
-	}
 
-	urlEntry, err := h.service.CreateShortURL(req.URL, userID, anonymousID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
-		return
+		// The ID in the body is only a hint; it can never select another visitor's identity
+		if req.AnonymousID != nil && *req.AnonymousID != "" && *req.AnonymousID != *anonymousID {
+			c.JSON(http.StatusForbidden, ErrorResponse{Error: service.ErrAnonymousIDMismatch.Error()})
+			return
+		}
 	}
 
-	// Build short URL from BASE_URL env or request origin
-	baseURL := os.Getenv("BASE_URL")
-	if baseURL == "" {
-		// Fallback: use request scheme and host
-		scheme := "http"
-		if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
-			scheme = "https"
-		}
-		host := c.GetHeader("X-Forwarded-Host")
-		if host == "" {
-			host = c.Request.Host
+	urlEntry, err := h.service.CreateShortURL(service.CreateURLInput{
+		OriginalURL:  req.URL,
+		CustomAlias:  req.CustomAlias,
+		UserID:       userID,
+		AnonymousID:  anonymousID,
+		ClientIP:     c.ClientIP(),
+		ExpiresAt:    req.ExpiresAt,
+		MaxClicks:    req.MaxClicks,
+		Password:     req.Password,
+		RedirectType: req.RedirectType,
+		Tags:         req.Tags,
+	})
+	if err != nil {
+		status := urlErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			status = http.StatusBadRequest
 		}
-		baseURL = scheme + "://" + host
+		c.JSON(status, ErrorResponse{Error: err.Error()})
+		return
 	}
-	shortURL := baseURL + "/" + urlEntry.ShortCode
 
 	response := CreateURLResponse{
-		ShortCode:   urlEntry.ShortCode,
-		ShortURL:    shortURL,
-		OriginalURL: urlEntry.OriginalURL,
+		ShortCode:    urlEntry.ShortCode,
+		ShortURL:     shortURLBase(c) + "/" + urlEntry.ShortCode,
+		OriginalURL:  urlEntry.OriginalURL,
+		ExpiresAt:    urlEntry.ExpiresAt,
+		MaxClicks:    urlEntry.MaxClicks,
+		Protected:    urlEntry.Protected,
+		RedirectType: urlEntry.RedirectType,
+		Tags:         urlEntry.Tags,
 	}
 
-	// Only return anonymous ID if it was newly generated (first-time user)
-	if isNewAnonymousID && anonymousID != nil {
-		response.AnonymousID = *anonymousID
+	// Only return the anonymous identity if it was newly issued (first-time visitor)
+	if newIdentity != nil {
+		response.AnonymousID = newIdentity.ID
+		response.AnonymousToken = newIdentity.Token
+		setAnonymousCookie(c, newIdentity)
 	}
 
 	c.JSON(http.StatusCreated, response)
@@ -112,32 +166,136 @@ func (h *URLHandler) CreateShortURL(c *gin.Context) {
 
 // RedirectURL godoc
 // @Summary      Redirect to original URL
-// @Description  Redirect to the original URL using short code
+// @Description  Redirect to the original URL using short code.
+// @Description  The status is the link's redirect_type (301, 302, 307 or 308) or the server default.
+// @Description  Password-protected links show an unlock form to browsers; API clients send the password
+// @Description  in the X-Link-Password header or the password query parameter.
+// @Description  Repeated wrong passwords for a link or from a client IP are slowed down and then locked out;
+// @Description  the Retry-After header says how many seconds to wait.
 // @Tags         urls
 // @Param        code path string true "Short code"
-// @Success      301
+// @Param        X-Link-Password header string false "Password for protected links"
+// @Param        password query string false "Password for protected links"
+// @Success      301 "Moved Permanently"
+// @Success      302 "Found"
+// @Success      307 "Temporary Redirect"
+// @Success      308 "Permanent Redirect"
+// @Failure      401 {object} ErrorResponse "Link is password protected"
 // @Failure      404 {object} ErrorResponse
+// @Failure      410 {object} ErrorResponse "Link deleted, disabled or expired"
+// @Failure      429 {object} ErrorResponse "Too many wrong passwords"
 // @Router       /{code} [get]
 func (h *URLHandler) RedirectURL(c *gin.Context) {
 	code := c.Param("code")
 
-	originalURL, err := h.service.RedirectAndCount(code)
+	access := service.RedirectAccess{Password: linkPassword(c)}
+	unlockedByCookie := false
+	if cookie, err := c.Cookie(unlockCookieName(code)); err == nil {
+		access.Unlock = func(passwordHash string) bool {
+			unlockedByCookie = middleware.ValidateLinkAccessToken(cookie, code, passwordHash)
+			return unlockedByCookie
+		}
+	}
+
+	ctx := c.Request.Context()
+	var charge *service.LoginCharge
+	if access.Password != "" {
+		var wait time.Duration
+		attempt := service.LoginAttempt{Account: service.LinkAccount(code), ClientIP: c.ClientIP()}
+		if charge, wait = h.loginGuard.Check(ctx, attempt); wait > 0 {
+			setRetryAfter(c, wait)
+			renderUnlockPage(c, http.StatusTooManyRequests, code, "Too many wrong passwords, try again later")
+			return
+		}
+	}
+
+	urlEntry, err := h.service.RedirectAndCount(code, access)
 	if err != nil {
-		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Short URL not found"})
+		if errors.Is(err, service.ErrInvalidLinkPassword) {
+			if wait := h.loginGuard.RecordFailure(ctx, charge); wait > 0 {
+				setRetryAfter(c, wait)
+			}
+		} else {
+			h.loginGuard.Release(ctx, charge)
+		}
+
+		switch {
+		case errors.Is(err, service.ErrLinkPasswordRequired):
+			renderUnlockPage(c, http.StatusUnauthorized, code, "")
+		case errors.Is(err, service.ErrInvalidLinkPassword):
+			renderUnlockPage(c, http.StatusUnauthorized, code, "Incorrect password")
+		case errors.Is(err, service.ErrURLExpired):
+			c.JSON(http.StatusGone, ErrorResponse{Error: "Short URL has expired"})
+		case errors.Is(err, service.ErrURLGone):
+			c.JSON(http.StatusGone, ErrorResponse{Error: "Short URL is no longer available"})
+		case errors.Is(err, service.ErrURLNotFound):
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "Short URL not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to resolve short URL"})
+		}
 		return
 	}
 
-	c.Redirect(http.StatusMovedPermanently, originalURL)
+	h.analytics.RecordClick(service.ClickInput{
+		ShortCode:      code,
+		Referrer:       c.Request.Referer(),
+		UserAgent:      c.Request.UserAgent(),
+		ClientIP:       c.ClientIP(),
+		AcceptLanguage: c.GetHeader("Accept-Language"),
+	})
+
+	// Remember the unlock so repeat visits skip the prompt
+	if urlEntry.Protected && !unlockedByCookie {
+		h.loginGuard.RecordSuccess(ctx, charge)
+		if token, err := middleware.GenerateLinkAccessToken(code, urlEntry.Password); err == nil {
+			c.SetSameSite(http.SameSiteLaxMode)
+			c.SetCookie(unlockCookieName(code), token, int(middleware.LinkAccessTTL.Seconds()), "/"+code, "", isSecureRequest(c), true)
+		}
+	} else {
+		// The password was not what let the visitor in
+		h.loginGuard.Release(ctx, charge)
+	}
+
+	// Every visit is counted and the destination may be edited later, so
+	// browsers and shared caches must not remember the redirect
+	c.Header("Cache-Control", "private, no-store, max-age=0")
+
+	// The unlock form is a POST - answer with 303 so the browser follows with a GET
+	if c.Request.Method == http.MethodPost {
+		c.Redirect(http.StatusSeeOther, urlEntry.OriginalURL)
+		return
+	}
+
+	c.Redirect(h.service.RedirectStatus(urlEntry), urlEntry.OriginalURL)
+}
+
+// UnlockURL godoc
+// @Summary      Unlock password-protected URL
+// @Description  Submit the unlock form of a password-protected link and get redirected to the original URL
+// @Tags         urls
+// @Accept       x-www-form-urlencoded
+// @Param        code path string true "Short code"
+// @Param        password formData string true "Link password"
+// @Success      303
+// @Failure      401 {object} ErrorResponse
+// @Failure      404 {object} ErrorResponse
+// @Failure      410 {object} ErrorResponse
+// @Failure      429 {object} ErrorResponse
+// @Router       /{code} [post]
+func (h *URLHandler) UnlockURL(c *gin.Context) {
+	h.RedirectURL(c)
 }
 
 // GetURLInfo godoc
 // @Summary      Get URL information
-// @Description  Get detailed information about a shortened URL
+// @Description  Get detailed information about a shortened URL.
+// @Description  The destination and domain of a password-protected link are only shown to its owner.
 // @Tags         urls
 // @Produce      json
 // @Param        code path string true "Short code"
 // @Success      200 {object} model.URL
 // @Failure      404 {object} ErrorResponse
+// @Security     ApiKeyAuth
 // @Router       /api/urls/{code} [get]
 func (h *URLHandler) GetURLInfo(c *gin.Context) {
 	code := c.Param("code")
@@ -148,64 +306,366 @@ func (h *URLHandler) GetURLInfo(c *gin.Context) {
 		return
 	}
 
+	if urlEntry.Protected {
+		userID, ok := middleware.GetUserIDFromJWT(c)
+		if !ok || urlEntry.UserID == nil || *urlEntry.UserID != userID {
+			// The domain would give the destination away too
+			urlEntry.OriginalURL = ""
+			urlEntry.Domain = ""
+		}
+	}
+
+	c.JSON(http.StatusOK, urlEntry)
+}
+
+// UpdateURL godoc
+// @Summary      Update short URL
+// @Description  Change the destination, expiry, password or enabled state of a short URL owned by the authenticated user
+// @Tags         urls
+// @Accept       json
+// @Produce      json
+// @Param        code path string true "Short code"
+// @Param        request body UpdateURLRequest true "Fields to update"
+// @Success      200 {object} model.URL
+// @Failure      400 {object} ErrorResponse
+// @Failure      403 {object} ErrorResponse
+// @Failure      404 {object} ErrorResponse
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /api/urls/{code} [patch]
+func (h *URLHandler) UpdateURL(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	code := c.Param("code")
+
+	var req UpdateURLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	urlEntry, err := h.service.UpdateURL(code, userID, service.UpdateURLInput{
+		OriginalURL:  req.URL,
+		Disabled:     req.Disabled,
+		ExpiresAt:    req.ExpiresAt,
+		MaxClicks:    req.MaxClicks,
+		Password:     req.Password,
+		RedirectType: req.RedirectType,
+		Tags:         req.Tags,
+	})
+	if err != nil {
+		c.JSON(urlErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, urlEntry)
+}
+
+// DeleteURL godoc
+// @Summary      Delete short URL
+// @Description  Soft-delete a short URL owned by the authenticated user; the code stays reserved and can be restored
+// @Tags         urls
+// @Produce      json
+// @Param        code path string true "Short code"
+// @Success      200 {object} map[string]interface{} "Link deleted"
+// @Failure      403 {object} ErrorResponse
+// @Failure      404 {object} ErrorResponse
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /api/urls/{code} [delete]
+func (h *URLHandler) DeleteURL(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	code := c.Param("code")
+
+	if err := h.service.DeleteURL(code, userID); err != nil {
+		c.JSON(urlErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Link deleted",
+		"short_code": code,
+	})
+}
+
+// RestoreURL godoc
+// @Summary      Restore short URL
+// @Description  Restore a deleted short URL owned by the authenticated user
+// @Tags         urls
+// @Produce      json
+// @Param        code path string true "Short code"
+// @Success      200 {object} model.URL
+// @Failure      403 {object} ErrorResponse
+// @Failure      404 {object} ErrorResponse
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /api/urls/{code}/restore [post]
+func (h *URLHandler) RestoreURL(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	code := c.Param("code")
+
+	urlEntry, err := h.service.RestoreURL(code, userID)
+	if err != nil {
+		c.JSON(urlErrorStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, urlEntry)
 }
 
 // ListURLs godoc
-// @Summary      List all URLs
-// @Description  Get list of shortened URLs (user's own or all if admin)
+// @Summary      List URLs
+// @Description  Get a page of the caller's shortened URLs, newest first by default. Pass next_cursor back as cursor to fetch the following page.
 // @Tags         urls
 // @Produce      json
-// @Param        anonymous_id query string false "Anonymous ID to filter links"
-// @Success      200 {object} map[string]interface{} "Returns total count and array of URLs"
+// @Param        anonymous_id query string false "Optional - must match the anonymous token"
+// @Param        X-Anonymous-Token header string false "Anonymous token, for clients without the anon_token cookie"
+// @Param        cursor query string false "next_cursor from the previous page"
+// @Param        limit query int false "Page size (max 200)" default(50)
+// @Param        sort query string false "Sort key: created, updated or clicks" default(created)
+// @Param        order query string false "Sort order: asc or desc" default(desc)
+// @Param        status query string false "Filter: active, disabled, expired, protected, deleted or taken_down"
+// @Param        domain query string false "Destination domain, including its subdomains"
+// @Param        created_from query string false "Created at or after (RFC3339)"
+// @Param        created_to query string false "Created before (RFC3339)"
+// @Param        q query string false "Search in short code and destination URL"
+// @Success      200 {object} service.URLPage
+// @Failure      400 {object} ErrorResponse
+// @Failure      401 {object} ErrorResponse "Invalid anonymous token"
+// @Failure      403 {object} ErrorResponse "anonymous_id does not match the anonymous token"
 // @Failure      500 {object} ErrorResponse
+// @Security     ApiKeyAuth
 // @Router       /api/urls [get]
 func (h *URLHandler) ListURLs(c *gin.Context) {
-	// Check if user is authenticated
-	userIDInterface, isAuthenticated := c.Get("userID")
-	anonymousID := c.Query("anonymous_id")
-
-	var urls []model.URL
-	var err error
+	query, ok := parseURLListQuery(c)
+	if !ok {
+		return
+	}
 
-	if isAuthenticated {
+	if userID, isAuthenticated := c.Get("userID"); isAuthenticated {
 		// Authenticated user - show their links
-		userID := userIDInterface.(uint)
-		urls, err = h.service.ListUserURLs(userID)
-	} else if anonymousID != "" {
-		// Anonymous user with ID - show their links
-		urls, err = h.service.ListAnonymousURLs(anonymousID)
+		id := userID.(uint)
+		query.UserID = &id
+	} else if requested := c.Query("anonymous_id"); requested != "" && !hasAnonymousID(c, requested) {
+		// A bare anonymous_id proves nothing without the matching signed token
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: service.ErrAnonymousIDMismatch.Error()})
+		return
+	} else if anonymousID, ok := middleware.GetAnonymousID(c); ok {
+		// Anonymous visitor with a signed identity - show their links
+		query.AnonymousID = &anonymousID
 	} else {
-		// No authentication and no anonymous_id - return empty list
-		urls = []model.URL{}
+		// No authentication and no anonymous identity - return empty list
+		c.JSON(http.StatusOK, service.URLPage{URLs: []model.URL{}})
+		return
 	}
 
+	respondURLPage(c, h.service, query)
+}
+
+// ExportURLs godoc
+// @Summary      Export links
+// @Description  Streams all of the caller's links, with click counts and metadata, as CSV or NDJSON.
+// @Description  The file can be imported again with POST /api/urls/imports. Link passwords are never exported;
+// @Description  password-protected links are marked with password_protected.
+// @Tags         urls
+// @Produce      text/csv
+// @Produce      application/x-ndjson
+// @Param        format query string false "csv (default) or ndjson" Enums(csv, ndjson)
+// @Success      200 {file} file
+// @Failure      400 {object} ErrorResponse
+// @Failure      401 {object} ErrorResponse
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /api/urls/export [get]
+func (h *URLHandler) ExportURLs(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	format, err := linkfile.ParseFormat(c.DefaultQuery("format", linkfile.FormatCSV))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch URLs"})
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	writer, err := linkfile.NewWriter(c.Writer, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"total": len(urls),
-		"urls":  urls,
+	filename := "links-" + time.Now().UTC().Format("20060102") + "." + format
+	c.Header("Content-Type", linkfile.ContentType(format))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-store")
+
+	err = h.service.ExportURLs(userID, func(urls []model.URL) error {
+		for i := range urls {
+			if err := writer.Write(linkRecord(&urls[i])); err != nil {
+				return err
+			}
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
 	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		log.Printf("Export for user %d failed: %v", userID, err)
+		// Once rows are streamed the status is sent and the file just ends early
+		if !c.Writer.Written() {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to export links"})
+		}
+	}
 }
 
-// Helper function to generate anonymous ID (UUID v4)
-func generateAnonymousID() string {
-	// Generate a random anonymous ID with timestamp and random suffix
-	return "anon-" + generateRandomString(32)
+// linkRecord converts a link to its export form
+func linkRecord(urlEntry *model.URL) linkfile.Record {
+	createdAt := urlEntry.CreatedAt
+	return linkfile.Record{
+		ShortCode:    urlEntry.ShortCode,
+		OriginalURL:  urlEntry.OriginalURL,
+		Clicks:       urlEntry.Clicks,
+		Tags:         urlEntry.Tags,
+		ExpiresAt:    urlEntry.ExpiresAt,
+		MaxClicks:    urlEntry.MaxClicks,
+		RedirectType: urlEntry.RedirectType,
+		Disabled:     urlEntry.Disabled,
+		Protected:    urlEntry.Protected,
+		CreatedAt:    &createdAt,
+	}
 }
 
-func generateRandomString(length int) string {
-	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
+// parseURLListQuery reads paging, sorting and filter parameters shared by link
+// listings. On invalid input it writes a 400 response and returns false.
+func parseURLListQuery(c *gin.Context) (service.URLListQuery, bool) {
+	query := service.URLListQuery{
+		Cursor: c.Query("cursor"),
+		SortBy: c.Query("sort"),
+		Status: c.Query("status"),
+		Domain: c.Query("domain"),
+		Search: c.Query("q"),
+	}
+
+	switch c.DefaultQuery("order", "desc") {
+	case "asc":
+		query.Ascending = true
+	case "desc":
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "order must be asc or desc"})
+		return query, false
+	}
+
+	var err error
+	if limit := c.Query("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "limit must be a number"})
+			return query, false
+		}
+	}
+	if from := c.Query("created_from"); from != "" {
+		createdFrom, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "created_from must be an RFC3339 timestamp"})
+			return query, false
+		}
+		query.CreatedFrom = &createdFrom
+	}
+	if to := c.Query("created_to"); to != "" {
+		createdTo, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "created_to must be an RFC3339 timestamp"})
+			return query, false
+		}
+		query.CreatedTo = &createdTo
+	}
+
+	return query, true
+}
+
+func respondURLPage(c *gin.Context, urlService service.URLService, query service.URLListQuery) {
+	page, err := urlService.ListURLPage(query)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidListQuery) || errors.Is(err, service.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch URLs"})
+		return
+	}
 
-	// Seed random with current time
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	c.JSON(http.StatusOK, page)
+}
+
+// linkPassword reads a link password from the unlock form, the X-Link-Password header or the query string
+func linkPassword(c *gin.Context) string {
+	if c.Request.Method == http.MethodPost {
+		return c.PostForm("password")
+	}
+	if password := c.GetHeader(linkPasswordHeader); password != "" {
+		return password
+	}
+	return c.Query("password")
+}
 
-	result := make([]byte, length)
-	for i := range result {
-		result[i] = charset[rng.Intn(len(charset))]
+func unlockCookieName(code string) string {
+	return "link_unlock_" + code
+}
+
+func isSecureRequest(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
+
+// urlErrorStatus maps URL service errors to HTTP status codes
+func urlErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrURLNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrNotURLOwner):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrAliasTaken):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrAnonymousQuotaExceeded):
+		return http.StatusTooManyRequests
+	case errors.Is(err, service.ErrInvalidURL),
+		errors.Is(err, service.ErrInvalidExpiry),
+		errors.Is(err, service.ErrInvalidRedirectType),
+		errors.Is(err, service.ErrInvalidTags),
+		errors.Is(err, service.ErrInvalidBatch),
+		errors.Is(err, service.ErrInvalidAlias),
+		errors.Is(err, service.ErrAliasReserved):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
 	}
-	return string(result)
+}
+
+// shortURLBase returns BASE_URL, or the scheme and host the request came in on
+func shortURLBase(c *gin.Context) string {
+	if baseURL := os.Getenv("BASE_URL"); baseURL != "" {
+		return baseURL
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	host := c.GetHeader("X-Forwarded-Host")
+	if host == "" {
+		host = c.Request.Host
+	}
+	return scheme + "://" + host
+}
+
+// hasAnonymousID reports whether the request's signed identity is anonymousID
+func hasAnonymousID(c *gin.Context, anonymousID string) bool {
+	id, ok := middleware.GetAnonymousID(c)
+	return ok && id == anonymousID
+}
+
+// setAnonymousCookie hands a new anonymous identity to the browser. Lax lets
+// the cookie reach the API when the visitor comes back from another site.
+func setAnonymousCookie(c *gin.Context, identity *service.AnonymousIdentity) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(middleware.AnonymousCookie, identity.Token, int(time.Until(identity.ExpiresAt).Seconds()), "/", "", isSecureRequest(c), true)
 }
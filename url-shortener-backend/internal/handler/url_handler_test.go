@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/config"
+	"url-shortener/internal/loginguard"
+	"url-shortener/internal/middleware"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// noClicks drops counted clicks; the redirect tests only look at responses
+type noClicks struct{}
+
+func (noClicks) Add(string) {}
+
+// noAnalytics drops recorded clicks; other AnalyticsService methods are not used
+type noAnalytics struct {
+	service.AnalyticsService
+}
+
+func (noAnalytics) RecordClick(service.ClickInput) {}
+
+// newRedirectTest serves the redirect routes over an in-memory database and
+// returns the URL service to create links with
+func newRedirectTest(t *testing.T, guardCfg config.LoginGuardConfig) (*gin.Engine, service.URLService) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	if err := middleware.InitJWT(config.JWTConfig{Secret: "test-secret", Issuer: "test", Audience: "test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&model.URL{}, &model.LinkClaim{}, &model.LoginLockout{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	urls := service.NewURLService(repository.NewURLRepository(db), repository.NewLinkClaimRepository(db),
+		config.LoadURLConfig(), config.AnonymousConfig{}, noClicks{})
+	guard := service.NewLoginGuard(loginguard.NewMemoryStore(), repository.NewLoginLockoutRepository(db), guardCfg)
+	h := NewURLHandler(urls, noAnalytics{}, nil, guard)
+
+	router := gin.New()
+	router.GET("/:code", h.RedirectURL)
+	router.POST("/:code", h.UnlockURL)
+	return router, urls
+}
+
+// testGuardConfig locks a link out after three wrong passwords
+func testGuardConfig() config.LoginGuardConfig {
+	return config.LoginGuardConfig{
+		AccountFreeAttempts: 3,
+		AccountMaxAttempts:  3,
+		IPFreeAttempts:      100,
+		IPMaxAttempts:       100,
+		BackoffBase:         time.Second,
+		BackoffMax:          time.Minute,
+		LockoutDuration:     time.Minute,
+		LockoutMax:          time.Hour,
+		FailureWindow:       time.Hour,
+	}
+}
+
+func createProtectedLink(t *testing.T, urls service.URLService, code, password string) {
+	t.Helper()
+	owner := uint(1)
+	_, err := urls.CreateShortURL(service.CreateURLInput{
+		OriginalURL: "https://example.com/private",
+		CustomAlias: code,
+		UserID:      &owner,
+		Password:    password,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func unlockRequest(code, password string) *http.Request {
+	form := url.Values{"password": {password}}
+	req := httptest.NewRequest(http.MethodPost, "/"+code, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "text/html")
+	return req
+}
+
+func serve(router *gin.Engine, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// unlockCookie returns the unlock cookie set by a response, or nil
+func unlockCookie(w *httptest.ResponseRecorder, code string) *http.Cookie {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == unlockCookieName(code) {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestRedirectURLUnlock(t *testing.T) {
+	router, urls := newRedirectTest(t, testGuardConfig())
+	createProtectedLink(t, urls, "secret-doc", "s3cret")
+
+	// A browser without a cookie gets the unlock form
+	req := httptest.NewRequest(http.MethodGet, "/secret-doc", nil)
+	req.Header.Set("Accept", "text/html")
+	w := serve(router, req)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), `action="/secret-doc"`) {
+		t.Fatalf("locked visit: status %d, body %q", w.Code, w.Body.String())
+	}
+
+	// A wrong password shows the form again and sets no cookie
+	w = serve(router, unlockRequest("secret-doc", "wrong"))
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "Incorrect password") {
+		t.Fatalf("wrong password: status %d, body %q", w.Code, w.Body.String())
+	}
+	if unlockCookie(w, "secret-doc") != nil {
+		t.Error("wrong password set an unlock cookie")
+	}
+
+	// The right password answers the form POST with 303 and sets the cookie
+	w = serve(router, unlockRequest("secret-doc", "s3cret"))
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("unlock status = %d, want %d", w.Code, http.StatusSeeOther)
+	}
+	if got := w.Header().Get("Location"); got != "https://example.com/private" {
+		t.Errorf("Location = %q", got)
+	}
+	cookie := unlockCookie(w, "secret-doc")
+	if cookie == nil {
+		t.Fatal("unlock set no cookie")
+	}
+	if !cookie.HttpOnly || cookie.Path != "/secret-doc" {
+		t.Errorf("cookie HttpOnly = %v, Path = %q", cookie.HttpOnly, cookie.Path)
+	}
+
+	// The cookie lets a GET through with the link's own redirect status
+	req = httptest.NewRequest(http.MethodGet, "/secret-doc", nil)
+	req.AddCookie(cookie)
+	w = serve(router, req)
+	if w.Code != http.StatusFound {
+		t.Fatalf("visit with cookie: status %d, want %d", w.Code, http.StatusFound)
+	}
+	if unlockCookie(w, "secret-doc") != nil {
+		t.Error("visit with cookie issued a new cookie")
+	}
+
+	// The cookie does not unlock another link
+	createProtectedLink(t, urls, "other-doc", "s3cret")
+	req = httptest.NewRequest(http.MethodGet, "/other-doc", nil)
+	req.AddCookie(&http.Cookie{Name: unlockCookieName("other-doc"), Value: cookie.Value})
+	if w := serve(router, req); w.Code != http.StatusUnauthorized {
+		t.Errorf("cookie of another link: status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestRedirectURLPasswordChangeRevokesCookie(t *testing.T) {
+	router, urls := newRedirectTest(t, testGuardConfig())
+	createProtectedLink(t, urls, "secret-doc", "s3cret")
+
+	cookie := unlockCookie(serve(router, unlockRequest("secret-doc", "s3cret")), "secret-doc")
+	if cookie == nil {
+		t.Fatal("unlock set no cookie")
+	}
+
+	newPassword := "n3w-s3cret"
+	if _, err := urls.UpdateURL("secret-doc", 1, service.UpdateURLInput{Password: &newPassword}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/secret-doc", nil)
+	req.AddCookie(cookie)
+	if w := serve(router, req); w.Code != http.StatusUnauthorized {
+		t.Errorf("cookie after password change: status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestRedirectURLThrottlesWrongPasswords(t *testing.T) {
+	router, urls := newRedirectTest(t, testGuardConfig())
+	createProtectedLink(t, urls, "secret-doc", "s3cret")
+
+	for i := 0; i < 3; i++ {
+		if w := serve(router, unlockRequest("secret-doc", "wrong")); w.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: status %d, want %d", i+1, w.Code, http.StatusUnauthorized)
+		}
+	}
+
+	// Locked out: even the right password is refused until the lockout ends
+	w := serve(router, unlockRequest("secret-doc", "s3cret"))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("locked link: status %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q, want 60", got)
+	}
+
+	// Other links are not affected
+	createProtectedLink(t, urls, "other-doc", "s3cret")
+	if w := serve(router, unlockRequest("other-doc", "s3cret")); w.Code != http.StatusSeeOther {
+		t.Errorf("other link: status %d, want %d", w.Code, http.StatusSeeOther)
+	}
+}
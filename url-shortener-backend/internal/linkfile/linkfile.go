@@ -0,0 +1,375 @@
+// Package linkfile reads and writes the CSV and NDJSON files short links are
+// exported to and imported from
+package linkfile
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported file formats
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// tagSeparator joins tags inside one CSV field
+const tagSeparator = "|"
+
+// maxLineSize bounds a single NDJSON line
+const maxLineSize = 1 << 20
+
+var (
+	ErrUnknownFormat = errors.New("format must be csv or ndjson")
+	ErrInvalidHeader = errors.New("CSV header must name an original_url column")
+	ErrInvalidRecord = errors.New("invalid record")
+)
+
+// Record is one link in an export file. Imports only need OriginalURL; the
+// other fields are kept when present.
+type Record struct {
+	ShortCode    string     `json:"short_code,omitempty"`
+	OriginalURL  string     `json:"original_url"`
+	Clicks       int64      `json:"clicks"`
+	Tags         []string   `json:"tags,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	MaxClicks    *int64     `json:"max_clicks,omitempty"`
+	RedirectType int        `json:"redirect_type,omitempty"`
+	Disabled     bool       `json:"disabled"`
+	Protected    bool       `json:"password_protected"` // Exports never contain the password itself
+	Password     string     `json:"password,omitempty"` // Import only - protects the imported link
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+}
+
+// columns are the CSV columns written by exports, in order
+var columns = []string{
+	"short_code", "original_url", "clicks", "tags", "expires_at", "max_clicks",
+	"redirect_type", "disabled", "password_protected", "created_at",
+}
+
+// columnAliases lets files from other shorteners name their columns differently
+var columnAliases = map[string]string{
+	"url":       "original_url",
+	"long_url":  "original_url",
+	"code":      "short_code",
+	"alias":     "short_code",
+	"slug":      "short_code",
+	"protected": "password_protected",
+}
+
+// ParseFormat accepts a format name, a file name or a content type
+func ParseFormat(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch {
+	case value == FormatCSV, strings.HasSuffix(value, ".csv"), strings.HasPrefix(value, "text/csv"):
+		return FormatCSV, nil
+	case value == FormatNDJSON, value == "jsonl",
+		strings.HasSuffix(value, ".ndjson"), strings.HasSuffix(value, ".jsonl"),
+		strings.HasPrefix(value, "application/x-ndjson"), strings.HasPrefix(value, "application/ndjson"):
+		return FormatNDJSON, nil
+	default:
+		return "", ErrUnknownFormat
+	}
+}
+
+// ContentType returns the MIME type of a format
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// Writer writes records in one format. Flush must be called at the end.
+type Writer interface {
+	Write(record Record) error
+	Flush() error
+}
+
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case FormatNDJSON:
+		buffered := bufio.NewWriter(w)
+		return &ndjsonWriter{buf: buffered, enc: json.NewEncoder(buffered)}, nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (cw *csvWriter) Write(record Record) error {
+	if !cw.header {
+		if err := cw.w.Write(columns); err != nil {
+			return err
+		}
+		cw.header = true
+	}
+	return cw.w.Write([]string{
+		record.ShortCode,
+		record.OriginalURL,
+		strconv.FormatInt(record.Clicks, 10),
+		strings.Join(record.Tags, tagSeparator),
+		formatTime(record.ExpiresAt),
+		formatInt(record.MaxClicks),
+		formatRedirectType(record.RedirectType),
+		strconv.FormatBool(record.Disabled),
+		strconv.FormatBool(record.Protected),
+		formatTime(record.CreatedAt),
+	})
+}
+
+// Flush writes the header of an empty export and any buffered rows
+func (cw *csvWriter) Flush() error {
+	if !cw.header {
+		if err := cw.w.Write(columns); err != nil {
+			return err
+		}
+		cw.header = true
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+type ndjsonWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func (nw *ndjsonWriter) Write(record Record) error {
+	record.Password = ""
+	return nw.enc.Encode(record)
+}
+
+func (nw *ndjsonWriter) Flush() error {
+	return nw.buf.Flush()
+}
+
+// Reader reads records one at a time. Read returns io.EOF at the end of the
+// file; an error wrapping ErrInvalidRecord only concerns that record, and
+// reading can go on. Line is the line the last record started on.
+type Reader interface {
+	Read() (Record, error)
+	Line() int
+}
+
+func NewReader(r io.Reader, format string) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		cr.TrimLeadingSpace = true
+		cr.ReuseRecord = true
+		return &csvReader{r: cr}, nil
+	case FormatNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+		return &ndjsonReader{scanner: scanner}, nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+type csvReader struct {
+	r      *csv.Reader
+	index  map[string]int
+	line   int
+	header bool
+}
+
+func (cr *csvReader) Line() int {
+	return cr.line
+}
+
+func (cr *csvReader) Read() (Record, error) {
+	if !cr.header {
+		if err := cr.readHeader(); err != nil {
+			return Record{}, err
+		}
+	}
+
+	fields, err := cr.r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return Record{}, err
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			cr.line = parseErr.StartLine
+			return Record{}, fmt.Errorf("%w: %v", ErrInvalidRecord, parseErr.Err)
+		}
+		return Record{}, err
+	}
+	cr.line, _ = cr.r.FieldPos(0)
+
+	field := func(name string) string {
+		if i, ok := cr.index[name]; ok && i < len(fields) {
+			return strings.TrimSpace(fields[i])
+		}
+		return ""
+	}
+
+	record := Record{
+		ShortCode:   field("short_code"),
+		OriginalURL: field("original_url"),
+		Password:    field("password"),
+	}
+	if value := field("tags"); value != "" {
+		record.Tags = strings.Split(value, tagSeparator)
+	}
+
+	var errs []string
+	check := func(name string, err error) {
+		if err != nil {
+			errs = append(errs, name)
+		}
+	}
+	record.Clicks, err = parseInt(field("clicks"))
+	check("clicks", err)
+	record.ExpiresAt, err = parseTime(field("expires_at"))
+	check("expires_at", err)
+	record.MaxClicks, err = parseOptionalInt(field("max_clicks"))
+	check("max_clicks", err)
+	redirectType, err := parseInt(field("redirect_type"))
+	record.RedirectType = int(redirectType)
+	check("redirect_type", err)
+	record.Disabled, err = parseBool(field("disabled"))
+	check("disabled", err)
+	record.Protected, err = parseBool(field("password_protected"))
+	check("password_protected", err)
+	record.CreatedAt, err = parseTime(field("created_at"))
+	check("created_at", err)
+	if len(errs) > 0 {
+		return Record{}, fmt.Errorf("%w: cannot parse %s", ErrInvalidRecord, strings.Join(errs, ", "))
+	}
+
+	return record, nil
+}
+
+func (cr *csvReader) readHeader() error {
+	header, err := cr.r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+	cr.header = true
+
+	cr.index = make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if alias, ok := columnAliases[name]; ok {
+			name = alias
+		}
+		if _, seen := cr.index[name]; !seen {
+			cr.index[name] = i
+		}
+	}
+	if _, ok := cr.index["original_url"]; !ok {
+		return ErrInvalidHeader
+	}
+	return nil
+}
+
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (nr *ndjsonReader) Line() int {
+	return nr.line
+}
+
+func (nr *ndjsonReader) Read() (Record, error) {
+	for nr.scanner.Scan() {
+		nr.line++
+		line := strings.TrimSpace(nr.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return Record{}, fmt.Errorf("%w: %v", ErrInvalidRecord, err)
+		}
+		return record, nil
+	}
+	if err := nr.scanner.Err(); err != nil {
+		nr.line++
+		if errors.Is(err, bufio.ErrTooLong) {
+			return Record{}, fmt.Errorf("line longer than %d bytes: %w", maxLineSize, err)
+		}
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatInt(n *int64) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.FormatInt(*n, 10)
+}
+
+func formatRedirectType(status int) string {
+	if status == 0 {
+		return ""
+	}
+	return strconv.Itoa(status)
+}
+
+func parseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func parseInt(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func parseOptionalInt(value string) (*int64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func parseBool(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
@@ -0,0 +1,226 @@
+package linkfile
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"csv", FormatCSV},
+		{" CSV ", FormatCSV},
+		{"links.csv", FormatCSV},
+		{"text/csv; charset=utf-8", FormatCSV},
+		{"ndjson", FormatNDJSON},
+		{"jsonl", FormatNDJSON},
+		{"links.NDJSON", FormatNDJSON},
+		{"links.jsonl", FormatNDJSON},
+		{"application/x-ndjson", FormatNDJSON},
+		{"application/ndjson", FormatNDJSON},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseFormat(%q) = %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "json", "links.txt", "application/json"} {
+		if _, err := ParseFormat(value); !errors.Is(err, ErrUnknownFormat) {
+			t.Errorf("ParseFormat(%q): err = %v, want ErrUnknownFormat", value, err)
+		}
+	}
+}
+
+func testRecords() []Record {
+	expires := time.Date(2026, 12, 31, 23, 59, 59, 0, time.UTC)
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	maxClicks := int64(100)
+	return []Record{
+		{
+			ShortCode:    "spring-sale",
+			OriginalURL:  "https://example.com/sale?a=1,2",
+			Clicks:       42,
+			Tags:         []string{"spring", "email"},
+			ExpiresAt:    &expires,
+			MaxClicks:    &maxClicks,
+			RedirectType: 301,
+			Disabled:     true,
+			CreatedAt:    &created,
+		},
+		{
+			ShortCode:   "private",
+			OriginalURL: "https://example.com/private",
+			Protected:   true,
+			Password:    "never-exported",
+			CreatedAt:   &created,
+		},
+	}
+}
+
+// readAll reads every record, failing the test on any error but io.EOF
+func readAll(t *testing.T, r io.Reader, format string) []Record {
+	t.Helper()
+	reader, err := NewReader(r, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []Record
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records
+		}
+		if err != nil {
+			t.Fatalf("line %d: %v", reader.Line(), err)
+		}
+		records = append(records, record)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []string{FormatCSV, FormatNDJSON} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer, err := NewWriter(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, record := range testRecords() {
+				if err := writer.Write(record); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := writer.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			if strings.Contains(buf.String(), "never-exported") {
+				t.Error("export contains a link password")
+			}
+
+			got := readAll(t, &buf, format)
+			want := testRecords()
+			want[1].Password = ""
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("records after a round trip:\n got %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestEmptyExport(t *testing.T) {
+	var buf bytes.Buffer
+	writer, _ := NewWriter(&buf, FormatCSV)
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != strings.Join(columns, ",")+"\n" {
+		t.Errorf("empty CSV export = %q, want only the header", got)
+	}
+
+	buf.Reset()
+	writer, _ = NewWriter(&buf, FormatNDJSON)
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("empty NDJSON export = %q, want nothing", buf.String())
+	}
+
+	if _, err := NewWriter(&buf, "xml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("NewWriter(xml): err = %v, want ErrUnknownFormat", err)
+	}
+	if _, err := NewReader(&buf, "xml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("NewReader(xml): err = %v, want ErrUnknownFormat", err)
+	}
+}
+
+func TestCSVReaderColumns(t *testing.T) {
+	// A BOM, other column names and order, and a password column
+	file := "\ufeffSlug, Long_URL ,protected,password,extra\n" +
+		"docs,https://example.com/docs,true,s3cret,ignored\n" +
+		"short,https://example.com/short\n"
+
+	got := readAll(t, strings.NewReader(file), FormatCSV)
+	want := []Record{
+		{ShortCode: "docs", OriginalURL: "https://example.com/docs", Protected: true, Password: "s3cret"},
+		{ShortCode: "short", OriginalURL: "https://example.com/short"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("records:\n got %+v\nwant %+v", got, want)
+	}
+
+	// original_url is the one required column
+	reader, _ := NewReader(strings.NewReader("short_code,clicks\nabc,1\n"), FormatCSV)
+	if _, err := reader.Read(); !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("header without original_url: err = %v, want ErrInvalidHeader", err)
+	}
+
+	// An empty file has no records
+	if records := readAll(t, strings.NewReader(""), FormatCSV); len(records) != 0 {
+		t.Errorf("empty file: %d records", len(records))
+	}
+}
+
+func TestInvalidRecords(t *testing.T) {
+	tests := []struct {
+		format   string
+		file     string
+		wantLine int
+	}{
+		{FormatCSV, "original_url,clicks,expires_at\n" +
+			"https://example.com/a,many,yesterday\n" +
+			"https://example.com/b,1,\n", 2},
+		{FormatNDJSON, "{\"original_url\":\"https://example.com/a\",\"clicks\":\"many\"}\n" +
+			"\n" +
+			"{\"original_url\":\"https://example.com/b\",\"clicks\":1}\n", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			reader, err := NewReader(strings.NewReader(tt.file), tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// A bad record is reported with its line, and reading goes on
+			if _, err := reader.Read(); !errors.Is(err, ErrInvalidRecord) {
+				t.Fatalf("bad record: err = %v, want ErrInvalidRecord", err)
+			}
+			if reader.Line() != tt.wantLine {
+				t.Errorf("bad record on line %d, want %d", reader.Line(), tt.wantLine)
+			}
+
+			record, err := reader.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if record.OriginalURL != "https://example.com/b" || record.Clicks != 1 {
+				t.Errorf("record after a bad one = %+v", record)
+			}
+			if _, err := reader.Read(); !errors.Is(err, io.EOF) {
+				t.Errorf("end of file: err = %v, want io.EOF", err)
+			}
+		})
+	}
+
+	// The CSV error names the fields that could not be parsed
+	reader, _ := NewReader(strings.NewReader("original_url,clicks,disabled\nhttps://example.com,x,maybe\n"), FormatCSV)
+	if _, err := reader.Read(); err == nil || !strings.Contains(err.Error(), "clicks, disabled") {
+		t.Errorf("err = %v, want it to name clicks and disabled", err)
+	}
+
+	// An NDJSON line over the limit stops the file
+	reader, _ = NewReader(strings.NewReader(strings.Repeat("x", maxLineSize+1)), FormatNDJSON)
+	if _, err := reader.Read(); err == nil || errors.Is(err, ErrInvalidRecord) {
+		t.Errorf("line over the limit: err = %v, want a file error", err)
+	}
+}
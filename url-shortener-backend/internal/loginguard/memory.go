@@ -0,0 +1,94 @@
+package loginguard
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store drops expired counters
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	attempts  Attempts
+	expiresAt time.Time
+}
+
+// memoryStore keeps counters in process memory. Each instance of the service
+// counts on its own, so use the Redis store behind a load balancer.
+type memoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{entries: make(map[string]*memoryEntry), lastSweep: time.Now()}
+}
+
+func (s *memoryStore) Charge(_ context.Context, key string, now time.Time, schedule Schedule, window time.Duration) (Attempts, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	entry := s.entry(key, now)
+	if entry == nil {
+		entry = &memoryEntry{}
+		s.entries[key] = entry
+	}
+	if now.Before(entry.attempts.LockedUntil) {
+		return entry.attempts, false, nil
+	}
+
+	entry.attempts.Failures++
+	block := schedule.Block(entry.attempts.Failures)
+	if block > 0 {
+		entry.attempts.LockedUntil = now.Add(block)
+	}
+	entry.expiresAt = now.Add(window + block)
+	return entry.attempts, true, nil
+}
+
+func (s *memoryStore) Refund(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry := s.entry(key, time.Now()); entry != nil && entry.attempts.Failures > 0 {
+		entry.attempts.Failures--
+	}
+	return nil
+}
+
+func (s *memoryStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// entry returns the live entry of a key, dropping it if it has expired
+func (s *memoryStore) entry(key string, now time.Time) *memoryEntry {
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+	if now.After(entry.expiresAt) {
+		delete(s.entries, key)
+		return nil
+	}
+	return entry
+}
+
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, entry := range s.entries {
+		if now.After(entry.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package loginguard
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisStore keeps counters in Redis so every instance of the service sees
+// the same failures and lockouts. Each key is a hash with a failures counter
+// and a locked_until timestamp (Unix milliseconds), changed by Lua scripts so
+// that counting and deciding is one step.
+type redisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore connects to the server at redisURL (redis://[:password@]host:port/db)
+func NewRedisStore(redisURL, prefix string) (Store, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &redisStore{client: client, prefix: prefix + "login:"}, nil
+}
+
+// chargeScript is Charge as one server-side step.
+// KEYS[1] is the counter; ARGV is now and the window in Unix milliseconds,
+// then the schedule in milliseconds. It returns the failures, locked_until
+// and 1 when the attempt was charged.
+var chargeScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local locked = tonumber(redis.call('HGET', KEYS[1], 'locked_until') or '0')
+if locked > now then
+	return {tonumber(redis.call('HGET', KEYS[1], 'failures') or '0'), locked, 0}
+end
+
+local failures = redis.call('HINCRBY', KEYS[1], 'failures', 1)
+local steps = #ARGV - 2
+local block = 0
+if steps > 0 then
+	block = tonumber(ARGV[2 + math.min(failures, steps)])
+end
+if block > 0 then
+	locked = now + block
+	redis.call('HSET', KEYS[1], 'locked_until', locked)
+end
+redis.call('PEXPIRE', KEYS[1], tonumber(ARGV[2]) + block)
+return {failures, locked, 1}
+`)
+
+// refundScript decrements the failures of a counter that still exists
+var refundScript = redis.NewScript(`
+if tonumber(redis.call('HGET', KEYS[1], 'failures') or '0') > 0 then
+	redis.call('HINCRBY', KEYS[1], 'failures', -1)
+end
+return 0
+`)
+
+func (s *redisStore) Charge(ctx context.Context, key string, now time.Time, schedule Schedule, window time.Duration) (Attempts, bool, error) {
+	args := make([]interface{}, 0, len(schedule)+2)
+	args = append(args, now.UnixMilli(), window.Milliseconds())
+	for _, block := range schedule {
+		args = append(args, block.Milliseconds())
+	}
+
+	result, err := chargeScript.Run(ctx, s.client, []string{s.prefix + key}, args...).Int64Slice()
+	if err != nil {
+		return Attempts{}, false, err
+	}
+
+	var attempts Attempts
+	attempts.Failures = int(result[0])
+	if result[1] > 0 {
+		attempts.LockedUntil = time.UnixMilli(result[1])
+	}
+	return attempts, result[2] == 1, nil
+}
+
+func (s *redisStore) Refund(ctx context.Context, key string) error {
+	return refundScript.Run(ctx, s.client, []string{s.prefix + key}).Err()
+}
+
+func (s *redisStore) Reset(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.prefix+key).Err()
+}
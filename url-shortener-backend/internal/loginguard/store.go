@@ -0,0 +1,43 @@
+package loginguard
+
+import (
+	"context"
+	"time"
+)
+
+// Attempts is the failed-login state of one key (an account or a client IP)
+type Attempts struct {
+	Failures    int       // Failures since the counter last expired or was reset
+	LockedUntil time.Time // Zero when the key is not locked
+}
+
+// Schedule is how long a key is blocked after each number of failures:
+// Schedule[n-1] follows the nth failure, and the last entry also applies to
+// every failure after it. Zero means no block.
+type Schedule []time.Duration
+
+// Block returns the block that follows the given number of failures
+func (s Schedule) Block(failures int) time.Duration {
+	if failures <= 0 || len(s) == 0 {
+		return 0
+	}
+	if failures > len(s) {
+		return s[len(s)-1]
+	}
+	return s[failures-1]
+}
+
+// Store keeps failed-login counters. Counters expire window after the last
+// attempt (plus any block), so old mistakes are forgotten.
+type Store interface {
+	// Charge counts an attempt against the key before its credentials are
+	// checked and blocks the key for what the new count calls for, in one
+	// atomic step, so concurrent attempts cannot all slip past the limit.
+	// A key still blocked at now is refused: the attempt is not counted and
+	// charged is false.
+	Charge(ctx context.Context, key string, now time.Time, schedule Schedule, window time.Duration) (attempts Attempts, charged bool, err error)
+	// Refund takes back one charged attempt that did not fail. A block it
+	// caused is left to run out.
+	Refund(ctx context.Context, key string) error
+	Reset(ctx context.Context, key string) error
+}
@@ -0,0 +1,228 @@
+package loginguard
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// testSchedule lets two attempts through, then blocks for 1s, 2s and 4s after
+func testSchedule() Schedule {
+	return Schedule{0, 0, time.Second, 2 * time.Second, 4 * time.Second}
+}
+
+// testStores returns a memory store and a Redis store backed by miniredis,
+// with a function that moves the clock of the store's key expiry forward
+func testStores(t *testing.T) map[string]struct {
+	store   Store
+	advance func(time.Duration)
+} {
+	t.Helper()
+	server := miniredis.RunT(t)
+	redisStore, err := NewRedisStore("redis://"+server.Addr()+"/0", "test:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]struct {
+		store   Store
+		advance func(time.Duration)
+	}{
+		"memory": {NewMemoryStore(), func(time.Duration) {}},
+		"redis":  {redisStore, server.FastForward},
+	}
+}
+
+func TestScheduleBlock(t *testing.T) {
+	schedule := testSchedule()
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 0},
+		{2, 0},
+		{3, time.Second},
+		{5, 4 * time.Second},
+		{9, 4 * time.Second}, // The last entry repeats
+	}
+	for _, tt := range tests {
+		if got := schedule.Block(tt.failures); got != tt.want {
+			t.Errorf("Block(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+	if got := Schedule(nil).Block(3); got != 0 {
+		t.Errorf("empty schedule Block(3) = %s, want 0", got)
+	}
+}
+
+func TestStoreCharge(t *testing.T) {
+	ctx := context.Background()
+	for name, tt := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			store := tt.store
+			now := time.Now().Truncate(time.Millisecond) // Redis keeps milliseconds
+
+			for want := 1; want <= 2; want++ {
+				attempts, charged, err := store.Charge(ctx, "account:alice", now, testSchedule(), time.Hour)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !charged || attempts.Failures != want || !attempts.LockedUntil.IsZero() {
+					t.Fatalf("free attempt %d: %+v, charged %v", want, attempts, charged)
+				}
+			}
+
+			// The third attempt is let through but blocks the next one
+			attempts, charged, err := store.Charge(ctx, "account:alice", now, testSchedule(), time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !charged || attempts.Failures != 3 {
+				t.Fatalf("third attempt: %+v, charged %v", attempts, charged)
+			}
+			if got := attempts.LockedUntil.Sub(now); got != time.Second {
+				t.Fatalf("third attempt blocks for %s, want 1s", got)
+			}
+
+			// Refused while blocked, without being counted
+			attempts, charged, err = store.Charge(ctx, "account:alice", now.Add(500*time.Millisecond), testSchedule(), time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if charged || attempts.Failures != 3 {
+				t.Fatalf("blocked attempt: %+v, charged %v", attempts, charged)
+			}
+
+			// Counted again once the block is over
+			attempts, charged, err = store.Charge(ctx, "account:alice", now.Add(time.Second), testSchedule(), time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !charged || attempts.Failures != 4 {
+				t.Fatalf("attempt after block: %+v, charged %v", attempts, charged)
+			}
+
+			// Other keys are counted on their own
+			attempts, _, err = store.Charge(ctx, "account:bob", now, testSchedule(), time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if attempts.Failures != 1 {
+				t.Errorf("other key failures = %d, want 1", attempts.Failures)
+			}
+		})
+	}
+}
+
+func TestStoreRefundAndReset(t *testing.T) {
+	ctx := context.Background()
+	for name, tt := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			store := tt.store
+			now := time.Now()
+
+			for i := 0; i < 2; i++ {
+				if _, _, err := store.Charge(ctx, "ip:10.0.0.1", now, testSchedule(), time.Hour); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := store.Refund(ctx, "ip:10.0.0.1"); err != nil {
+				t.Fatal(err)
+			}
+			attempts, _, err := store.Charge(ctx, "ip:10.0.0.1", now, testSchedule(), time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if attempts.Failures != 2 {
+				t.Errorf("failures after a refund = %d, want 2", attempts.Failures)
+			}
+
+			if err := store.Reset(ctx, "ip:10.0.0.1"); err != nil {
+				t.Fatal(err)
+			}
+			attempts, _, err = store.Charge(ctx, "ip:10.0.0.1", now, testSchedule(), time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if attempts.Failures != 1 {
+				t.Errorf("failures after a reset = %d, want 1", attempts.Failures)
+			}
+
+			// Refunding a key that was never charged does not go negative
+			if err := store.Refund(ctx, "ip:10.0.0.2"); err != nil {
+				t.Fatal(err)
+			}
+			attempts, _, err = store.Charge(ctx, "ip:10.0.0.2", now, testSchedule(), time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if attempts.Failures != 1 {
+				t.Errorf("failures after refunding an unknown key = %d, want 1", attempts.Failures)
+			}
+		})
+	}
+}
+
+func TestStoreWindowExpiry(t *testing.T) {
+	ctx := context.Background()
+	for name, tt := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			if _, _, err := tt.store.Charge(ctx, "account:alice", now, testSchedule(), time.Minute); err != nil {
+				t.Fatal(err)
+			}
+
+			// The memory store expires by the time it is given, Redis by its own clock
+			later := now.Add(2 * time.Minute)
+			tt.advance(2 * time.Minute)
+
+			attempts, _, err := tt.store.Charge(ctx, "account:alice", later, testSchedule(), time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if attempts.Failures != 1 {
+				t.Errorf("failures after the window = %d, want 1", attempts.Failures)
+			}
+		})
+	}
+}
+
+func TestStoreConcurrentCharges(t *testing.T) {
+	ctx := context.Background()
+	for name, tt := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			var (
+				wg      sync.WaitGroup
+				mu      sync.Mutex
+				charged int
+			)
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, ok, err := tt.store.Charge(ctx, "account:alice", now, testSchedule(), time.Hour)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					if ok {
+						mu.Lock()
+						charged++
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+
+			// Two free attempts and the one that set the block
+			if charged != 3 {
+				t.Errorf("%d concurrent attempts got through, want 3", charged)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"url-shortener/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// AnonymousCookie holds the signed anonymous identity of browser visitors
+	AnonymousCookie = "anon_token"
+	// AnonymousTokenHeader carries the same token for clients without cookies
+	AnonymousTokenHeader = "X-Anonymous-Token"
+)
+
+// AnonymousIdentity sets anonymousID in the context when the request carries
+// a valid anonymous token. A bad token in the header is rejected; a bad
+// cookie is cleared so the visitor gets a new identity.
+func AnonymousIdentity(identities service.AnonymousIdentityService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.GetHeader(AnonymousTokenHeader); token != "" {
+			anonymousID, err := identities.Verify(token)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			c.Set("anonymousID", anonymousID)
+			c.Next()
+			return
+		}
+
+		if token, err := c.Cookie(AnonymousCookie); err == nil && token != "" {
+			if anonymousID, err := identities.Verify(token); err == nil {
+				c.Set("anonymousID", anonymousID)
+			} else {
+				c.SetCookie(AnonymousCookie, "", -1, "/", "", false, true)
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// GetAnonymousID returns the verified anonymous ID of the request, if any
+func GetAnonymousID(c *gin.Context) (string, bool) {
+	anonymousID, exists := c.Get("anonymousID")
+	if !exists {
+		return "", false
+	}
+	return anonymousID.(string), true
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener/config"
+	"url-shortener/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAnonymousIdentity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	identities := service.NewAnonymousIdentityService(config.AnonymousConfig{Secret: "test-secret", IdentityTTL: time.Hour})
+	identity, err := identities.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := service.NewAnonymousIdentityService(config.AnonymousConfig{Secret: "forged", IdentityTTL: time.Hour})
+	forgedIdentity, err := forged.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := service.NewAnonymousIdentityService(config.AnonymousConfig{Secret: "test-secret", IdentityTTL: -time.Minute})
+	expiredIdentity, err := expired.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.GET("/", AnonymousIdentity(identities), func(c *gin.Context) {
+		anonymousID, _ := GetAnonymousID(c)
+		c.String(http.StatusOK, anonymousID)
+	})
+
+	tests := []struct {
+		name        string
+		header      string
+		cookie      string
+		wantStatus  int
+		wantID      string
+		wantCleared bool
+	}{
+		{"no token", "", "", http.StatusOK, "", false},
+		{"header", identity.Token, "", http.StatusOK, identity.ID, false},
+		{"cookie", "", identity.Token, http.StatusOK, identity.ID, false},
+		{"forged header", forgedIdentity.Token, "", http.StatusUnauthorized, "", false},
+		{"expired header", expiredIdentity.Token, "", http.StatusUnauthorized, "", false},
+		{"forged header beats a valid cookie", forgedIdentity.Token, identity.Token, http.StatusUnauthorized, "", false},
+		// Browsers get a new identity instead of an error
+		{"forged cookie", "", forgedIdentity.Token, http.StatusOK, "", true},
+		{"expired cookie", "", expiredIdentity.Token, http.StatusOK, "", true},
+		{"bare ID as cookie", "", identity.ID, http.StatusOK, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(AnonymousTokenHeader, tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: AnonymousCookie, Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && w.Body.String() != tt.wantID {
+				t.Errorf("anonymousID = %q, want %q", w.Body.String(), tt.wantID)
+			}
+			cleared := false
+			for _, cookie := range w.Result().Cookies() {
+				if cookie.Name == AnonymousCookie && cookie.MaxAge < 0 {
+					cleared = true
+				}
+			}
+			if cleared != tt.wantCleared {
+				t.Errorf("cookie cleared = %v, want %v", cleared, tt.wantCleared)
+			}
+		})
+	}
+}
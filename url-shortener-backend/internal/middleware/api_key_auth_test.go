@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newAPIKeyTest serves GET /links behind APIKeyAuth with the links:read
+// scope and RequireJWT, as the server routes do. The handler answers with the
+// authenticated user's ID.
+func newAPIKeyTest(t *testing.T) (*gin.Engine, service.APIKeyService, *gorm.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	initTestJWT(t)
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&model.User{}, &model.APIKey{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	for _, user := range []*model.User{
+		{ID: 1, Username: "alice", Email: "alice@example.com", Password: "hash"},
+		{ID: 2, Username: "bob", Email: "bob@example.com", Password: "hash", Disabled: true},
+	} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	apiKeys := service.NewAPIKeyService(repository.NewAPIKeyRepository(db), repository.NewUserRepository(db))
+	router := gin.New()
+	router.GET("/links", APIKeyAuth(apiKeys, service.ScopeLinksRead), RequireJWT(), func(c *gin.Context) {
+		c.String(http.StatusOK, strconv.FormatUint(uint64(c.MustGet("userID").(uint)), 10))
+	})
+	return router, apiKeys, db
+}
+
+func createTestAPIKey(t *testing.T, apiKeys service.APIKeyService, userID uint, scopes ...string) (*model.APIKey, string) {
+	t.Helper()
+	key, rawKey, err := apiKeys.CreateKey(userID, service.CreateAPIKeyInput{Name: "test", Scopes: scopes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, rawKey
+}
+
+func getLinks(router *gin.Engine, header, value string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/links", nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	router, apiKeys, db := newAPIKeyTest(t)
+
+	_, readKey := createTestAPIKey(t, apiKeys, 1, service.ScopeLinksRead)
+	_, writeKey := createTestAPIKey(t, apiKeys, 1, service.ScopeLinksWrite, service.ScopeAnalyticsRead)
+	revoked, revokedKey := createTestAPIKey(t, apiKeys, 1, service.ScopeLinksRead)
+	if err := apiKeys.RevokeKey(1, revoked.ID); err != nil {
+		t.Fatal(err)
+	}
+	expired, expiredKey := createTestAPIKey(t, apiKeys, 1, service.ScopeLinksRead)
+	if err := db.Model(expired).Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	_, disabledKey := createTestAPIKey(t, apiKeys, 2, service.ScopeLinksRead)
+	_, orphanKey := createTestAPIKey(t, apiKeys, 3, service.ScopeLinksRead)
+
+	// Same prefix as a real key, another secret
+	forgedKey := readKey[:len(readKey)-4] + "AAAA"
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{"key with the scope", APIKeyHeader, readKey, http.StatusOK},
+		{"key as a bearer token", "Authorization", "Bearer " + readKey, http.StatusOK},
+		{"key without the scope", APIKeyHeader, writeKey, http.StatusForbidden},
+		{"revoked key", APIKeyHeader, revokedKey, http.StatusUnauthorized},
+		{"expired key", APIKeyHeader, expiredKey, http.StatusUnauthorized},
+		{"key of a disabled user", APIKeyHeader, disabledKey, http.StatusForbidden},
+		{"key of a deleted user", APIKeyHeader, orphanKey, http.StatusUnauthorized},
+		{"wrong secret", APIKeyHeader, forgedKey, http.StatusUnauthorized},
+		{"malformed key", APIKeyHeader, "usk_nope", http.StatusUnauthorized},
+		{"no credentials", "", "", http.StatusUnauthorized}, // Passed on to RequireJWT
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := getLinks(router, tt.header, tt.value)
+			if w.Code != tt.want {
+				t.Fatalf("status %d, want %d (body %q)", w.Code, tt.want, w.Body.String())
+			}
+			if tt.want == http.StatusOK && w.Body.String() != "1" {
+				t.Errorf("authenticated as user %s, want 1", w.Body.String())
+			}
+		})
+	}
+
+	// A user's JWT still works on the same route
+	token, err := GenerateAccessToken(1, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := getLinks(router, "Authorization", "Bearer "+token); w.Code != http.StatusOK {
+		t.Errorf("JWT: status %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestAPIKeyAuthRecordsUse(t *testing.T) {
+	router, apiKeys, db := newAPIKeyTest(t)
+	key, rawKey := createTestAPIKey(t, apiKeys, 1, service.ScopeLinksRead)
+
+	if w := getLinks(router, APIKeyHeader, rawKey); w.Code != http.StatusOK {
+		t.Fatalf("status %d", w.Code)
+	}
+	var stored model.APIKey
+	if err := db.First(&stored, key.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.LastUsedAt == nil {
+		t.Fatal("last_used_at was not recorded")
+	}
+
+	// Refused requests are not recorded as uses
+	if err := apiKeys.RevokeKey(1, key.ID); err != nil {
+		t.Fatal(err)
+	}
+	db.Model(&stored).Update("last_used_at", nil)
+	getLinks(router, APIKeyHeader, rawKey)
+	if err := db.First(&stored, key.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.LastUsedAt != nil {
+		t.Error("a revoked key's use was recorded")
+	}
+}
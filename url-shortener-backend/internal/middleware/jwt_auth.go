@@ -1,93 +1,264 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
+	"url-shortener/config"
+	"url-shortener/internal/service"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	gonanoid "github.com/matoous/go-nanoid/v2"
 )
 
-var jwtSecret []byte
+// Token types carried in the typ claim
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	TokenTypeMFA     = "mfa" // Proves the password step of a two-step login
+)
+
+// MFAChallengeTTL is how long a user has to enter their second factor after the password
+const MFAChallengeTTL = 5 * time.Minute
+
+// ErrWrongTokenType is returned when a valid token is used where another type is expected
+var ErrWrongTokenType = errors.New("token type not accepted here")
+
+var (
+	jwtSecret   []byte
+	jwtIssuer   string
+	jwtAudience string
+	jwtKeys     *jwtKeySet // nil when user tokens are signed with jwtSecret
+
+	// userStatus is consulted for every access token once CheckUserStatus
+	// has been called; nil trusts the token alone
+	userStatus func(userID uint) error
+)
 
-func init() {
-	// Use environment variable or default secret (change in production!)
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "your-secret-key-change-this-in-production"
+// InitJWT configures token signing. With a key directory, user tokens are
+// signed with RS256/EdDSA keys; otherwise with the HS256 secret. The secret
+// also signs link unlock tokens in both modes.
+func InitJWT(cfg config.JWTConfig) error {
+	jwtSecret = []byte(cfg.Secret)
+	jwtIssuer = cfg.Issuer
+	jwtAudience = cfg.Audience
+
+	if cfg.KeyDir == "" {
+		jwtKeys = nil
+		return nil
 	}
-	jwtSecret = []byte(secret)
+
+	keys, err := loadJWTKeySet(cfg.KeyDir, cfg.SigningKeyID)
+	if err != nil {
+		return fmt.Errorf("loading JWT keys from %s: %w", cfg.KeyDir, err)
+	}
+	jwtKeys = keys
+	return nil
 }
 
-// Claims represents JWT claims
+// WatchJWTKeys re-reads the key directory periodically so keys can be added
+// and retired without a restart. It returns immediately in HS256 mode.
+func WatchJWTKeys(ctx context.Context, interval time.Duration) {
+	if jwtKeys != nil {
+		jwtKeys.watch(ctx, interval)
+	}
+}
+
+// signToken signs claims with the current signing key, or the HS256 secret
+func signToken(claims jwt.Claims) (string, error) {
+	if jwtKeys == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+	}
+
+	key, err := jwtKeys.signingKey()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.private)
+}
+
+// verificationKey resolves the key a token was signed with from its kid header
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	if jwtKeys == nil {
+		return jwtSecret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := jwtKeys.verificationKey(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+	return key.public, nil
+}
+
+// validMethods lists the algorithms accepted for user tokens in the current mode
+func validMethods() []string {
+	if jwtKeys == nil {
+		return []string{jwt.SigningMethodHS256.Alg()}
+	}
+	return []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+}
+
+// Claims represents JWT claims. Type tells access and refresh tokens apart;
+// iss, aud and jti are carried in the registered claims.
 type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
+	Type     string `json:"typ"`
 	jwt.RegisteredClaims
 }
 
 // GenerateAccessToken generates a short-lived access token (15 minutes)
 func GenerateAccessToken(userID uint, username string) (string, error) {
+	tokenID, err := gonanoid.New()
+	if err != nil {
+		return "", err
+	}
+
 	claims := Claims{
 		UserID:   userID,
 		Username: username,
+		Type:     TokenTypeAccess,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Issuer:    jwtIssuer,
+			Audience:  jwt.ClaimStrings{jwtAudience},
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return signToken(claims)
 }
 
-// GenerateRefreshToken generates a long-lived refresh token (7 days)
-func GenerateRefreshToken(userID uint, username string) (string, error) {
+// GenerateRefreshToken generates a long-lived refresh token (7 days).
+// tokenID becomes the jti claim, which identifies the token's server-side record.
+func GenerateRefreshToken(userID uint, username, tokenID string, expiresAt time.Time) (string, error) {
 	claims := Claims{
 		UserID:   userID,
 		Username: username,
+		Type:     TokenTypeRefresh,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)),
+			ID:        tokenID,
+			Issuer:    jwtIssuer,
+			Audience:  jwt.ClaimStrings{jwtAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return signToken(claims)
 }
 
-// ValidateToken validates JWT token and returns claims
-func ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+// GenerateMFAToken issues the challenge token returned by a password login
+// when the account has two-factor authentication enabled
+func GenerateMFAToken(userID uint, username string) (string, error) {
+	tokenID, err := gonanoid.New()
+	if err != nil {
+		return "", err
+	}
+
+	claims := Claims{
+		UserID:   userID,
+		Username: username,
+		Type:     TokenTypeMFA,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Issuer:    jwtIssuer,
+			Audience:  jwt.ClaimStrings{jwtAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(MFAChallengeTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return signToken(claims)
+}
+
+// ValidateToken validates a JWT token of the expected type (TokenTypeAccess,
+// TokenTypeRefresh or TokenTypeMFA) and returns its claims
+func ValidateToken(tokenString, expectedType string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, verificationKey,
+		jwt.WithValidMethods(validMethods()),
+		jwt.WithIssuer(jwtIssuer),
+		jwt.WithAudience(jwtAudience),
+		jwt.WithExpirationRequired(),
+	)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, jwt.ErrSignatureInvalid
+	}
+	if claims.Type != expectedType || claims.ID == "" {
+		return nil, ErrWrongTokenType
+	}
+
+	return claims, nil
+}
+
+// CheckUserStatus makes RequireJWT and OptionalJWT re-read the token's user
+// on every request, so disabled and deleted accounts lose access at once
+// instead of when their access token expires
+func CheckUserStatus(users service.UserService) {
+	userStatus = func(userID uint) error {
+		user, err := users.GetByID(userID)
+		if err != nil {
+			return err
+		}
+		if user.Disabled {
+			return service.ErrAccountDisabled
+		}
+		return nil
 	}
+}
 
-	return nil, jwt.ErrSignatureInvalid
+// abortForUserStatus rejects a valid token whose user may no longer sign in
+func abortForUserStatus(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrAccountDisabled) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 }
 
 // OptionalJWT provides optional JWT authentication
-// Sets userID in context if valid token present, but doesn't block if not
+// Sets userID in context if valid token present, but doesn't block if not.
+// Requests already authenticated by APIKeyAuth pass through.
 func OptionalJWT() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if authenticatedByAPIKey(c) {
+			c.Next()
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 
 		if authHeader != "" {
 			// Extract token from "Bearer <token>"
 			parts := strings.SplitN(authHeader, " ", 2)
 			if len(parts) == 2 && parts[0] == "Bearer" {
-				claims, err := ValidateToken(parts[1])
+				claims, err := ValidateToken(parts[1], TokenTypeAccess)
+				if err == nil && userStatus != nil {
+					if statusErr := userStatus(claims.UserID); statusErr != nil {
+						abortForUserStatus(c, statusErr)
+						return
+					}
+				}
 				if err == nil {
 					// Token valid, set user info in context
 					c.Set("userID", claims.UserID)
@@ -100,9 +271,15 @@ func OptionalJWT() gin.HandlerFunc {
 	}
 }
 
-// RequireJWT requires valid JWT authentication
+// RequireJWT requires valid JWT authentication, or a request already
+// authenticated by APIKeyAuth
 func RequireJWT() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if authenticatedByAPIKey(c) {
+			c.Next()
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 
 		if authHeader == "" {
@@ -121,13 +298,19 @@ func RequireJWT() gin.HandlerFunc {
 			return
 		}
 
-		claims, err := ValidateToken(parts[1])
+		claims, err := ValidateToken(parts[1], TokenTypeAccess)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error": "Invalid or expired token",
 			})
 			return
 		}
+		if userStatus != nil {
+			if err := userStatus(claims.UserID); err != nil {
+				abortForUserStatus(c, err)
+				return
+			}
+		}
 
 		// Store user info in context
 		c.Set("userID", claims.UserID)
@@ -147,3 +330,37 @@ func GetUserIDFromJWT(c *gin.Context) (uint, bool) {
 	id, ok := userID.(uint)
 	return id, ok
 }
+
+// JWTIssuer exposes token signing and verification to the service layer,
+// which cannot import this package
+type JWTIssuer struct{}
+
+func (JWTIssuer) AccessToken(userID uint, username string) (string, error) {
+	return GenerateAccessToken(userID, username)
+}
+
+func (JWTIssuer) RefreshToken(userID uint, username, tokenID string, expiresAt time.Time) (string, error) {
+	return GenerateRefreshToken(userID, username, tokenID, expiresAt)
+}
+
+// RefreshTokenID verifies a refresh token and returns its jti claim
+func (JWTIssuer) RefreshTokenID(tokenString string) (string, error) {
+	claims, err := ValidateToken(tokenString, TokenTypeRefresh)
+	if err != nil {
+		return "", err
+	}
+	return claims.ID, nil
+}
+
+func (JWTIssuer) MFAToken(userID uint, username string) (string, error) {
+	return GenerateMFAToken(userID, username)
+}
+
+// MFATokenUserID verifies an MFA challenge token and returns the user it was issued to
+func (JWTIssuer) MFATokenUserID(tokenString string) (uint, error) {
+	claims, err := ValidateToken(tokenString, TokenTypeMFA)
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserID, nil
+}
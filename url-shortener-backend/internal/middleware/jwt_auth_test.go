@@ -0,0 +1,220 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener/internal/model"
+	"url-shortener/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	testIssuer   = "url-shortener-test"
+	testAudience = "url-shortener-test-api"
+)
+
+func initTestJWT(t *testing.T) {
+	t.Helper()
+	jwtSecret = []byte("test-secret")
+	jwtIssuer = testIssuer
+	jwtAudience = testAudience
+}
+
+// signedTestToken signs an access token with the test secret after letting
+// modify change its claims
+func signedTestToken(t *testing.T, modify func(*Claims)) string {
+	t.Helper()
+	claims := Claims{
+		UserID:   1,
+		Username: "alice",
+		Type:     TokenTypeAccess,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "token-id",
+			Issuer:    testIssuer,
+			Audience:  jwt.ClaimStrings{testAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	modify(&claims)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestValidateToken(t *testing.T) {
+	initTestJWT(t)
+
+	access, err := GenerateAccessToken(1, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refresh, err := GenerateRefreshToken(1, "alice", "refresh-id", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfa, err := GenerateMFAToken(1, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		token        string
+		expectedType string
+		wantErr      bool
+	}{
+		{"access token as access", access, TokenTypeAccess, false},
+		{"refresh token as refresh", refresh, TokenTypeRefresh, false},
+		{"mfa token as mfa", mfa, TokenTypeMFA, false},
+		{"refresh token as access", refresh, TokenTypeAccess, true},
+		{"mfa token as access", mfa, TokenTypeAccess, true},
+		{"access token as refresh", access, TokenTypeRefresh, true},
+		{"mfa token as refresh", mfa, TokenTypeRefresh, true},
+		{"wrong issuer", signedTestToken(t, func(c *Claims) { c.Issuer = "someone-else" }), TokenTypeAccess, true},
+		{"wrong audience", signedTestToken(t, func(c *Claims) { c.Audience = jwt.ClaimStrings{"another-api"} }), TokenTypeAccess, true},
+		{"missing jti", signedTestToken(t, func(c *Claims) { c.ID = "" }), TokenTypeAccess, true},
+		{"missing expiry", signedTestToken(t, func(c *Claims) { c.ExpiresAt = nil }), TokenTypeAccess, true},
+		{"expired", signedTestToken(t, func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) }), TokenTypeAccess, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ValidateToken(tt.token, tt.expectedType)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ValidateToken accepted the token as %s", tt.expectedType)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateToken: %v", err)
+			}
+			if claims.UserID != 1 {
+				t.Errorf("UserID = %d, want 1", claims.UserID)
+			}
+		})
+	}
+}
+
+func TestJWTMiddlewareTokenTypes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	initTestJWT(t)
+
+	access, err := GenerateAccessToken(1, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refresh, err := GenerateRefreshToken(1, "alice", "refresh-id", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfa, err := GenerateMFAToken(1, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		token        string
+		wantRequired int  // Status returned behind RequireJWT
+		wantUser     bool // Whether OptionalJWT sets the user
+	}{
+		{"access token", access, http.StatusOK, true},
+		{"refresh token", refresh, http.StatusUnauthorized, false},
+		{"mfa token", mfa, http.StatusUnauthorized, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/required", RequireJWT(), func(c *gin.Context) { c.Status(http.StatusOK) })
+			router.GET("/optional", OptionalJWT(), func(c *gin.Context) {
+				if _, ok := GetUserIDFromJWT(c); ok {
+					c.Status(http.StatusOK)
+					return
+				}
+				c.Status(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/required", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantRequired {
+				t.Errorf("RequireJWT status = %d, want %d", w.Code, tt.wantRequired)
+			}
+
+			req = httptest.NewRequest(http.MethodGet, "/optional", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if gotUser := w.Code == http.StatusOK; gotUser != tt.wantUser {
+				t.Errorf("OptionalJWT set the user = %v, want %v", gotUser, tt.wantUser)
+			}
+		})
+	}
+}
+
+// fakeUsers serves GetByID from a map; other UserService methods are not used
+type fakeUsers struct {
+	service.UserService
+	users map[uint]*model.User
+}
+
+func (f fakeUsers) GetByID(id uint) (*model.User, error) {
+	if user, ok := f.users[id]; ok {
+		return user, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestJWTMiddlewareUserStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	initTestJWT(t)
+	CheckUserStatus(fakeUsers{users: map[uint]*model.User{
+		1: {ID: 1, Username: "alice"},
+		2: {ID: 2, Username: "bob", Disabled: true},
+	}})
+	t.Cleanup(func() { userStatus = nil })
+
+	tests := []struct {
+		name   string
+		userID uint
+		want   int
+	}{
+		{"active user", 1, http.StatusOK},
+		{"disabled user", 2, http.StatusForbidden},
+		{"deleted user", 3, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := GenerateAccessToken(tt.userID, "someone")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// A regular, non-admin route
+			for _, mw := range []gin.HandlerFunc{RequireJWT(), OptionalJWT()} {
+				router := gin.New()
+				router.GET("/api/me", mw, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+				req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+				req.Header.Set("Authorization", "Bearer "+token)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				if w.Code != tt.want {
+					t.Errorf("status = %d, want %d", w.Code, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSAKeyBits is the smallest RSA key accepted for signing or verification
+const minRSAKeyBits = 2048
+
+// jwtKey is one key of the key directory
+type jwtKey struct {
+	kid        string
+	method     jwt.SigningMethod // RS256 or EdDSA
+	private    crypto.Signer     // nil for verify-only keys
+	public     crypto.PublicKey
+	activeFrom time.Time // When the key may start signing; zero when it may sign at once
+}
+
+// jwtKeySet holds the keys loaded from JWT_KEY_DIR. Every key verifies; one
+// private key signs. A key whose kid names a later date or time is published
+// in the JWKS until then before it takes over signing, so verifiers can pick
+// it up first.
+type jwtKeySet struct {
+	dir       string
+	pinnedKID string
+
+	mu   sync.RWMutex
+	keys map[string]*jwtKey
+}
+
+func loadJWTKeySet(dir, pinnedKID string) (*jwtKeySet, error) {
+	set := &jwtKeySet{dir: dir, pinnedKID: pinnedKID}
+	if err := set.reload(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// reload re-reads the key directory. On error the current keys stay in use.
+func (s *jwtKeySet) reload() error {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*jwtKey, len(files))
+	for _, file := range files {
+		key, err := readJWTKey(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(file), err)
+		}
+		keys[key.kid] = key
+	}
+
+	if _, err := pickSigningKey(keys, s.pinnedKID, time.Now()); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+
+// watch reloads the key directory on every tick until the context is cancelled
+func (s *jwtKeySet) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.reload(); err != nil {
+				log.Println("JWT keys: reload failed, keeping current keys:", err)
+			}
+		}
+	}
+}
+
+func (s *jwtKeySet) signingKey() (*jwtKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return pickSigningKey(s.keys, s.pinnedKID, time.Now())
+}
+
+func (s *jwtKeySet) verificationKey(kid string) (*jwtKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+func (s *jwtKeySet) publicKeys() []*jwtKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]*jwtKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].kid < keys[j].kid })
+	return keys
+}
+
+// pickSigningKey returns the pinned key, or the active private key with the
+// greatest kid (use sortable kids such as 2026-10-01). When no key is active
+// yet, the key that activates first is used.
+func pickSigningKey(keys map[string]*jwtKey, pinnedKID string, now time.Time) (*jwtKey, error) {
+	if pinnedKID != "" {
+		key, ok := keys[pinnedKID]
+		if !ok || key.private == nil {
+			return nil, fmt.Errorf("signing key %q not found or has no private key", pinnedKID)
+		}
+		return key, nil
+	}
+
+	var active, earliest *jwtKey
+	for _, key := range keys {
+		if key.private == nil {
+			continue
+		}
+		if !key.activeFrom.After(now) && (active == nil || key.kid > active.kid) {
+			active = key
+		}
+		if earliest == nil || key.activeFrom.Before(earliest.activeFrom) {
+			earliest = key
+		}
+	}
+
+	if active != nil {
+		return active, nil
+	}
+	if earliest != nil {
+		return earliest, nil
+	}
+	return nil, errors.New("no private key available for signing")
+}
+
+// keyActivation returns when the key with the given kid may start signing. A
+// kid that is an RFC 3339 time, or starts with a date (2026-10-01,
+// 2026-10-01-rsa, taken as 00:00 UTC), activates then; other keys at once.
+// File times are not used: copying or restoring the directory changes them.
+func keyActivation(kid string) time.Time {
+	if t, err := time.Parse(time.RFC3339, kid); err == nil {
+		return t
+	}
+	if len(kid) >= len(time.DateOnly) {
+		if t, err := time.Parse(time.DateOnly, kid[:len(time.DateOnly)]); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// readJWTKey parses a PEM private or public key; the kid is the file name without .pem
+func readJWTKey(file string) (*jwtKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+	key := &jwtKey{kid: kid, activeFrom: keyActivation(kid)}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.method, key.public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.method, key.public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", parsed)
+	}
+
+	if rsaKey, ok := key.public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("RSA key must be at least %d bits", minRSAKeyBits)
+	}
+	return key, nil
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty" example:"RSA"`
+	Kid string `json:"kid" example:"2026-10-01"`
+	Use string `json:"use" example:"sig"`
+	Alg string `json:"alg" example:"RS256"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // Ed25519 curve
+	X   string `json:"x,omitempty"`   // Ed25519 public key
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWKS returns the public keys that verify user tokens. It is empty
+// when tokens are signed with the shared HS256 secret.
+func PublicJWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	if jwtKeys == nil {
+		return jwks
+	}
+
+	for _, key := range jwtKeys.publicKeys() {
+		jwk := JWK{Kid: key.kid, Use: "sig", Alg: key.method.Alg()}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
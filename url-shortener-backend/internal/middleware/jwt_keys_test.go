@@ -0,0 +1,352 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"url-shortener/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// initTestJWTKeys switches token signing to the key directory and back to
+// HS256 when the test ends
+func initTestJWTKeys(t *testing.T, dir string) {
+	t.Helper()
+	cfg := config.JWTConfig{Secret: "test-secret", Issuer: testIssuer, Audience: testAudience, KeyDir: dir}
+	if err := InitJWT(cfg); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		InitJWT(config.JWTConfig{Secret: "test-secret", Issuer: testIssuer, Audience: testAudience})
+	})
+}
+
+// writeKey writes key as <kid>.pem into dir; private keys are PKCS#8, public keys PKIX
+func writeKey(t *testing.T, dir, kid string, key interface{}) {
+	t.Helper()
+	var (
+		block pem.Block
+		err   error
+	)
+	switch key.(type) {
+	case *rsa.PrivateKey, ed25519.PrivateKey:
+		block.Type = "PRIVATE KEY"
+		block.Bytes, err = x509.MarshalPKCS8PrivateKey(key)
+	default:
+		block.Type = "PUBLIC KEY"
+		block.Bytes, err = x509.MarshalPKIXPublicKey(key)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), pem.EncodeToMemory(&block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, minRSAKeyBits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// tokenKID returns the kid header of a signed token
+func tokenKID(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+// day returns the date kid of the day offset days from today
+func day(offset int) string {
+	return time.Now().UTC().AddDate(0, 0, offset).Format(time.DateOnly)
+}
+
+func TestKeyActivation(t *testing.T) {
+	tests := []struct {
+		kid  string
+		want time.Time
+	}{
+		{"2026-10-01", time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)},
+		{"2026-10-01-rsa", time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)},
+		{"2026-10-01T12:30:00Z", time.Date(2026, 10, 1, 12, 30, 0, 0, time.UTC)},
+		{"2026-10-01T12:30:00+02:00", time.Date(2026, 10, 1, 10, 30, 0, 0, time.UTC)},
+		{"primary", time.Time{}},
+		{"2026-13-01", time.Time{}},
+		{"2026", time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := keyActivation(tt.kid); !got.Equal(tt.want) {
+			t.Errorf("keyActivation(%q) = %s, want %s", tt.kid, got, tt.want)
+		}
+	}
+}
+
+func TestPickSigningKey(t *testing.T) {
+	now := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	signer := newEd25519Key(t)
+	key := func(kid string, private bool) *jwtKey {
+		k := &jwtKey{kid: kid, method: jwt.SigningMethodEdDSA, public: signer.Public(), activeFrom: keyActivation(kid)}
+		if private {
+			k.private = signer
+		}
+		return k
+	}
+	keySet := func(keys ...*jwtKey) map[string]*jwtKey {
+		m := make(map[string]*jwtKey, len(keys))
+		for _, k := range keys {
+			m[k.kid] = k
+		}
+		return m
+	}
+
+	tests := []struct {
+		name      string
+		keys      map[string]*jwtKey
+		pinnedKID string
+		want      string
+	}{
+		{"greatest active kid", keySet(key("2026-09-01", true), key("2026-10-01", true)), "", "2026-10-01"},
+		{"future key is skipped", keySet(key("2026-10-01", true), key("2026-11-01", true)), "", "2026-10-01"},
+		{"verify-only key is skipped", keySet(key("2026-09-01", true), key("2026-10-01", false)), "", "2026-09-01"},
+		{"undated key is active", keySet(key("primary", true), key("2026-11-01", true)), "", "primary"},
+		{"earliest when none is active", keySet(key("2026-12-01", true), key("2026-11-01", true)), "", "2026-11-01"},
+		{"pinned key", keySet(key("2026-09-01", true), key("2026-10-01", true)), "2026-09-01", "2026-09-01"},
+		{"pinned future key", keySet(key("2026-10-01", true), key("2026-11-01", true)), "2026-11-01", "2026-11-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pickSigningKey(tt.keys, tt.pinnedKID, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.kid != tt.want {
+				t.Errorf("signing key = %s, want %s", got.kid, tt.want)
+			}
+		})
+	}
+
+	// Errors: nothing can sign, or the pinned key cannot
+	if _, err := pickSigningKey(keySet(key("2026-10-01", false)), "", now); err == nil {
+		t.Error("no private key: want an error")
+	}
+	if _, err := pickSigningKey(keySet(key("2026-10-01", true)), "missing", now); err == nil {
+		t.Error("unknown pinned key: want an error")
+	}
+	if _, err := pickSigningKey(keySet(key("2026-10-01", false)), "2026-10-01", now); err == nil {
+		t.Error("verify-only pinned key: want an error")
+	}
+}
+
+func TestJWTKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldKID, nextKID, futureKID := day(-30), day(-1), day(30)
+	oldKey := newEd25519Key(t)
+	writeKey(t, dir, oldKID, oldKey)
+	initTestJWTKeys(t, dir)
+
+	oldToken, err := GenerateAccessToken(1, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid := tokenKID(t, oldToken); kid != oldKID {
+		t.Fatalf("token kid = %s, want %s", kid, oldKID)
+	}
+	if _, err := ValidateToken(oldToken, TokenTypeAccess); err != nil {
+		t.Fatal(err)
+	}
+
+	// A key that activates later is loaded but does not sign yet
+	writeKey(t, dir, futureKID, newRSAKey(t))
+	if err := jwtKeys.reload(); err != nil {
+		t.Fatal(err)
+	}
+	token, err := GenerateAccessToken(1, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid := tokenKID(t, token); kid != oldKID {
+		t.Errorf("with a future key: token kid = %s, want %s", kid, oldKID)
+	}
+
+	// An active key with a greater kid takes over; old tokens keep verifying
+	writeKey(t, dir, nextKID, newRSAKey(t))
+	if err := jwtKeys.reload(); err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := GenerateAccessToken(1, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid := tokenKID(t, newToken); kid != nextKID {
+		t.Fatalf("after rotation: token kid = %s, want %s", kid, nextKID)
+	}
+	if _, err := ValidateToken(newToken, TokenTypeAccess); err != nil {
+		t.Errorf("new token: %v", err)
+	}
+	if _, err := ValidateToken(oldToken, TokenTypeAccess); err != nil {
+		t.Errorf("old token after rotation: %v", err)
+	}
+
+	// Replacing the old private key with its public key keeps it verifying
+	writeKey(t, dir, oldKID, oldKey.Public())
+	if err := jwtKeys.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateToken(oldToken, TokenTypeAccess); err != nil {
+		t.Errorf("old token after retiring its key: %v", err)
+	}
+
+	// Deleting it rejects the tokens it signed
+	if err := os.Remove(filepath.Join(dir, oldKID+".pem")); err != nil {
+		t.Fatal(err)
+	}
+	if err := jwtKeys.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateToken(oldToken, TokenTypeAccess); err == nil {
+		t.Error("token of a deleted key was accepted")
+	}
+
+	// A broken file fails the reload and keeps the current keys
+	if err := os.WriteFile(filepath.Join(dir, "broken.pem"), []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := jwtKeys.reload(); err == nil {
+		t.Error("reload with a broken key: want an error")
+	}
+	if _, err := ValidateToken(newToken, TokenTypeAccess); err != nil {
+		t.Errorf("after a failed reload: %v", err)
+	}
+}
+
+func TestJWTKeysRejectForgedTokens(t *testing.T) {
+	dir := t.TempDir()
+	kid := day(-1)
+	writeKey(t, dir, kid, newEd25519Key(t))
+	initTestJWTKeys(t, dir)
+
+	claims := Claims{
+		UserID: 1,
+		Type:   TokenTypeAccess,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    testIssuer,
+			Audience:  jwt.ClaimStrings{testAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+
+	// HS256 with the shared secret is not accepted once keys are configured
+	hs256, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateToken(hs256, TokenTypeAccess); err == nil {
+		t.Error("HS256 token accepted in key mode")
+	}
+
+	// A key that is not in the directory, under a known kid
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = kid
+	forged, err := token.SignedString(newEd25519Key(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateToken(forged, TokenTypeAccess); err == nil {
+		t.Error("token signed with an unknown key accepted")
+	}
+}
+
+func TestLoadJWTKeySetErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "verify-only", newEd25519Key(t).Public())
+	if _, err := loadJWTKeySet(dir, ""); err == nil {
+		t.Error("directory without a private key: want an error")
+	}
+
+	dir = t.TempDir()
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeKey(t, dir, "small", small)
+	if _, err := loadJWTKeySet(dir, ""); err == nil {
+		t.Error("1024-bit RSA key: want an error")
+	}
+}
+
+func TestPublicJWKS(t *testing.T) {
+	initTestJWT(t)
+	jwtKeys = nil
+	if jwks := PublicJWKS(); jwks.Keys == nil || len(jwks.Keys) != 0 {
+		t.Errorf("HS256 mode: JWKS = %+v, want an empty list", jwks)
+	}
+
+	dir := t.TempDir()
+	rsaKey := newRSAKey(t)
+	edKey := newEd25519Key(t)
+	writeKey(t, dir, "2026-09-01", rsaKey)
+	writeKey(t, dir, "2026-08-01", edKey.Public())
+	initTestJWTKeys(t, dir)
+
+	jwks := PublicJWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("JWKS has %d keys, want 2", len(jwks.Keys))
+	}
+
+	// Sorted by kid; verify-only keys are published too
+	ed, rs := jwks.Keys[0], jwks.Keys[1]
+	if ed.Kid != "2026-08-01" || ed.Kty != "OKP" || ed.Crv != "Ed25519" || ed.Alg != "EdDSA" || ed.Use != "sig" {
+		t.Errorf("Ed25519 JWK = %+v", ed)
+	}
+	if x, err := base64.RawURLEncoding.DecodeString(ed.X); err != nil || !ed25519.PublicKey(x).Equal(edKey.Public()) {
+		t.Errorf("Ed25519 x does not decode to the public key: %v", err)
+	}
+	if ed.N != "" || ed.E != "" {
+		t.Errorf("Ed25519 JWK has RSA members: %+v", ed)
+	}
+
+	if rs.Kid != "2026-09-01" || rs.Kty != "RSA" || rs.Alg != "RS256" || rs.Use != "sig" || rs.Crv != "" || rs.X != "" {
+		t.Errorf("RSA JWK = %+v", rs)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(rs.N)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(rs.E)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	if !pub.Equal(&rsaKey.PublicKey) {
+		t.Error("RSA n and e do not decode to the public key")
+	}
+	if rs.E != "AQAB" {
+		t.Errorf("RSA e = %q, want AQAB", rs.E)
+	}
+}
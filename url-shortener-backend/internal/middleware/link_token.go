@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// LinkAccessTTL is how long an unlocked password-protected link stays unlocked
+const LinkAccessTTL = 30 * time.Minute
+
+// LinkAccessClaims grants access to a single password-protected short link
+// for as long as its password stays the same
+type LinkAccessClaims struct {
+	ShortCode   string `json:"short_code"`
+	PasswordKey string `json:"pwk"`
+	jwt.RegisteredClaims
+}
+
+// linkAccessSecret is derived from the JWT secret so unlock tokens can never
+// be used as user tokens and vice versa
+func linkAccessSecret() []byte {
+	sum := sha256.Sum256(append([]byte("link-access:"), jwtSecret...))
+	return sum[:]
+}
+
+// linkPasswordKey ties an unlock token to the link's password hash, so
+// changing or removing the password revokes every token issued before
+func linkPasswordKey(passwordHash string) string {
+	mac := hmac.New(sha256.New, linkAccessSecret())
+	mac.Write([]byte(passwordHash))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// GenerateLinkAccessToken issues a short-lived token that unlocks the given
+// short code while its password hash is passwordHash
+func GenerateLinkAccessToken(code, passwordHash string) (string, error) {
+	claims := LinkAccessClaims{
+		ShortCode:   code,
+		PasswordKey: linkPasswordKey(passwordHash),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(LinkAccessTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(linkAccessSecret())
+}
+
+// ValidateLinkAccessToken reports whether the token unlocks the given short
+// code with its current password hash
+func ValidateLinkAccessToken(tokenString, code, passwordHash string) bool {
+	token, err := jwt.ParseWithClaims(tokenString, &LinkAccessClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return linkAccessSecret(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return false
+	}
+
+	claims, ok := token.Claims.(*LinkAccessClaims)
+	if !ok || !token.Valid || claims.ShortCode != code {
+		return false
+	}
+	return hmac.Equal([]byte(claims.PasswordKey), []byte(linkPasswordKey(passwordHash)))
+}
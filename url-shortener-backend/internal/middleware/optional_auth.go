@@ -52,19 +52,6 @@ func RequireAuth(userService service.UserService) gin.HandlerFunc {
 	}
 }
 
-// AdminAuth requires admin authentication (for demo purposes)
-func AdminAuth() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Check if admin header is present
-		adminKey := c.GetHeader("X-Admin-Key")
-		if adminKey != "admin-secret-key" {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
-			return
-		}
-		c.Next()
-	}
-}
-
 // GetUserID helper to extract userID from context
 func GetUserID(c *gin.Context) (uint, bool) {
 	userIDInterface, exists := c.Get("userID")
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"url-shortener/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole allows only active users with one of the given roles. It must
+// follow RequireJWT. The user is re-read on every request, so role changes
+// and disabled accounts take effect immediately.
+func RequireRole(users service.UserService, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := GetUserIDFromJWT(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		user, err := users.GetByID(userID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+		if user.Disabled {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": service.ErrAccountDisabled.Error()})
+			return
+		}
+
+		for _, role := range roles {
+			if user.Role == role {
+				c.Set("role", user.Role)
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"url-shortener/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireVerifiedEmail blocks signed-in users who have not verified their
+// email address. Anonymous requests pass through. It must follow the
+// authentication middleware of the route.
+func RequireVerifiedEmail(users service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := GetUserIDFromJWT(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		user, err := users.GetByID(userID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+		if user.EmailVerifiedAt == nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": service.ErrEmailNotVerified.Error()})
+			return
+		}
+
+		c.Next()
+	}
+}
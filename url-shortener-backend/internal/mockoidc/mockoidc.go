@@ -0,0 +1,288 @@
+// Package mockoidc is a minimal OpenID Connect provider for trying out and
+// testing the sign-in flow locally. It signs in whoever asks, so never
+// expose it outside a development machine or a test.
+//
+// Adding login_hint=<email> to the authorization request skips the login form.
+package mockoidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	keyID   = "mock-key"
+	codeTTL = time.Minute
+	idTTL   = time.Hour
+)
+
+// authRequest is what an issued authorization code stands for
+type authRequest struct {
+	clientID      string
+	redirectURI   string
+	nonce         string
+	challenge     string
+	email         string
+	emailVerified bool
+	expiresAt     time.Time
+}
+
+// Provider serves the discovery document, JWKS, authorization and token
+// endpoints of one issuer with one registered client
+type Provider struct {
+	issuer   string
+	clientID string
+	key      *rsa.PrivateKey
+	mux      *http.ServeMux
+
+	mu    sync.Mutex
+	codes map[string]authRequest
+}
+
+var loginForm = template.Must(template.New("login").Parse(`<!doctype html>
+<title>Mock OIDC sign-in</title>
+<h1>Mock OIDC sign-in</h1>
+<form method="post">
+{{range $k, $v := .Params}}<input type="hidden" name="{{$k}}" value="{{index $v 0}}">
+{{end}}<label>Email <input name="login_hint" value="{{.Email}}"></label>
+<label><input type="checkbox" name="email_verified" value="true" checked> Email verified</label>
+<button>Sign in</button>
+<button name="deny" value="1">Deny</button>
+</form>`))
+
+// New creates a provider for the issuer URL it will be served at. It signs
+// ID tokens with a fresh RSA key.
+func New(issuer, clientID string) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Provider{
+		issuer:   strings.TrimRight(issuer, "/"),
+		clientID: clientID,
+		key:      key,
+		mux:      http.NewServeMux(),
+		codes:    make(map[string]authRequest),
+	}
+	p.mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	p.mux.HandleFunc("/jwks", p.jwks)
+	p.mux.HandleFunc("/authorize", p.authorize)
+	p.mux.HandleFunc("/token", p.token)
+	return p, nil
+}
+
+func (p *Provider) Issuer() string {
+	return p.issuer
+}
+
+func (p *Provider) ClientID() string {
+	return p.clientID
+}
+
+func (p *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mux.ServeHTTP(w, r)
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize shows the login form, or signs in straight away when the request
+// carries a login_hint
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	params := r.Form
+
+	redirectURI := params.Get("redirect_uri")
+	if params.Get("client_id") != p.clientID || redirectURI == "" {
+		http.Error(w, "unknown client_id or missing redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if params.Get("response_type") != "code" || params.Get("code_challenge_method") != "S256" || params.Get("code_challenge") == "" {
+		redirectError(w, r, redirectURI, params.Get("state"), "invalid_request", "response_type=code with an S256 code_challenge is required")
+		return
+	}
+	if params.Get("deny") != "" {
+		redirectError(w, r, redirectURI, params.Get("state"), "access_denied", "the user denied the request")
+		return
+	}
+
+	email := params.Get("login_hint")
+	if email == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		loginForm.Execute(w, map[string]interface{}{"Params": params, "Email": "dev@example.com"})
+		return
+	}
+
+	// A form post without the checkbox means an unverified email
+	verified := r.Method == http.MethodGet || params.Get("email_verified") == "true"
+
+	code, err := randomString()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	p.mu.Lock()
+	p.codes[code] = authRequest{
+		clientID:      p.clientID,
+		redirectURI:   redirectURI,
+		nonce:         params.Get("nonce"),
+		challenge:     params.Get("code_challenge"),
+		email:         email,
+		emailVerified: verified,
+		expiresAt:     time.Now().Add(codeTTL),
+	}
+	p.mu.Unlock()
+
+	query := url.Values{"code": {code}, "state": {params.Get("state")}}
+	http.Redirect(w, r, redirectURI+"?"+query.Encode(), http.StatusFound)
+}
+
+// token exchanges an authorization code for an ID token after checking the
+// client, the redirect URI and the PKCE verifier
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request", err.Error())
+		return
+	}
+
+	clientID, _, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostForm.Get("client_id")
+	}
+
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	req, found := p.codes[code]
+	delete(p.codes, code) // Codes work once
+	p.mu.Unlock()
+
+	switch {
+	case r.PostForm.Get("grant_type") != "authorization_code":
+		tokenError(w, "unsupported_grant_type", "only authorization_code is supported")
+		return
+	case !found || time.Now().After(req.expiresAt):
+		tokenError(w, "invalid_grant", "unknown or expired code")
+		return
+	case clientID != req.clientID || r.PostForm.Get("redirect_uri") != req.redirectURI:
+		tokenError(w, "invalid_grant", "client_id or redirect_uri does not match")
+		return
+	case pkceChallenge(r.PostForm.Get("code_verifier")) != req.challenge:
+		tokenError(w, "invalid_grant", "code_verifier does not match code_challenge")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                p.issuer,
+		"sub":                subjectFor(req.email),
+		"aud":                req.clientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(idTTL).Unix(),
+		"email":              req.email,
+		"email_verified":     req.emailVerified,
+		"preferred_username": strings.SplitN(req.email, "@", 2)[0],
+	}
+	if req.nonce != "" {
+		claims["nonce"] = req.nonce
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	accessToken, err := randomString()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(idTTL.Seconds()),
+		"id_token":     idToken,
+	})
+}
+
+// subjectFor gives each email a stable subject, like a real provider's user ID
+func subjectFor(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(email)))
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func redirectError(w http.ResponseWriter, r *http.Request, redirectURI, state, code, description string) {
+	query := url.Values{"error": {code}, "error_description": {description}, "state": {state}}
+	http.Redirect(w, r, redirectURI+"?"+query.Encode(), http.StatusFound)
+}
+
+func tokenError(w http.ResponseWriter, code, description string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code, "error_description": description})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// APIKey is a long-lived credential for scripts and integrations. Only a hash
+// of the key is stored; Prefix is kept in clear so users can recognise it.
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id" example:"1"`
+	UserID     uint       `gorm:"not null;index" json:"-"`
+	Name       string     `gorm:"not null" json:"name" example:"CI pipeline"`
+	Prefix     string     `gorm:"uniqueIndex;not null" json:"prefix" example:"usk_Xb3kq9Zp"`
+	KeyHash    string     `gorm:"not null" json:"-"`
+	Scopes     []string   `gorm:"serializer:json" json:"scopes" example:"links:read,links:write"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" example:"2025-12-18T10:00:00Z"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" example:"2026-12-18T10:00:00Z"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" example:"2025-12-19T10:00:00Z"`
+	CreatedAt  time.Time  `json:"created_at" example:"2025-12-18T10:00:00Z"`
+}
+
+// HasScope reports whether the key grants the scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
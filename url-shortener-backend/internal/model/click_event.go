@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// ClickEvent records a single redirect through a short link
+type ClickEvent struct {
+	ID             uint      `gorm:"primaryKey" json:"id" example:"1"`
+	ShortCode      string    `gorm:"not null;index:idx_click_events_code_time,priority:1" json:"short_code" example:"abc12345"`
+	Referrer       string    `json:"referrer" example:"https://news.ycombinator.com/item?id=1"`
+	ReferrerDomain string    `gorm:"index" json:"referrer_domain" example:"news.ycombinator.com"` // "direct" when no referrer was sent
+	UserAgent      string    `json:"user_agent" example:"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"`
+	Browser        string    `json:"browser" example:"Safari"`
+	OS             string    `json:"os" example:"iOS"`
+	DeviceType     string    `json:"device_type" example:"mobile"` // desktop, mobile, tablet or bot
+	IPHash         string    `gorm:"index" json:"ip_hash" example:"9f86d081884c7d65"`
+	AcceptLanguage string    `json:"accept_language" example:"en-US,en;q=0.9"`
+	CreatedAt      time.Time `gorm:"index:idx_click_events_code_time,priority:2" json:"created_at" example:"2025-12-18T10:00:00Z"`
+}
@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// States of an import job
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// ImportJob tracks a file of links being imported in the background
+type ImportJob struct {
+	ID         uint             `gorm:"primaryKey" json:"id" example:"1"`
+	UserID     uint             `gorm:"not null;index" json:"user_id" example:"1"`
+	Status     string           `gorm:"not null;index" json:"status" example:"running"` // pending, running, completed or failed
+	Format     string           `json:"format" example:"csv"`
+	OnConflict string           `json:"on_conflict" example:"skip"`                // skip or rename
+	FileSize   int64            `json:"file_size" example:"1048576"`               // Bytes uploaded
+	BytesRead  int64            `json:"bytes_read" example:"524288"`               // Progress through the file
+	Progress   int              `json:"progress" example:"50"`                     // Percent of the file processed
+	Rows       int              `json:"rows" example:"5000"`                       // Rows processed so far
+	Imported   int              `json:"imported" example:"4990"`                   // Links created with their original short code
+	Renamed    int              `json:"renamed" example:"3"`                       // Links created with a new code because theirs was taken
+	Conflicts  int              `json:"conflicts" example:"5"`                     // Rows skipped because their short code was taken
+	Failed     int              `json:"failed" example:"2"`                        // Rows that could not be imported, conflicts excluded
+	Errors     []ImportRowError `gorm:"serializer:json" json:"errors"`             // Conflicts and failures, up to IMPORT_MAX_ERRORS
+	Error      string           `json:"error,omitempty" example:"file is not CSV"` // Why a failed job stopped
+	StartedAt  *time.Time       `json:"started_at,omitempty" example:"2026-10-17T12:00:01Z"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty" example:"2026-10-17T12:00:09Z"`
+	CreatedAt  time.Time        `json:"created_at" example:"2026-10-17T12:00:00Z"`
+	UpdatedAt  time.Time        `json:"updated_at" example:"2026-10-17T12:00:05Z"`
+}
+
+// ImportRowError explains why one row of an import was not imported as is
+type ImportRowError struct {
+	Line      int    `json:"line" example:"42"`
+	ShortCode string `json:"short_code,omitempty" example:"spring-sale"`
+	ErrorCode string `json:"error_code" example:"alias_taken"`
+	Error     string `json:"error" example:"custom alias is already taken"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// LinkClaim records that a user took over a link created under an anonymous identity
+type LinkClaim struct {
+	ID          uint      `gorm:"primaryKey" json:"id" example:"1"`
+	UserID      uint      `gorm:"not null;index" json:"user_id" example:"1"`
+	AnonymousID string    `gorm:"not null;index" json:"anonymous_id" example:"anon_3f2b9c0e5d7a41c88e6f0b2d4a9c1e57"`
+	ShortCode   string    `gorm:"not null;index" json:"short_code" example:"abc12345"`
+	ClientIP    string    `json:"client_ip" example:"203.0.113.7"` // IP of the claim request
+	CreatedAt   time.Time `json:"created_at" example:"2026-10-17T12:00:00Z"`
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Scopes of a login lockout
+const (
+	LockoutScopeAccount = "account"
+	LockoutScopeIP      = "ip"
+)
+
+// LoginLockout records that too many failed sign-ins locked an account or a client IP
+type LoginLockout struct {
+	ID          uint      `gorm:"primaryKey" json:"id" example:"1"`
+	Scope       string    `gorm:"not null;index" json:"scope" example:"account"`            // account or ip
+	Subject     string    `gorm:"not null;index" json:"subject" example:"john@example.com"` // Email (or MFA user) or client IP
+	ClientIP    string    `json:"client_ip" example:"203.0.113.7"`                          // IP of the attempt that triggered the lockout
+	Failures    int       `json:"failures" example:"10"`
+	LockedUntil time.Time `json:"locked_until" example:"2026-10-17T12:15:00Z"`
+	CreatedAt   time.Time `json:"created_at" example:"2026-10-17T12:00:00Z"`
+}
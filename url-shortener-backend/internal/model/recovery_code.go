@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// RecoveryCode is a single-use code that replaces a TOTP code when the
+// authenticator is lost. Only a hash of the code is stored.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"uniqueIndex;not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// RefreshToken is an issued refresh token. Only a hash of the token is stored.
+// Every rotation issues a new token in the same family; presenting a token that
+// was already rotated revokes the whole family.
+type RefreshToken struct {
+	ID          uint       `gorm:"primaryKey" json:"id" example:"1"`
+	UserID      uint       `gorm:"not null;index" json:"user_id" example:"1"`
+	JTI         string     `gorm:"uniqueIndex;not null" json:"-"`
+	FamilyID    string     `gorm:"index;not null" json:"family_id" example:"Vd3kq9ZpX1mN0bQe"`
+	TokenHash   string     `gorm:"not null" json:"-"`
+	DeviceLabel string     `json:"device_label" example:"Chrome on macOS"`
+	UserAgent   string     `json:"user_agent" example:"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0)"`
+	ExpiresAt   time.Time  `gorm:"index" json:"expires_at" example:"2025-12-25T10:00:00Z"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty" example:"2025-12-19T10:00:00Z"`
+	ReplacedBy  string     `json:"-"` // JTI of the token issued when this one was rotated
+	CreatedAt   time.Time  `json:"created_at" example:"2025-12-18T10:00:00Z"`
+}
@@ -1,15 +1,52 @@
 package model
 
-import "time"
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // URL represents a shortened URL entry
 type URL struct {
-	ID          uint      `gorm:"primaryKey" json:"id" example:"1"`
-	UserID      *uint     `gorm:"index" json:"user_id,omitempty" example:"1"`                                         // Nullable - for logged-in users
-	AnonymousID *string   `gorm:"index" json:"anonymous_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"` // Nullable - for anonymous users
-	ShortCode   string    `gorm:"uniqueIndex;not null" json:"short_code" example:"abc12345"`
-	OriginalURL string    `gorm:"not null" json:"original_url" example:"https://example.com/very/long/path"`
-	Clicks      int64     `gorm:"default:0" json:"clicks" example:"42"`
-	CreatedAt   time.Time `json:"created_at" example:"2025-12-18T10:00:00Z"`
-	UpdatedAt   time.Time `json:"updated_at" example:"2025-12-18T10:00:00Z"`
+	ID             uint       `gorm:"primaryKey" json:"id" example:"1"`
+	UserID         *uint      `gorm:"index" json:"user_id,omitempty" example:"1"`                                         // Nullable - for logged-in users
+	AnonymousID    *string    `gorm:"index" json:"anonymous_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"` // Nullable - for anonymous users
+	CreatorIPHash  string     `gorm:"index" json:"-"`                                                                     // Keyed hash of the client IP - anonymous links only, for creation quotas
+	CreatorAnonID  string     `gorm:"index" json:"-"`                                                                     // Anonymous identity that created the link - kept after claiming, for creation quotas
+	ShortCode      string     `gorm:"uniqueIndex;not null" json:"short_code" example:"abc12345"`
+	OriginalURL    string     `gorm:"not null" json:"original_url" example:"https://example.com/very/long/path"`
+	Domain         string     `gorm:"index" json:"domain" example:"example.com"` // Lower-cased host of OriginalURL, kept in sync by BeforeSave
+	Clicks         int64      `gorm:"default:0" json:"clicks" example:"42"`
+	Disabled       bool       `gorm:"default:false" json:"disabled" example:"false"`                       // Paused by the owner - redirects return 410
+	Password       string     `json:"-"`                                                                   // bcrypt hash - empty for public links
+	Protected      bool       `gorm:"default:false" json:"password_protected" example:"false"`             // Visitors must unlock the link with its password
+	RedirectType   int        `gorm:"default:0" json:"redirect_type,omitempty" example:"302"`              // 301, 302, 307 or 308 - 0 uses the server default
+	Tags           []string   `gorm:"serializer:json" json:"tags,omitempty" example:"spring,email"`        // Lower-cased labels chosen by the owner
+	ExpiresAt      *time.Time `gorm:"index" json:"expires_at,omitempty" example:"2025-12-31T23:59:59Z"`    // Nullable - link stops working after this time
+	MaxClicks      *int64     `json:"max_clicks,omitempty" example:"100"`                                  // Nullable - link stops working after this many clicks
+	ExpiredAt      *time.Time `gorm:"index" json:"expired_at,omitempty" example:"2026-01-01T00:00:00Z"`    // Set by the expiry sweeper once the link has expired
+	AnonymousTTL   bool       `gorm:"default:false" json:"anonymous_ttl,omitempty" example:"true"`         // ExpiresAt is the anonymous link TTL - claiming the link removes it
+	TakenDownAt    *time.Time `gorm:"index" json:"taken_down_at,omitempty" example:"2026-01-01T00:00:00Z"` // Set when an admin takes the link down - redirects return 410
+	TakedownReason string     `json:"takedown_reason,omitempty" example:"Phishing"`
+	CreatedAt      time.Time  `json:"created_at" example:"2025-12-18T10:00:00Z"`
+	UpdatedAt      time.Time  `json:"updated_at" example:"2025-12-18T10:00:00Z"`
+
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty" swaggertype:"string" format:"date-time"` // Soft delete - code stays reserved
+}
+
+// BeforeSave keeps Domain in sync with OriginalURL
+func (u *URL) BeforeSave(tx *gorm.DB) error {
+	u.Domain = DomainOf(u.OriginalURL)
+	return nil
+}
+
+// DomainOf returns the lower-cased host of a URL, or "" if it cannot be parsed
+func DomainOf(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
 }
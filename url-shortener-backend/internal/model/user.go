@@ -2,12 +2,25 @@ package model
 
 import "time"
 
+// User roles
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 // User represents a registered user
 type User struct {
-	ID        uint      `gorm:"primaryKey" json:"id" example:"1"`
-	Username  string    `gorm:"uniqueIndex;not null" json:"username" example:"john_doe"`
-	Password  string    `gorm:"not null" json:"-"` // Hidden from JSON
-	Email     string    `gorm:"uniqueIndex" json:"email" example:"john@example.com"`
-	CreatedAt time.Time `json:"created_at" example:"2025-12-18T10:00:00Z"`
-	UpdatedAt time.Time `json:"updated_at" example:"2025-12-18T10:00:00Z"`
+	ID                uint       `gorm:"primaryKey" json:"id" example:"1"`
+	Username          string     `gorm:"uniqueIndex;not null" json:"username" example:"john_doe"`
+	Password          string     `gorm:"not null" json:"-"` // Hidden from JSON
+	Email             string     `gorm:"uniqueIndex" json:"email" example:"john@example.com"`
+	Role              string     `gorm:"not null;default:user" json:"role" example:"user"`           // user or admin
+	Disabled          bool       `gorm:"default:false" json:"disabled" example:"false"`              // Disabled by an admin - cannot sign in
+	EmailVerifiedAt   *time.Time `json:"email_verified_at,omitempty" example:"2025-12-18T10:05:00Z"` // Nil until the user follows the verification email
+	TOTPEnabled       bool       `gorm:"default:false" json:"totp_enabled" example:"false"`          // Login requires a TOTP or recovery code
+	TOTPSecret        string     `json:"-"`                                                          // Base32 secret of the confirmed authenticator
+	TOTPPendingSecret string     `json:"-"`                                                          // Secret being enrolled, until confirmed with a code
+	TOTPLastStep      int64      `json:"-"`                                                          // Time step of the last accepted code, so a code cannot be replayed
+	CreatedAt         time.Time  `json:"created_at" example:"2025-12-18T10:00:00Z"`
+	UpdatedAt         time.Time  `json:"updated_at" example:"2025-12-18T10:00:00Z"`
 }
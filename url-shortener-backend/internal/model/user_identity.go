@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// UserIdentity links an account at an external OpenID Connect provider to a user
+type UserIdentity struct {
+	ID          uint      `gorm:"primaryKey" json:"id" example:"1"`
+	UserID      uint      `gorm:"not null;index" json:"user_id" example:"1"`
+	Provider    string    `gorm:"not null;uniqueIndex:idx_user_identities_subject" json:"provider" example:"company"`
+	Subject     string    `gorm:"not null;uniqueIndex:idx_user_identities_subject" json:"subject" example:"248289761001"` // The provider's stable user ID (sub claim)
+	Email       string    `json:"email" example:"john@example.com"`                                                       // Verified email at the time of linking
+	LastLoginAt time.Time `json:"last_login_at" example:"2026-10-17T12:00:00Z"`
+	CreatedAt   time.Time `json:"created_at" example:"2026-10-17T12:00:00Z"`
+}
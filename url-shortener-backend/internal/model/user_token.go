@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Purposes of single-use user tokens
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposeOIDCLogin         = "oidc_login" // Hands a finished OIDC sign-in to the frontend
+)
+
+// UserToken is a single-use token emailed to a user, e.g. for a password
+// reset. Only a hash of the token is stored.
+type UserToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	Purpose   string    `gorm:"not null"`
+	TokenHash string    `gorm:"uniqueIndex;not null"`
+	Email     string    // Address the token was sent to
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+	"url-shortener/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// Supported timeline bucket sizes
+const (
+	BucketHour = "hour"
+	BucketDay  = "day"
+	BucketWeek = "week"
+)
+
+// Columns of click_events that can be used for breakdowns
+const (
+	BreakdownReferrer = "referrer_domain"
+	BreakdownBrowser  = "browser"
+	BreakdownOS       = "os"
+	BreakdownDevice   = "device_type"
+)
+
+// BucketCount is the number of clicks in one time bucket
+type BucketCount struct {
+	Bucket string `json:"bucket" example:"2025-12-18T00:00:00Z"`
+	Clicks int64  `json:"clicks" example:"12"`
+}
+
+// ValueCount is the number of clicks for one breakdown value
+type ValueCount struct {
+	Value  string `json:"value" example:"google.com"`
+	Clicks int64  `json:"clicks" example:"7"`
+}
+
+type AnalyticsRepository interface {
+	RecordClicks(events []*model.ClickEvent) error
+	CountClicks(code string, from, to time.Time) (int64, error)
+	CountByBucket(code, bucket string, from, to time.Time) ([]BucketCount, error)
+	CountByColumn(code, column string, from, to time.Time, limit int) ([]ValueCount, error)
+}
+
+type analyticsRepository struct {
+	db *gorm.DB
+}
+
+func NewAnalyticsRepository(db *gorm.DB) AnalyticsRepository {
+	return &analyticsRepository{db: db}
+}
+
+// RecordClicks inserts click events in one statement
+func (r *analyticsRepository) RecordClicks(events []*model.ClickEvent) error {
+	return r.db.Create(events).Error
+}
+
+func (r *analyticsRepository) CountClicks(code string, from, to time.Time) (int64, error) {
+	var count int64
+	err := r.clicksBetween(code, from, to).Count(&count).Error
+	return count, err
+}
+
+func (r *analyticsRepository) CountByBucket(code, bucket string, from, to time.Time) ([]BucketCount, error) {
+	expr, err := r.bucketExpr(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := []BucketCount{}
+	err = r.clicksBetween(code, from, to).
+		Select(expr + " AS bucket, COUNT(*) AS clicks").
+		Group("bucket").
+		Order("bucket").
+		Scan(&counts).Error
+	return counts, err
+}
+
+func (r *analyticsRepository) CountByColumn(code, column string, from, to time.Time, limit int) ([]ValueCount, error) {
+	switch column {
+	case BreakdownReferrer, BreakdownBrowser, BreakdownOS, BreakdownDevice:
+	default:
+		return nil, fmt.Errorf("unsupported breakdown column %q", column)
+	}
+
+	counts := []ValueCount{}
+	err := r.clicksBetween(code, from, to).
+		Select(column + " AS value, COUNT(*) AS clicks").
+		Group(column).
+		Order("clicks DESC").
+		Limit(limit).
+		Scan(&counts).Error
+	return counts, err
+}
+
+func (r *analyticsRepository) clicksBetween(code string, from, to time.Time) *gorm.DB {
+	return r.db.Model(&model.ClickEvent{}).
+		Where("short_code = ? AND created_at >= ? AND created_at < ?", code, from, to)
+}
+
+// bucketExpr truncates created_at to the start of its bucket (UTC, weeks start on Monday)
+func (r *analyticsRepository) bucketExpr(bucket string) (string, error) {
+	postgres := r.db.Dialector.Name() == "postgres"
+
+	switch bucket {
+	case BucketHour:
+		if postgres {
+			return `to_char(date_trunc('hour', created_at AT TIME ZONE 'UTC'), 'YYYY-MM-DD"T"HH24:00:00"Z"')`, nil
+		}
+		return `strftime('%Y-%m-%dT%H:00:00Z', created_at)`, nil
+	case BucketDay:
+		if postgres {
+			return `to_char(date_trunc('day', created_at AT TIME ZONE 'UTC'), 'YYYY-MM-DD"T00:00:00Z"')`, nil
+		}
+		return `strftime('%Y-%m-%dT00:00:00Z', created_at)`, nil
+	case BucketWeek:
+		if postgres {
+			return `to_char(date_trunc('week', created_at AT TIME ZONE 'UTC'), 'YYYY-MM-DD"T00:00:00Z"')`, nil
+		}
+		return `strftime('%Y-%m-%dT00:00:00Z', created_at, 'weekday 0', '-6 days')`, nil
+	default:
+		return "", fmt.Errorf("unsupported bucket %q", bucket)
+	}
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"log"
+	"time"
+	"url-shortener/internal/cache"
+	"url-shortener/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// notFoundMarker is cached for unknown codes so repeated typos do not hit the database
+var notFoundMarker = []byte{0}
+
+// cachedURLRepository decorates a URLRepository with a read-through cache for
+// short code lookups. Only the redirect lookup (FindByShortCodeWithDeleted) is
+// served from the cache; writes that can change a link invalidate its entry.
+// Link password hashes never reach the cache: password-protected links are
+// not cached and are always read from the database.
+type cachedURLRepository struct {
+	URLRepository
+	cache       cache.Cache
+	ttl         time.Duration
+	negativeTTL time.Duration
+}
+
+func NewCachedURLRepository(repo URLRepository, c cache.Cache, ttl, negativeTTL time.Duration) URLRepository {
+	return &cachedURLRepository{
+		URLRepository: repo,
+		cache:         c,
+		ttl:           ttl,
+		negativeTTL:   negativeTTL,
+	}
+}
+
+func (r *cachedURLRepository) FindByShortCodeWithDeleted(code string) (*model.URL, error) {
+	ctx := context.Background()
+	key := urlCacheKey(code)
+
+	data, err := r.cache.Get(ctx, key)
+	switch {
+	case err == nil && bytes.Equal(data, notFoundMarker):
+		return nil, gorm.ErrRecordNotFound
+	case err == nil:
+		var url model.URL
+		if decodeErr := gob.NewDecoder(bytes.NewReader(data)).Decode(&url); decodeErr == nil && !url.Protected {
+			return &url, nil
+		}
+		r.invalidate(code)
+	case !errors.Is(err, cache.ErrMiss):
+		// A broken cache must never break redirects
+		log.Println("URL cache: lookup failed:", err)
+	}
+
+	url, err := r.URLRepository.FindByShortCodeWithDeleted(code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		r.set(key, notFoundMarker, r.negativeTTL)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if url.Protected {
+		// Visitors need the password hash, which is not cached
+		return url, nil
+	}
+	cached := *url
+	cached.Password = "" // Empty for public links; cleared so it can never leak
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&cached); err == nil {
+		r.set(key, buf.Bytes(), r.ttl)
+	}
+	return url, nil
+}
+
+func (r *cachedURLRepository) Create(url *model.URL) error {
+	// Drop a cached "not found" for the new code
+	defer r.invalidate(url.ShortCode)
+	return r.URLRepository.Create(url)
+}
+
+func (r *cachedURLRepository) CreateBatch(urls []*model.URL) error {
+	codes := make([]string, len(urls))
+	for i, url := range urls {
+		codes[i] = url.ShortCode
+	}
+	defer r.invalidate(codes...)
+	return r.URLRepository.CreateBatch(urls)
+}
+
+func (r *cachedURLRepository) Update(url *model.URL) error {
+	defer r.invalidate(url.ShortCode)
+	return r.URLRepository.Update(url)
+}
+
+func (r *cachedURLRepository) Delete(url *model.URL) error {
+	defer r.invalidate(url.ShortCode)
+	return r.URLRepository.Delete(url)
+}
+
+func (r *cachedURLRepository) Restore(url *model.URL) error {
+	defer r.invalidate(url.ShortCode)
+	return r.URLRepository.Restore(url)
+}
+
+func (r *cachedURLRepository) ConsumeClick(code string, now time.Time) (bool, error) {
+	// The cached click count of budgeted links must not drift from the database
+	defer r.invalidate(code)
+	return r.URLRepository.ConsumeClick(code, now)
+}
+
+func (r *cachedURLRepository) MarkExpired(now time.Time) ([]string, error) {
+	codes, err := r.URLRepository.MarkExpired(now)
+	r.invalidate(codes...)
+	return codes, err
+}
+
+func (r *cachedURLRepository) PurgeExpired(before time.Time) ([]string, error) {
+	codes, err := r.URLRepository.PurgeExpired(before)
+	r.invalidate(codes...)
+	return codes, err
+}
+
+func (r *cachedURLRepository) DeleteByUserID(userID uint) ([]string, error) {
+	codes, err := r.URLRepository.DeleteByUserID(userID)
+	r.invalidate(codes...)
+	return codes, err
+}
+
+func (r *cachedURLRepository) OrphanByUserID(userID uint) ([]string, error) {
+	codes, err := r.URLRepository.OrphanByUserID(userID)
+	r.invalidate(codes...)
+	return codes, err
+}
+
+func (r *cachedURLRepository) PurgeExpiredAnonymous(before time.Time) ([]string, error) {
+	codes, err := r.URLRepository.PurgeExpiredAnonymous(before)
+	r.invalidate(codes...)
+	return codes, err
+}
+
+func (r *cachedURLRepository) ClaimAnonymousURLs(userID uint, anonymousID string, codes []string, clientIP string) ([]model.URL, error) {
+	claimed, err := r.URLRepository.ClaimAnonymousURLs(userID, anonymousID, codes, clientIP)
+	for _, url := range claimed {
+		r.invalidate(url.ShortCode)
+	}
+	return claimed, err
+}
+
+func (r *cachedURLRepository) set(key string, value []byte, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	if err := r.cache.Set(context.Background(), key, value, ttl); err != nil {
+		log.Println("URL cache: store failed:", err)
+	}
+}
+
+func (r *cachedURLRepository) invalidate(codes ...string) {
+	if len(codes) == 0 {
+		return
+	}
+	keys := make([]string, len(codes))
+	for i, code := range codes {
+		keys[i] = urlCacheKey(code)
+	}
+	if err := r.cache.Delete(context.Background(), keys...); err != nil {
+		log.Println("URL cache: invalidation failed:", err)
+	}
+}
+
+func urlCacheKey(code string) string {
+	return "url:" + code
+}
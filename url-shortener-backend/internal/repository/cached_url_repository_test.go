@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"testing"
+	"time"
+	"url-shortener/internal/cache"
+	"url-shortener/internal/model"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&model.URL{}, &model.ClickEvent{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestCachedURLRepositoryInvalidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, repo URLRepository, db *gorm.DB, url *model.URL)
+		check  func(t *testing.T, url *model.URL, err error)
+	}{
+		{
+			name: "update",
+			change: func(t *testing.T, repo URLRepository, _ *gorm.DB, url *model.URL) {
+				url.OriginalURL = "https://example.org/updated"
+				if err := repo.Update(url); err != nil {
+					t.Fatal(err)
+				}
+			},
+			check: func(t *testing.T, url *model.URL, err error) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if url.OriginalURL != "https://example.org/updated" {
+					t.Errorf("OriginalURL = %q, want the updated URL", url.OriginalURL)
+				}
+			},
+		},
+		{
+			name: "delete",
+			change: func(t *testing.T, repo URLRepository, _ *gorm.DB, url *model.URL) {
+				if err := repo.Delete(url); err != nil {
+					t.Fatal(err)
+				}
+			},
+			check: func(t *testing.T, url *model.URL, err error) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !url.DeletedAt.Valid {
+					t.Error("DeletedAt is not set after delete")
+				}
+			},
+		},
+		{
+			name: "restore",
+			change: func(t *testing.T, repo URLRepository, _ *gorm.DB, url *model.URL) {
+				if err := repo.Delete(url); err != nil {
+					t.Fatal(err)
+				}
+				// Cache the deleted link before restoring it
+				if _, err := repo.FindByShortCodeWithDeleted(url.ShortCode); err != nil {
+					t.Fatal(err)
+				}
+				if err := repo.Restore(url); err != nil {
+					t.Fatal(err)
+				}
+			},
+			check: func(t *testing.T, url *model.URL, err error) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if url.DeletedAt.Valid {
+					t.Error("DeletedAt is still set after restore")
+				}
+			},
+		},
+		{
+			name: "purge expired",
+			change: func(t *testing.T, repo URLRepository, db *gorm.DB, url *model.URL) {
+				// Expire the link behind the cache, as the expiry sweeper does
+				expiredAt := time.Now().Add(-time.Hour)
+				if err := db.Model(url).Update("expired_at", expiredAt).Error; err != nil {
+					t.Fatal(err)
+				}
+				codes, err := repo.PurgeExpired(time.Now())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(codes) != 1 || codes[0] != url.ShortCode {
+					t.Fatalf("PurgeExpired returned %v, want [%s]", codes, url.ShortCode)
+				}
+			},
+			check: func(t *testing.T, _ *model.URL, err error) {
+				if !errors.Is(err, gorm.ErrRecordNotFound) {
+					t.Errorf("err = %v, want gorm.ErrRecordNotFound", err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			repo := NewCachedURLRepository(NewURLRepository(db), cache.NewMemoryCache(10), time.Hour, time.Hour)
+
+			url := &model.URL{ShortCode: "abc12345", OriginalURL: "https://example.com"}
+			if err := repo.Create(url); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := repo.FindByShortCodeWithDeleted(url.ShortCode); err != nil {
+				t.Fatal(err)
+			}
+
+			tt.change(t, repo, db, url)
+
+			cached, err := repo.FindByShortCodeWithDeleted(url.ShortCode)
+			tt.check(t, cached, err)
+		})
+	}
+}
+
+func TestCachedURLRepositoryLeavesOutPasswords(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	c := cache.NewMemoryCache(10)
+	repo := NewCachedURLRepository(NewURLRepository(db), c, time.Hour, time.Hour)
+
+	public := &model.URL{ShortCode: "public01", OriginalURL: "https://example.com"}
+	protected := &model.URL{ShortCode: "secret01", OriginalURL: "https://example.com", Protected: true, Password: "$2a$10$hash"}
+	for _, url := range []*model.URL{public, protected} {
+		if err := repo.Create(url); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.FindByShortCodeWithDeleted(url.ShortCode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Public links are cached
+	if _, err := c.Get(ctx, urlCacheKey(public.ShortCode)); err != nil {
+		t.Errorf("public link is not cached: %v", err)
+	}
+
+	// Protected links are not, and still come with their hash
+	if _, err := c.Get(ctx, urlCacheKey(protected.ShortCode)); !errors.Is(err, cache.ErrMiss) {
+		t.Errorf("protected link is cached: err = %v", err)
+	}
+	url, err := repo.FindByShortCodeWithDeleted(protected.ShortCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url.Password != protected.Password {
+		t.Errorf("Password = %q, want the stored hash", url.Password)
+	}
+
+	// A protected entry cached by an older version is dropped, not served
+	stale := *protected
+	stale.OriginalURL = "https://example.org/stale"
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&stale); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(ctx, urlCacheKey(protected.ShortCode), buf.Bytes(), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	url, err = repo.FindByShortCodeWithDeleted(protected.ShortCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url.OriginalURL != protected.OriginalURL {
+		t.Errorf("OriginalURL = %q, want the stored URL", url.OriginalURL)
+	}
+	if _, err := c.Get(ctx, urlCacheKey(protected.ShortCode)); !errors.Is(err, cache.ErrMiss) {
+		t.Errorf("stale protected entry was kept: err = %v", err)
+	}
+
+	// Protecting a cached public link drops its entry
+	public.Protected, public.Password = true, "$2a$10$hash"
+	if err := repo.Update(public); err != nil {
+		t.Fatal(err)
+	}
+	url, err = repo.FindByShortCodeWithDeleted(public.ShortCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url.Password != public.Password {
+		t.Errorf("after protecting: Password = %q, want the stored hash", url.Password)
+	}
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"time"
+	"url-shortener/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type ImportJobRepository interface {
+	Create(job *model.ImportJob) error
+	Update(job *model.ImportJob) error
+	FindByID(id uint) (*model.ImportJob, error)
+	FailUnfinished(reason string) (int64, error)
+}
+
+type importJobRepository struct {
+	db *gorm.DB
+}
+
+func NewImportJobRepository(db *gorm.DB) ImportJobRepository {
+	return &importJobRepository{db: db}
+}
+
+func (r *importJobRepository) Create(job *model.ImportJob) error {
+	return r.db.Create(job).Error
+}
+
+func (r *importJobRepository) Update(job *model.ImportJob) error {
+	return r.db.Save(job).Error
+}
+
+func (r *importJobRepository) FindByID(id uint) (*model.ImportJob, error) {
+	var job model.ImportJob
+	if err := r.db.First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// FailUnfinished marks pending and running jobs as failed. Jobs run inside
+// the server process, so any still unfinished at startup were interrupted.
+func (r *importJobRepository) FailUnfinished(reason string) (int64, error) {
+	result := r.db.Model(&model.ImportJob{}).
+		Where("status IN ?", []string{model.ImportStatusPending, model.ImportStatusRunning}).
+		Updates(map[string]interface{}{"status": model.ImportStatusFailed, "error": reason, "finished_at": time.Now()})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"url-shortener/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// LinkClaimFilter selects claim records; zero fields match everything
+type LinkClaimFilter struct {
+	UserID      uint
+	AnonymousID string
+	ShortCode   string
+}
+
+// LinkClaimRepository reads the claim audit trail. Claims are written by
+// URLRepository.ClaimAnonymousURLs in the same transaction as the links.
+type LinkClaimRepository interface {
+	ClaimedCodes(userID uint, anonymousID string, codes []string) ([]string, error)
+	List(filter LinkClaimFilter, limit, offset int) ([]model.LinkClaim, int64, error)
+}
+
+type linkClaimRepository struct {
+	db *gorm.DB
+}
+
+func NewLinkClaimRepository(db *gorm.DB) LinkClaimRepository {
+	return &linkClaimRepository{db: db}
+}
+
+// ClaimedCodes returns which of the codes the user already claimed from the anonymous identity
+func (r *linkClaimRepository) ClaimedCodes(userID uint, anonymousID string, codes []string) ([]string, error) {
+	var claimed []string
+	if len(codes) == 0 {
+		return claimed, nil
+	}
+	err := r.db.Model(&model.LinkClaim{}).
+		Where("user_id = ? AND anonymous_id = ? AND short_code IN ?", userID, anonymousID, codes).
+		Distinct().
+		Pluck("short_code", &claimed).Error
+	return claimed, err
+}
+
+// List returns a page of claims, newest first
+func (r *linkClaimRepository) List(filter LinkClaimFilter, limit, offset int) ([]model.LinkClaim, int64, error) {
+	query := r.db.Model(&model.LinkClaim{})
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.AnonymousID != "" {
+		query = query.Where("anonymous_id = ?", filter.AnonymousID)
+	}
+	if filter.ShortCode != "" {
+		query = query.Where("short_code = ?", filter.ShortCode)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var claims []model.LinkClaim
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&claims).Error
+	return claims, total, err
+}
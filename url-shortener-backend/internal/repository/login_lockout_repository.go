@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"url-shortener/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type LoginLockoutRepository interface {
+	Create(lockout *model.LoginLockout) error
+	List(scope string, limit, offset int) ([]model.LoginLockout, int64, error)
+}
+
+type loginLockoutRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginLockoutRepository(db *gorm.DB) LoginLockoutRepository {
+	return &loginLockoutRepository{db: db}
+}
+
+func (r *loginLockoutRepository) Create(lockout *model.LoginLockout) error {
+	return r.db.Create(lockout).Error
+}
+
+// List returns a page of lockouts, newest first, optionally of one scope
+func (r *loginLockoutRepository) List(scope string, limit, offset int) ([]model.LoginLockout, int64, error) {
+	query := r.db.Model(&model.LoginLockout{})
+	if scope != "" {
+		query = query.Where("scope = ?", scope)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var lockouts []model.LoginLockout
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&lockouts).Error
+	return lockouts, total, err
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"time"
+	"url-shortener/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository interface {
+	Create(token *model.RefreshToken) error
+	FindByJTI(jti string) (*model.RefreshToken, error)
+	Rotate(current *model.RefreshToken, next *model.RefreshToken) (bool, error)
+	RevokeFamily(familyID string) (int64, error)
+	RevokeAllForUser(userID uint) (int64, error)
+	PurgeExpired(before time.Time) (int64, error)
+}
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+func (r *refreshTokenRepository) Create(token *model.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *refreshTokenRepository) FindByJTI(jti string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	err := r.db.Where("jti = ?", jti).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Rotate revokes the current token and stores its replacement in one transaction.
+// It reports false without storing anything when the current token was already
+// revoked, e.g. by a concurrent refresh with the same token.
+func (r *refreshTokenRepository) Rotate(current *model.RefreshToken, next *model.RefreshToken) (bool, error) {
+	rotated := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Updates(map[string]interface{}{
+				"revoked_at":  time.Now(),
+				"replaced_by": next.JTI,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		rotated = true
+		return tx.Create(next).Error
+	})
+	return rotated, err
+}
+
+func (r *refreshTokenRepository) RevokeFamily(familyID string) (int64, error) {
+	result := r.db.Model(&model.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+func (r *refreshTokenRepository) RevokeAllForUser(userID uint) (int64, error) {
+	result := r.db.Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// PurgeExpired deletes tokens that expired before the given time; they can no
+// longer be presented, so they are not needed for reuse detection either
+func (r *refreshTokenRepository) PurgeExpired(before time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", before).Delete(&model.RefreshToken{})
+	return result.RowsAffected, result.Error
+}
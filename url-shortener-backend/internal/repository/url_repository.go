@@ -1,20 +1,77 @@
 package repository
 
 import (
+	"strings"
+	"time"
 	"url-shortener/internal/model"
 
 	"gorm.io/gorm"
 )
 
+// Sort keys for link listings
+const (
+	SortCreated = "created"
+	SortUpdated = "updated"
+	SortClicks  = "clicks"
+)
+
+// Link states that listings can be filtered on
+const (
+	StatusActive    = "active"
+	StatusDisabled  = "disabled"
+	StatusExpired   = "expired"
+	StatusProtected = "protected"
+	StatusDeleted   = "deleted"
+	StatusTakenDown = "taken_down"
+)
+
+// URLListFilter selects, orders and limits a page of links
+type URLListFilter struct {
+	UserID      *uint
+	AnonymousID *string
+	Domain      string // Matches the domain and its subdomains
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Status      string
+	Search      string // Case-insensitive substring of the short code or destination
+	SortBy      string
+	Ascending   bool
+	After       *URLCursor // Position of the last row of the previous page
+	Limit       int
+}
+
+// URLCursor is the sort key and id of a row, used for keyset pagination
+type URLCursor struct {
+	Time   time.Time // created_at or updated_at, depending on the sort
+	Clicks int64
+	ID     uint
+}
+
 type URLRepository interface {
 	Create(url *model.URL) error
+	CreateBatch(urls []*model.URL) error
 	FindByShortCode(code string) (*model.URL, error)
+	FindByShortCodeWithDeleted(code string) (*model.URL, error)
 	FindByOriginalURL(originalURL string) (*model.URL, error)
-	IncrementClicks(code string) error
-	List() ([]model.URL, error)
+	Update(url *model.URL) error
+	Delete(url *model.URL) error
+	Restore(url *model.URL) error
+	IncrementClicksBatch(counts map[string]int64) error
+	ConsumeClick(code string, now time.Time) (bool, error)
+	MarkExpired(now time.Time) ([]string, error)
+	PurgeExpired(before time.Time) ([]string, error)
 	ListByUserID(userID uint) ([]model.URL, error)
+	ListByUserIDWithDeleted(userID uint) ([]model.URL, error)
+	EachByUserID(userID uint, batchSize int, fn func([]model.URL) error) error
+	DeleteByUserID(userID uint) ([]string, error)
+	OrphanByUserID(userID uint) ([]string, error)
 	ListByAnonymousID(anonymousID string) ([]model.URL, error)
-	ClaimAnonymousURLs(userID uint, anonymousID string) error
+	CountByAnonymousIDSince(anonymousID string, since time.Time) (int64, error)
+	CountByCreatorIPSince(ipHash string, since time.Time) (int64, error)
+	PurgeExpiredAnonymous(before time.Time) ([]string, error)
+	ListPage(filter URLListFilter) ([]model.URL, error)
+	Count(filter URLListFilter) (int64, error)
+	ClaimAnonymousURLs(userID uint, anonymousID string, codes []string, clientIP string) ([]model.URL, error)
 }
 
 type urlRepository struct {
@@ -29,6 +86,13 @@ func (r *urlRepository) Create(url *model.URL) error {
 	return r.db.Create(url).Error
 }
 
+// CreateBatch inserts all links in one transaction; none are kept if one fails
+func (r *urlRepository) CreateBatch(urls []*model.URL) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(urls).Error
+	})
+}
+
 func (r *urlRepository) FindByShortCode(code string) (*model.URL, error) {
 	var url model.URL
 	err := r.db.Where("short_code = ?", code).First(&url).Error
@@ -38,6 +102,16 @@ func (r *urlRepository) FindByShortCode(code string) (*model.URL, error) {
 	return &url, nil
 }
 
+// FindByShortCodeWithDeleted also returns soft-deleted rows
+func (r *urlRepository) FindByShortCodeWithDeleted(code string) (*model.URL, error) {
+	var url model.URL
+	err := r.db.Unscoped().Where("short_code = ?", code).First(&url).Error
+	if err != nil {
+		return nil, err
+	}
+	return &url, nil
+}
+
 func (r *urlRepository) FindByOriginalURL(originalURL string) (*model.URL, error) {
 	var url model.URL
 	err := r.db.Where("original_url = ?", originalURL).First(&url).Error
@@ -47,16 +121,84 @@ func (r *urlRepository) FindByOriginalURL(originalURL string) (*model.URL, error
 	return &url, nil
 }
 
-func (r *urlRepository) IncrementClicks(code string) error {
-	return r.db.Model(&model.URL{}).
-		Where("short_code = ?", code).
-		UpdateColumn("clicks", gorm.Expr("clicks + ?", 1)).Error
+func (r *urlRepository) Update(url *model.URL) error {
+	return r.db.Save(url).Error
 }
 
-func (r *urlRepository) List() ([]model.URL, error) {
-	var urls []model.URL
-	err := r.db.Order("created_at DESC").Find(&urls).Error
-	return urls, err
+func (r *urlRepository) Delete(url *model.URL) error {
+	return r.db.Delete(url).Error
+}
+
+func (r *urlRepository) Restore(url *model.URL) error {
+	if err := r.db.Unscoped().Model(url).Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	url.DeletedAt = gorm.DeletedAt{}
+	return nil
+}
+
+// IncrementClicksBatch applies aggregated click counts in a single transaction
+func (r *urlRepository) IncrementClicksBatch(counts map[string]int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for code, n := range counts {
+			err := tx.Model(&model.URL{}).
+				Where("short_code = ?", code).
+				UpdateColumn("clicks", gorm.Expr("clicks + ?", n)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ConsumeClick increments the click count only while the link is within its
+// expiry date and click budget. It reports whether the click was accepted.
+func (r *urlRepository) ConsumeClick(code string, now time.Time) (bool, error) {
+	result := r.db.Model(&model.URL{}).
+		Where("short_code = ? AND expired_at IS NULL", code).
+		Where("(expires_at IS NULL OR expires_at > ?)", now).
+		Where("(max_clicks IS NULL OR clicks < max_clicks)").
+		UpdateColumn("clicks", gorm.Expr("clicks + ?", 1))
+	return result.RowsAffected == 1, result.Error
+}
+
+// MarkExpired stamps expired_at on links that ran past their expiry date or
+// click budget, and returns the short codes it marked
+func (r *urlRepository) MarkExpired(now time.Time) ([]string, error) {
+	var codes []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.URL{}).
+			Where("expired_at IS NULL").
+			Where("((expires_at IS NOT NULL AND expires_at <= ?) OR (max_clicks IS NOT NULL AND clicks >= max_clicks))", now).
+			Pluck("short_code", &codes).Error
+		if err != nil || len(codes) == 0 {
+			return err
+		}
+		return tx.Model(&model.URL{}).
+			Where("short_code IN ?", codes).
+			UpdateColumn("expired_at", now).Error
+	})
+	return codes, err
+}
+
+// PurgeExpired permanently removes links that expired before the given time,
+// together with their click events. It returns the removed codes.
+func (r *urlRepository) PurgeExpired(before time.Time) ([]string, error) {
+	var codes []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Model(&model.URL{}).
+			Where("expired_at IS NOT NULL AND expired_at < ?", before).
+			Pluck("short_code", &codes).Error
+		if err != nil || len(codes) == 0 {
+			return err
+		}
+		if err := tx.Where("short_code IN ?", codes).Delete(&model.ClickEvent{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("short_code IN ?", codes).Delete(&model.URL{}).Error
+	})
+	return codes, err
 }
 
 func (r *urlRepository) ListByUserID(userID uint) ([]model.URL, error) {
@@ -65,16 +207,252 @@ func (r *urlRepository) ListByUserID(userID uint) ([]model.URL, error) {
 	return urls, err
 }
 
+// ListByUserIDWithDeleted also returns the user's soft-deleted links
+func (r *urlRepository) ListByUserIDWithDeleted(userID uint) ([]model.URL, error) {
+	var urls []model.URL
+	err := r.db.Unscoped().Where("user_id = ?", userID).Order("created_at DESC").Find(&urls).Error
+	return urls, err
+}
+
+// EachByUserID passes the user's links to fn batchSize at a time, oldest
+// first, so large accounts can be streamed without loading every link
+func (r *urlRepository) EachByUserID(userID uint, batchSize int, fn func([]model.URL) error) error {
+	var batch []model.URL
+	return r.db.Where("user_id = ?", userID).Order("id").
+		FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+}
+
+// DeleteByUserID permanently removes every link of the user, including
+// soft-deleted ones, and their click events. It returns the removed codes.
+func (r *urlRepository) DeleteByUserID(userID uint) ([]string, error) {
+	var codes []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&model.URL{}).Where("user_id = ?", userID).Pluck("short_code", &codes).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		if err := tx.Where("short_code IN ?", codes).Delete(&model.ClickEvent{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("user_id = ?", userID).Delete(&model.URL{}).Error
+	})
+	return codes, err
+}
+
+// OrphanByUserID detaches every link from the user. The links keep working
+// but no longer have an owner. It returns the detached codes.
+func (r *urlRepository) OrphanByUserID(userID uint) ([]string, error) {
+	var codes []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&model.URL{}).Where("user_id = ?", userID).Pluck("short_code", &codes).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&model.URL{}).Where("user_id = ?", userID).UpdateColumn("user_id", nil).Error
+	})
+	return codes, err
+}
+
 func (r *urlRepository) ListByAnonymousID(anonymousID string) ([]model.URL, error) {
 	var urls []model.URL
 	err := r.db.Where("anonymous_id = ?", anonymousID).Order("created_at DESC").Find(&urls).Error
 	return urls, err
 }
 
-func (r *urlRepository) ClaimAnonymousURLs(userID uint, anonymousID string) error {
-	return r.db.Model(&model.URL{}).
-		Where("anonymous_id = ? AND user_id IS NULL", anonymousID).
-		Update("user_id", userID).
-		Update("anonymous_id", nil).
-		Error
+// CountByAnonymousIDSince counts the links an anonymous identity created
+// since the given time, including ones deleted or claimed since. Links
+// created before creator_anon_id was added only carry anonymous_id.
+func (r *urlRepository) CountByAnonymousIDSince(anonymousID string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&model.URL{}).
+		Where("(creator_anon_id = ? OR anonymous_id = ?) AND created_at >= ?", anonymousID, anonymousID, since).
+		Count(&count).Error
+	return count, err
+}
+
+// CountByCreatorIPSince counts the anonymous links created from a client IP
+// since the given time, whether or not they were claimed since
+func (r *urlRepository) CountByCreatorIPSince(ipHash string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&model.URL{}).
+		Where("creator_ip_hash = ? AND created_at >= ?", ipHash, since).
+		Count(&count).Error
+	return count, err
+}
+
+// PurgeExpiredAnonymous permanently removes unclaimed anonymous links that
+// expired before the given time, and their click events. It returns the
+// removed codes.
+func (r *urlRepository) PurgeExpiredAnonymous(before time.Time) ([]string, error) {
+	var codes []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Model(&model.URL{}).
+			Where("user_id IS NULL AND anonymous_id IS NOT NULL").
+			Where("(expires_at < ? OR expired_at < ?)", before, before).
+			Pluck("short_code", &codes).Error
+		if err != nil || len(codes) == 0 {
+			return err
+		}
+		if err := tx.Where("short_code IN ?", codes).Delete(&model.ClickEvent{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("short_code IN ?", codes).Delete(&model.URL{}).Error
+	})
+	return codes, err
+}
+
+// ListPage returns up to filter.Limit links after the cursor, using keyset pagination
+func (r *urlRepository) ListPage(filter URLListFilter) ([]model.URL, error) {
+	column := sortColumn(filter.SortBy)
+	direction, op := "DESC", "<"
+	if filter.Ascending {
+		direction, op = "ASC", ">"
+	}
+
+	query := r.filtered(filter)
+	if filter.After != nil {
+		var value interface{} = filter.After.Time
+		if column == "clicks" {
+			value = filter.After.Clicks
+		}
+		query = query.Where("("+column+" "+op+" ? OR ("+column+" = ? AND id "+op+" ?))", value, value, filter.After.ID)
+	}
+
+	var urls []model.URL
+	err := query.
+		Order(column + " " + direction).
+		Order("id " + direction).
+		Limit(filter.Limit).
+		Find(&urls).Error
+	return urls, err
+}
+
+// Count returns how many links match the filter, ignoring cursor and limit
+func (r *urlRepository) Count(filter URLListFilter) (int64, error) {
+	var count int64
+	err := r.filtered(filter).Count(&count).Error
+	return count, err
+}
+
+func (r *urlRepository) filtered(filter URLListFilter) *gorm.DB {
+	query := r.db.Model(&model.URL{})
+	if filter.Status == StatusDeleted {
+		query = r.db.Unscoped().Model(&model.URL{}).Where("deleted_at IS NOT NULL")
+	}
+
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.AnonymousID != nil {
+		query = query.Where("anonymous_id = ?", *filter.AnonymousID)
+	}
+	if filter.Domain != "" {
+		domain := strings.ToLower(filter.Domain)
+		query = query.Where(`(domain = ? OR domain LIKE ? ESCAPE '\')`, domain, "%."+escapeLike(domain))
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at < ?", *filter.CreatedTo)
+	}
+	if filter.Search != "" {
+		pattern := "%" + escapeLike(strings.ToLower(filter.Search)) + "%"
+		query = query.Where(`(LOWER(short_code) LIKE ? ESCAPE '\' OR LOWER(original_url) LIKE ? ESCAPE '\')`, pattern, pattern)
+	}
+
+	now := time.Now()
+	switch filter.Status {
+	case StatusActive:
+		query = query.Where("disabled = ? AND expired_at IS NULL AND taken_down_at IS NULL", false).
+			Where("(expires_at IS NULL OR expires_at > ?)", now).
+			Where("(max_clicks IS NULL OR clicks < max_clicks)")
+	case StatusDisabled:
+		query = query.Where("disabled = ?", true)
+	case StatusExpired:
+		query = query.Where("(expired_at IS NOT NULL OR expires_at <= ? OR (max_clicks IS NOT NULL AND clicks >= max_clicks))", now)
+	case StatusProtected:
+		query = query.Where("protected = ?", true)
+	case StatusTakenDown:
+		query = query.Where("taken_down_at IS NOT NULL")
+	}
+
+	return query
+}
+
+func sortColumn(sortBy string) string {
+	switch sortBy {
+	case SortUpdated:
+		return "updated_at"
+	case SortClicks:
+		return "clicks"
+	default:
+		return "created_at"
+	}
+}
+
+// escapeLike escapes the LIKE wildcards in user input
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// ClaimAnonymousURLs gives the anonymous identity's unclaimed links to the
+// user, either all of them or only the given codes, and records each claim.
+// Links only expiring because of the anonymous link TTL stop expiring. Each
+// update re-checks the owner, so a link claimed concurrently is skipped, and
+// claiming the same links again changes nothing. creator_anon_id is left as
+// it is, so claimed links still count against the creation quota. It returns
+// the claimed links.
+func (r *urlRepository) ClaimAnonymousURLs(userID uint, anonymousID string, codes []string, clientIP string) ([]model.URL, error) {
+	claimed := []model.URL{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("anonymous_id = ? AND user_id IS NULL", anonymousID)
+		if len(codes) > 0 {
+			query = query.Where("short_code IN ?", codes)
+		}
+		var candidates []model.URL
+		if err := query.Order("created_at DESC, id DESC").Find(&candidates).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, url := range candidates {
+			updates := map[string]interface{}{"user_id": userID, "anonymous_id": nil, "updated_at": now}
+			if url.AnonymousTTL {
+				updates["expires_at"] = nil
+				updates["expired_at"] = nil
+				updates["anonymous_ttl"] = false
+			}
+			result := tx.Model(&model.URL{}).
+				Where("id = ? AND anonymous_id = ? AND user_id IS NULL", url.ID, anonymousID).
+				UpdateColumns(updates)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+
+			claim := &model.LinkClaim{UserID: userID, AnonymousID: anonymousID, ShortCode: url.ShortCode, ClientIP: clientIP}
+			if err := tx.Create(claim).Error; err != nil {
+				return err
+			}
+
+			url.UserID = &userID
+			url.AnonymousID = nil
+			url.UpdatedAt = now
+			if url.AnonymousTTL {
+				url.ExpiresAt, url.ExpiredAt, url.AnonymousTTL = nil, nil, false
+			}
+			claimed = append(claimed, url)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
 }
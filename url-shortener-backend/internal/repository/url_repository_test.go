@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"testing"
+	"url-shortener/internal/model"
+
+	"gorm.io/gorm"
+)
+
+func TestURLRepositoryCreateBatchRollsBack(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.Create(&model.URL{ShortCode: "taken", OriginalURL: "https://example.com/taken"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	// One INSERT per link, so the first ones are written before the last fails
+	repo := NewURLRepository(db.Session(&gorm.Session{CreateBatchSize: 1}))
+
+	err := repo.CreateBatch([]*model.URL{
+		{ShortCode: "first", OriginalURL: "https://example.com/1"},
+		{ShortCode: "second", OriginalURL: "https://example.com/2"},
+		{ShortCode: "taken", OriginalURL: "https://example.com/3"},
+	})
+	if err == nil {
+		t.Fatal("batch with a taken short code was saved")
+	}
+
+	var codes []string
+	db.Unscoped().Model(&model.URL{}).Order("id").Pluck("short_code", &codes)
+	if len(codes) != 1 || codes[0] != "taken" {
+		t.Errorf("short codes after the failed batch: %v, want [taken]", codes)
+	}
+
+	// The next batch is saved whole
+	if err := repo.CreateBatch([]*model.URL{
+		{ShortCode: "first", OriginalURL: "https://example.com/1"},
+		{ShortCode: "second", OriginalURL: "https://example.com/2"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var n int64
+	db.Model(&model.URL{}).Count(&n)
+	if n != 3 {
+		t.Errorf("%d links, want 3", n)
+	}
+}
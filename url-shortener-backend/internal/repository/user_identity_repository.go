@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"time"
+	"url-shortener/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type UserIdentityRepository interface {
+	Create(identity *model.UserIdentity) error
+	FindBySubject(provider, subject string) (*model.UserIdentity, error)
+	TouchLastLogin(id uint, now time.Time) error
+}
+
+type userIdentityRepository struct {
+	db *gorm.DB
+}
+
+func NewUserIdentityRepository(db *gorm.DB) UserIdentityRepository {
+	return &userIdentityRepository{db: db}
+}
+
+func (r *userIdentityRepository) Create(identity *model.UserIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+func (r *userIdentityRepository) FindBySubject(provider, subject string) (*model.UserIdentity, error) {
+	var identity model.UserIdentity
+	err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *userIdentityRepository) TouchLastLogin(id uint, now time.Time) error {
+	return r.db.Model(&model.UserIdentity{}).Where("id = ?", id).UpdateColumn("last_login_at", now).Error
+}
@@ -1,16 +1,30 @@
 package repository
 
 import (
+	"strings"
 	"url-shortener/internal/model"
 
 	"gorm.io/gorm"
 )
 
+// UserListFilter selects a page of users for the admin API
+type UserListFilter struct {
+	Search   string // Case-insensitive substring of username or email
+	Role     string
+	Disabled *bool
+	Limit    int
+	Offset   int
+}
+
 type UserRepository interface {
 	Create(user *model.User) error
+	Update(userID uint, fields map[string]interface{}) error
+	Delete(userID uint) error
 	FindByUsername(username string) (*model.User, error)
 	FindByEmail(email string) (*model.User, error)
 	FindByID(id uint) (*model.User, error)
+	List(filter UserListFilter) ([]model.User, int64, error)
+	AdvanceTOTPStep(userID uint, step int64) (bool, error)
 }
 
 type userRepository struct {
@@ -25,6 +39,26 @@ func (r *userRepository) Create(user *model.User) error {
 	return r.db.Create(user).Error
 }
 
+// Update writes only the given columns, so it cannot undo a concurrent change
+// to another column of the same user, such as an admin disabling the account
+func (r *userRepository) Update(userID uint, fields map[string]interface{}) error {
+	return r.db.Model(&model.User{}).Where("id = ?", userID).Updates(fields).Error
+}
+
+// Delete removes the user together with their sessions, API keys, emailed
+// tokens, recovery codes and linked identities. Links are handled separately
+// by the caller.
+func (r *userRepository) Delete(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, owned := range []interface{}{&model.RefreshToken{}, &model.APIKey{}, &model.UserToken{}, &model.RecoveryCode{}, &model.UserIdentity{}, &model.LinkClaim{}, &model.ImportJob{}} {
+			if err := tx.Where("user_id = ?", userID).Delete(owned).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&model.User{}, userID).Error
+	})
+}
+
 func (r *userRepository) FindByUsername(username string) (*model.User, error) {
 	var user model.User
 	err := r.db.Where("username = ?", username).First(&user).Error
@@ -51,3 +85,36 @@ func (r *userRepository) FindByID(id uint) (*model.User, error) {
 	}
 	return &user, nil
 }
+
+// List returns a page of users, newest first, and the number of users matching the filter
+func (r *userRepository) List(filter UserListFilter) ([]model.User, int64, error) {
+	query := r.db.Model(&model.User{})
+	if filter.Search != "" {
+		pattern := "%" + escapeLike(strings.ToLower(filter.Search)) + "%"
+		query = query.Where(`(LOWER(username) LIKE ? ESCAPE '\' OR LOWER(email) LIKE ? ESCAPE '\')`, pattern, pattern)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.Disabled != nil {
+		query = query.Where("disabled = ?", *filter.Disabled)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []model.User
+	err := query.Order("id DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&users).Error
+	return users, total, err
+}
+
+// AdvanceTOTPStep records the time step of an accepted TOTP code. It reports
+// false if that step or a later one was already used, which blocks replays.
+func (r *userRepository) AdvanceTOTPStep(userID uint, step int64) (bool, error) {
+	result := r.db.Model(&model.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		UpdateColumn("totp_last_step", step)
+	return result.RowsAffected > 0, result.Error
+}
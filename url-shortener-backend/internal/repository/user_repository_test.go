@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"testing"
+	"url-shortener/internal/model"
+)
+
+func TestUserRepositoryUpdateKeepsOtherColumns(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&model.User{}); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewUserRepository(db)
+
+	user := &model.User{Username: "alice", Email: "alice@example.com", Password: "old-hash", Role: model.RoleUser}
+	if err := repo.Create(user); err != nil {
+		t.Fatal(err)
+	}
+
+	// An admin disables the account while the user's password change is in flight
+	if err := repo.Update(user.ID, map[string]interface{}{"disabled": true}); err != nil {
+		t.Fatal(err)
+	}
+	user.Password = "new-hash"
+	if err := repo.Update(user.ID, map[string]interface{}{"password": user.Password}); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := repo.FindByID(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.Disabled {
+		t.Error("the password update re-enabled the account")
+	}
+	if stored.Password != "new-hash" {
+		t.Errorf("Password = %q, want the new hash", stored.Password)
+	}
+}
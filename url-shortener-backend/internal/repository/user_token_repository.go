@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"time"
+	"url-shortener/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type UserTokenRepository interface {
+	Replace(token *model.UserToken) error
+	FindByHash(purpose, tokenHash string) (*model.UserToken, error)
+	Consume(token *model.UserToken, now time.Time) (bool, error)
+}
+
+type userTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewUserTokenRepository(db *gorm.DB) UserTokenRepository {
+	return &userTokenRepository{db: db}
+}
+
+// Replace stores a new token and deletes the user's earlier tokens for the
+// same purpose, so only the most recently emailed link works
+func (r *userTokenRepository) Replace(token *model.UserToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND purpose = ?", token.UserID, token.Purpose).
+			Delete(&model.UserToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+func (r *userTokenRepository) FindByHash(purpose, tokenHash string) (*model.UserToken, error) {
+	var token model.UserToken
+	err := r.db.Where("purpose = ? AND token_hash = ?", purpose, tokenHash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Consume marks an unused, unexpired token as used. It reports false when the
+// token was already used or has expired, so each token works exactly once.
+func (r *userTokenRepository) Consume(token *model.UserToken, now time.Time) (bool, error) {
+	result := r.db.Model(&model.UserToken{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", token.ID, now).
+		Update("used_at", now)
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+	"url-shortener/config"
+	"url-shortener/internal/mailer"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// MinPasswordLength matches the registration requirement
+const MinPasswordLength = 6
+
+var (
+	ErrInvalidUserToken     = errors.New("invalid or expired link")
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+	ErrEmailNotVerified     = errors.New("verify your email address first")
+	ErrWeakPassword         = fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+)
+
+// AccountService handles account recovery and email verification
+type AccountService interface {
+	RequestPasswordReset(email string) error
+	ResetPassword(token, newPassword string) error
+	SendVerificationEmail(userID uint) error
+	VerifyEmail(token string) error
+}
+
+type accountService struct {
+	userRepo  repository.UserRepository
+	tokenRepo repository.UserTokenRepository
+	sessions  TokenService
+	mail      mailer.Mailer
+	cfg       config.AccountConfig
+}
+
+func NewAccountService(userRepo repository.UserRepository, tokenRepo repository.UserTokenRepository, sessions TokenService, mail mailer.Mailer, cfg config.AccountConfig) AccountService {
+	return &accountService{
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		sessions:  sessions,
+		mail:      mail,
+		cfg:       cfg,
+	}
+}
+
+// RequestPasswordReset emails a reset link if an active account uses the
+// address. It succeeds either way so callers cannot probe for accounts.
+func (s *accountService) RequestPasswordReset(email string) error {
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.Disabled {
+		return nil
+	}
+
+	token, err := s.issueToken(user, model.TokenPurposePasswordReset, s.cfg.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	s.deliver(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Someone asked to reset the password of your URL Shortener account.\n"+
+			"Choose a new password here:\n\n%s\n\n"+
+			"The link works once and expires in %s. If you did not ask for this, ignore this email.\n",
+			user.Username, s.appLink("/reset-password", token), humanDuration(s.cfg.PasswordResetTTL)),
+	})
+	return nil
+}
+
+// ResetPassword sets a new password with a reset token and signs the user out everywhere
+func (s *accountService) ResetPassword(token, newPassword string) error {
+	if len(newPassword) < MinPasswordLength {
+		return ErrWeakPassword
+	}
+
+	record, user, err := s.consumeToken(model.TokenPurposePasswordReset, token)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+	user.Password = string(hashedPassword)
+
+	// Following the emailed link proves the user controls the address
+	if user.EmailVerifiedAt == nil && record.Email == user.Email {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	err = s.userRepo.Update(user.ID, map[string]interface{}{
+		"password":          user.Password,
+		"email_verified_at": user.EmailVerifiedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.sessions.LogoutAll(user.ID); err != nil {
+		log.Printf("Failed to revoke sessions of user %d after password reset: %v", user.ID, err)
+	}
+	return nil
+}
+
+// SendVerificationEmail emails a verification link to the user's current address
+func (s *accountService) SendVerificationEmail(userID uint) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	token, err := s.issueToken(user, model.TokenPurposeEmailVerification, s.cfg.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	s.deliver(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Please confirm that this is your email address:\n\n%s\n\n"+
+			"The link expires in %s.\n",
+			user.Username, s.appLink("/verify-email", token), humanDuration(s.cfg.EmailVerificationTTL)),
+	})
+	return nil
+}
+
+// VerifyEmail marks the address the token was sent to as verified
+func (s *accountService) VerifyEmail(token string) error {
+	record, user, err := s.consumeToken(model.TokenPurposeEmailVerification, token)
+	if err != nil {
+		return err
+	}
+
+	// The user changed their address after the link was sent
+	if record.Email != user.Email {
+		return ErrInvalidUserToken
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	return s.userRepo.Update(user.ID, map[string]interface{}{"email_verified_at": user.EmailVerifiedAt})
+}
+
+// issueToken stores a new single-use token for the user, invalidating earlier ones
+func (s *accountService) issueToken(user *model.User, purpose string, ttl time.Duration) (string, error) {
+	token, err := gonanoid.New(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.tokenRepo.Replace(&model.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeToken redeems a token and loads its user
+func (s *accountService) consumeToken(purpose, token string) (*model.UserToken, *model.User, error) {
+	record, err := s.tokenRepo.FindByHash(purpose, hashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidUserToken
+		}
+		return nil, nil, err
+	}
+
+	consumed, err := s.tokenRepo.Consume(record, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	if !consumed {
+		return nil, nil, ErrInvalidUserToken
+	}
+
+	user, err := s.userRepo.FindByID(record.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidUserToken
+		}
+		return nil, nil, err
+	}
+	if user.Disabled {
+		return nil, nil, ErrAccountDisabled
+	}
+	return record, user, nil
+}
+
+func (s *accountService) appLink(path, token string) string {
+	return s.cfg.AppURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// humanDuration formats a link lifetime for emails, e.g. "48 hours" or "30 minutes"
+func humanDuration(d time.Duration) string {
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		if d == time.Hour {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", d/time.Hour)
+	case d >= time.Minute && d%time.Minute == 0:
+		return fmt.Sprintf("%d minutes", d/time.Minute)
+	default:
+		return d.String()
+	}
+}
+
+// deliver sends mail in the background so response times do not reveal
+// whether an account exists, and a slow mail server does not block requests
+func (s *accountService) deliver(msg mailer.Message) {
+	go func() {
+		if err := s.mail.Send(msg); err != nil {
+			log.Printf("Failed to send %q email: %v", msg.Subject, err)
+		}
+	}()
+}
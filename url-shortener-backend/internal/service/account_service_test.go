@@ -0,0 +1,261 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/config"
+	"url-shortener/internal/mailer"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// fakeMailer passes sent messages to the test; mail is sent in the background
+type fakeMailer struct {
+	sent chan mailer.Message
+}
+
+func (m *fakeMailer) Send(msg mailer.Message) error {
+	m.sent <- msg
+	return nil
+}
+
+// linkToken waits for the next email and returns the token of its link
+func (m *fakeMailer) linkToken(t *testing.T) string {
+	t.Helper()
+	select {
+	case msg := <-m.sent:
+		for _, line := range strings.Split(msg.Body, "\n") {
+			if link, err := url.Parse(line); err == nil && link.Query().Get("token") != "" {
+				return link.Query().Get("token")
+			}
+		}
+		t.Fatalf("email %q has no link", msg.Subject)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no email was sent")
+	}
+	return ""
+}
+
+// noMail fails the test when an email is sent within a short while
+func (m *fakeMailer) noMail(t *testing.T) {
+	t.Helper()
+	select {
+	case msg := <-m.sent:
+		t.Errorf("unexpected email %q to %s", msg.Subject, msg.To)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+type accountTest struct {
+	accounts AccountService
+	sessions TokenService
+	mail     *fakeMailer
+	db       *gorm.DB
+	user     *model.User
+}
+
+func newAccountTest(t *testing.T) *accountTest {
+	t.Helper()
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&model.User{}, &model.UserToken{}, &model.RefreshToken{}); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &model.User{Username: "alice", Email: "alice@example.com", Password: string(hash)}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	users := repository.NewUserRepository(db)
+	sessions := NewTokenService(repository.NewRefreshTokenRepository(db), users, fakeIssuer{})
+	mail := &fakeMailer{sent: make(chan mailer.Message, 10)}
+	cfg := config.AccountConfig{
+		AppURL:               "https://app.example.com",
+		PasswordResetTTL:     time.Hour,
+		EmailVerificationTTL: 48 * time.Hour,
+	}
+	accounts := NewAccountService(users, repository.NewUserTokenRepository(db), sessions, mail, cfg)
+	return &accountTest{accounts: accounts, sessions: sessions, mail: mail, db: db, user: user}
+}
+
+// reload reads the user back from the database
+func (a *accountTest) reload(t *testing.T) *model.User {
+	t.Helper()
+	var user model.User
+	if err := a.db.First(&user, a.user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &user
+}
+
+func TestPasswordReset(t *testing.T) {
+	a := newAccountTest(t)
+	session, err := a.sessions.IssueTokens(a.user, "laptop", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.accounts.RequestPasswordReset(a.user.Email); err != nil {
+		t.Fatal(err)
+	}
+	token := a.mail.linkToken(t)
+
+	// Only a hash of the token is stored
+	var stored model.UserToken
+	a.db.First(&stored)
+	if stored.TokenHash == token || stored.TokenHash != hashToken(token) {
+		t.Errorf("stored token hash %q", stored.TokenHash)
+	}
+
+	if err := a.accounts.ResetPassword(token, "short"); !errors.Is(err, ErrWeakPassword) {
+		t.Errorf("short password: err = %v, want ErrWeakPassword", err)
+	}
+	if err := a.accounts.ResetPassword(token, "new-password"); err != nil {
+		t.Fatal(err)
+	}
+
+	user := a.reload(t)
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("new-password")) != nil {
+		t.Error("password was not changed")
+	}
+	// Following the emailed link verifies the address
+	if user.EmailVerifiedAt == nil {
+		t.Error("email is not verified after a reset")
+	}
+	// Every session ends
+	if _, err := a.sessions.Refresh(session.RefreshToken); err == nil {
+		t.Error("session survived the password reset")
+	}
+
+	// The link works once
+	if err := a.accounts.ResetPassword(token, "third-password"); !errors.Is(err, ErrInvalidUserToken) {
+		t.Errorf("reused token: err = %v, want ErrInvalidUserToken", err)
+	}
+}
+
+func TestPasswordResetTokens(t *testing.T) {
+	a := newAccountTest(t)
+
+	// A new request replaces the earlier link
+	a.accounts.RequestPasswordReset(a.user.Email)
+	first := a.mail.linkToken(t)
+	a.accounts.RequestPasswordReset(a.user.Email)
+	second := a.mail.linkToken(t)
+	if err := a.accounts.ResetPassword(first, "new-password"); !errors.Is(err, ErrInvalidUserToken) {
+		t.Errorf("replaced token: err = %v, want ErrInvalidUserToken", err)
+	}
+
+	// Expired links are refused
+	a.db.Model(&model.UserToken{}).Where("token_hash = ?", hashToken(second)).Update("expires_at", time.Now().Add(-time.Minute))
+	if err := a.accounts.ResetPassword(second, "new-password"); !errors.Is(err, ErrInvalidUserToken) {
+		t.Errorf("expired token: err = %v, want ErrInvalidUserToken", err)
+	}
+
+	// Unknown tokens, and verification tokens used as reset tokens
+	if err := a.accounts.ResetPassword("made-up", "new-password"); !errors.Is(err, ErrInvalidUserToken) {
+		t.Errorf("unknown token: err = %v, want ErrInvalidUserToken", err)
+	}
+	if err := a.accounts.SendVerificationEmail(a.user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.accounts.ResetPassword(a.mail.linkToken(t), "new-password"); !errors.Is(err, ErrInvalidUserToken) {
+		t.Errorf("verification token: err = %v, want ErrInvalidUserToken", err)
+	}
+
+	// Disabled accounts get no link, and cannot use one sent before
+	a.accounts.RequestPasswordReset(a.user.Email)
+	token := a.mail.linkToken(t)
+	a.db.Model(a.user).Update("disabled", true)
+	if err := a.accounts.ResetPassword(token, "new-password"); !errors.Is(err, ErrAccountDisabled) {
+		t.Errorf("disabled account: err = %v, want ErrAccountDisabled", err)
+	}
+	if err := a.accounts.RequestPasswordReset(a.user.Email); err != nil {
+		t.Fatal(err)
+	}
+	a.mail.noMail(t)
+
+	// Unknown addresses succeed without an email
+	if err := a.accounts.RequestPasswordReset("nobody@example.com"); err != nil {
+		t.Errorf("unknown address: %v", err)
+	}
+	a.mail.noMail(t)
+
+	if user := a.reload(t); bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("old-password")) != nil {
+		t.Error("a refused reset changed the password")
+	}
+}
+
+func TestEmailVerification(t *testing.T) {
+	a := newAccountTest(t)
+
+	if err := a.accounts.SendVerificationEmail(a.user.ID); err != nil {
+		t.Fatal(err)
+	}
+	token := a.mail.linkToken(t)
+	if err := a.accounts.VerifyEmail(token); err != nil {
+		t.Fatal(err)
+	}
+	if a.reload(t).EmailVerifiedAt == nil {
+		t.Fatal("email is not verified")
+	}
+
+	// Used links and verified addresses
+	if err := a.accounts.VerifyEmail(token); !errors.Is(err, ErrInvalidUserToken) {
+		t.Errorf("reused token: err = %v, want ErrInvalidUserToken", err)
+	}
+	if err := a.accounts.SendVerificationEmail(a.user.ID); !errors.Is(err, ErrEmailAlreadyVerified) {
+		t.Errorf("verified address: err = %v, want ErrEmailAlreadyVerified", err)
+	}
+	if err := a.accounts.SendVerificationEmail(99); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("unknown user: err = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestEmailVerificationAfterAddressChange(t *testing.T) {
+	a := newAccountTest(t)
+
+	if err := a.accounts.SendVerificationEmail(a.user.ID); err != nil {
+		t.Fatal(err)
+	}
+	token := a.mail.linkToken(t)
+
+	// The link verifies the address it was sent to, not the new one
+	a.db.Model(a.user).Update("email", "alice@other.example.com")
+	if err := a.accounts.VerifyEmail(token); !errors.Is(err, ErrInvalidUserToken) {
+		t.Errorf("link to the old address: err = %v, want ErrInvalidUserToken", err)
+	}
+	if a.reload(t).EmailVerifiedAt != nil {
+		t.Error("new address was verified by a link sent to the old one")
+	}
+
+	// Expired links are refused
+	if err := a.accounts.SendVerificationEmail(a.user.ID); err != nil {
+		t.Fatal(err)
+	}
+	token = a.mail.linkToken(t)
+	a.db.Model(&model.UserToken{}).Where("token_hash = ?", hashToken(token)).Update("expires_at", time.Now().Add(-time.Minute))
+	if err := a.accounts.VerifyEmail(token); !errors.Is(err, ErrInvalidUserToken) {
+		t.Errorf("expired token: err = %v, want ErrInvalidUserToken", err)
+	}
+
+	// A reset link sent to the old address changes the password but does not verify the new one
+	a.db.Model(a.user).Update("email", "alice@example.com")
+	a.accounts.RequestPasswordReset("alice@example.com")
+	token = a.mail.linkToken(t)
+	a.db.Model(a.user).Update("email", "alice@third.example.com")
+	if err := a.accounts.ResetPassword(token, "new-password"); err != nil {
+		t.Fatal(err)
+	}
+	if a.reload(t).EmailVerifiedAt != nil {
+		t.Error("reset link verified an address it was not sent to")
+	}
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"url-shortener/config"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidStatsQuery = errors.New("invalid analytics query")
+
+// ClickInput holds the request metadata captured for one redirect
+type ClickInput struct {
+	ShortCode      string
+	Referrer       string
+	UserAgent      string
+	ClientIP       string
+	AcceptLanguage string
+}
+
+// ClickStatsQuery selects the time range and bucket size of a stats report
+type ClickStatsQuery struct {
+	From     time.Time
+	To       time.Time
+	Interval string // hour, day or week
+	Limit    int    // Max entries per breakdown
+}
+
+// ClickStats is the analytics report of a short link
+type ClickStats struct {
+	ShortCode string                   `json:"short_code" example:"abc12345"`
+	From      time.Time                `json:"from" example:"2025-11-18T00:00:00Z"`
+	To        time.Time                `json:"to" example:"2025-12-18T00:00:00Z"`
+	Interval  string                   `json:"interval" example:"day"`
+	Total     int64                    `json:"total" example:"42"`
+	Timeline  []repository.BucketCount `json:"timeline"`
+	Referrers []repository.ValueCount  `json:"referrers"`
+	Browsers  []repository.ValueCount  `json:"browsers"`
+	OS        []repository.ValueCount  `json:"os"`
+	Devices   []repository.ValueCount  `json:"devices"`
+}
+
+type AnalyticsService interface {
+	RecordClick(input ClickInput)
+	GetClickStats(code string, userID uint, query ClickStatsQuery) (*ClickStats, error)
+}
+
+type analyticsService struct {
+	repo    repository.AnalyticsRepository
+	urlRepo repository.URLRepository
+	cfg     config.AnalyticsConfig
+	events  ClickRecorder
+}
+
+func NewAnalyticsService(repo repository.AnalyticsRepository, urlRepo repository.URLRepository, cfg config.AnalyticsConfig, events ClickRecorder) AnalyticsService {
+	return &analyticsService{repo: repo, urlRepo: urlRepo, cfg: cfg, events: events}
+}
+
+// RecordClick queues a click event for the background writer so redirects are not slowed down
+func (s *analyticsService) RecordClick(input ClickInput) {
+	ua := parseUserAgent(input.UserAgent)
+	event := &model.ClickEvent{
+		ShortCode:      input.ShortCode,
+		Referrer:       input.Referrer,
+		ReferrerDomain: referrerDomain(input.Referrer),
+		UserAgent:      input.UserAgent,
+		Browser:        ua.Browser,
+		OS:             ua.OS,
+		DeviceType:     ua.DeviceType,
+		IPHash:         s.hashIP(input.ClientIP),
+		AcceptLanguage: input.AcceptLanguage,
+		CreatedAt:      time.Now(), // The click time, not the later insert
+	}
+	s.events.Record(event)
+}
+
+func (s *analyticsService) GetClickStats(code string, userID uint, query ClickStatsQuery) (*ClickStats, error) {
+	urlEntry, err := s.urlRepo.FindByShortCodeWithDeleted(code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrURLNotFound
+		}
+		return nil, err
+	}
+	if !isOwner(urlEntry, userID) {
+		return nil, ErrNotURLOwner
+	}
+
+	if query.Interval == "" {
+		query.Interval = repository.BucketDay
+	}
+	switch query.Interval {
+	case repository.BucketHour, repository.BucketDay, repository.BucketWeek:
+	default:
+		return nil, fmt.Errorf("%w: interval must be hour, day or week", ErrInvalidStatsQuery)
+	}
+	if query.To.IsZero() {
+		query.To = time.Now()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.AddDate(0, 0, -30)
+	}
+	if !query.From.Before(query.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidStatsQuery)
+	}
+	if query.Limit <= 0 {
+		query.Limit = 10
+	}
+
+	stats := &ClickStats{
+		ShortCode: code,
+		From:      query.From,
+		To:        query.To,
+		Interval:  query.Interval,
+	}
+
+	if stats.Total, err = s.repo.CountClicks(code, query.From, query.To); err != nil {
+		return nil, err
+	}
+	if stats.Timeline, err = s.repo.CountByBucket(code, query.Interval, query.From, query.To); err != nil {
+		return nil, err
+	}
+
+	breakdowns := []struct {
+		column string
+		dest   *[]repository.ValueCount
+	}{
+		{repository.BreakdownReferrer, &stats.Referrers},
+		{repository.BreakdownBrowser, &stats.Browsers},
+		{repository.BreakdownOS, &stats.OS},
+		{repository.BreakdownDevice, &stats.Devices},
+	}
+	for _, b := range breakdowns {
+		if *b.dest, err = s.repo.CountByColumn(code, b.column, query.From, query.To, query.Limit); err != nil {
+			return nil, err
+		}
+	}
+
+	return stats, nil
+}
+
+// hashIP turns a visitor IP into a salted, truncated hash that still allows counting unique visitors
+func (s *analyticsService) hashIP(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(s.cfg.IPHashSalt + ip))
+	return hex.EncodeToString(sum[:16])
+}
+
+// referrerDomain extracts the host of a referrer URL, or "direct" when there is none
+func referrerDomain(referrer string) string {
+	if referrer == "" {
+		return "direct"
+	}
+	u, err := url.Parse(referrer)
+	if err != nil || u.Hostname() == "" {
+		return "unknown"
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
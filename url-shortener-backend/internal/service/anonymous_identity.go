@@ -0,0 +1,88 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+	"url-shortener/config"
+	"url-shortener/internal/signer"
+)
+
+// anonymousIDPrefix marks IDs issued by the server; the old client-visible
+// "anon-" IDs are never accepted
+const anonymousIDPrefix = "anon_"
+
+var (
+	ErrInvalidAnonymousToken = errors.New("invalid or expired anonymous token")
+	ErrAnonymousIDMismatch   = errors.New("anonymous_id does not belong to this client")
+)
+
+// AnonymousIdentity is a visitor without an account. The token proves the
+// visitor was given the ID by this server.
+type AnonymousIdentity struct {
+	ID        string
+	Token     string
+	ExpiresAt time.Time
+}
+
+// AnonymousIdentityService issues and checks signed anonymous identities
+type AnonymousIdentityService interface {
+	Issue() (*AnonymousIdentity, error)
+	Verify(token string) (string, error)
+}
+
+type anonymousIdentityService struct {
+	signer *signer.Signer
+	ttl    time.Duration
+}
+
+func NewAnonymousIdentityService(cfg config.AnonymousConfig) AnonymousIdentityService {
+	return &anonymousIdentityService{
+		signer: signer.New(cfg.Secret, "anonymous-identity"),
+		ttl:    cfg.IdentityTTL,
+	}
+}
+
+// anonymousTokenPayload is signed into the token
+type anonymousTokenPayload struct {
+	ID        string `json:"id"`
+	ExpiresAt int64  `json:"e"`
+}
+
+// Issue creates a new identity with a 128-bit random ID
+func (s *anonymousIdentityService) Issue() (*AnonymousIdentity, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	identity := &AnonymousIdentity{
+		ID:        anonymousIDPrefix + hex.EncodeToString(b),
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+	payload, err := json.Marshal(anonymousTokenPayload{ID: identity.ID, ExpiresAt: identity.ExpiresAt.Unix()})
+	if err != nil {
+		return nil, err
+	}
+	identity.Token = s.signer.Sign(payload)
+	return identity, nil
+}
+
+// Verify checks the token's signature and expiry and returns the anonymous ID
+func (s *anonymousIdentityService) Verify(token string) (string, error) {
+	payload, err := s.signer.Open(token)
+	if err != nil {
+		return "", ErrInvalidAnonymousToken
+	}
+
+	var claims anonymousTokenPayload
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.ID == "" {
+		return "", ErrInvalidAnonymousToken
+	}
+	if time.Now().Unix() > claims.ExpiresAt {
+		return "", ErrInvalidAnonymousToken
+	}
+	return claims.ID, nil
+}
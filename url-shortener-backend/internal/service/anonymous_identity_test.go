@@ -0,0 +1,90 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/internal/signer"
+)
+
+func TestAnonymousIdentity(t *testing.T) {
+	identities := NewAnonymousIdentityService(testAnonymousConfig())
+
+	identity, err := identities.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(identity.ID, anonymousIDPrefix) || len(identity.ID) != len(anonymousIDPrefix)+32 {
+		t.Errorf("ID = %q", identity.ID)
+	}
+	if got := time.Until(identity.ExpiresAt); got < time.Hour-time.Minute || got > time.Hour {
+		t.Errorf("expires in %s, want 1h", got)
+	}
+
+	anonymousID, err := identities.Verify(identity.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if anonymousID != identity.ID {
+		t.Errorf("Verify = %q, want %q", anonymousID, identity.ID)
+	}
+
+	other, err := identities.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.ID == identity.ID {
+		t.Error("two identities share an ID")
+	}
+}
+
+func TestAnonymousIdentityRejects(t *testing.T) {
+	cfg := testAnonymousConfig()
+	identities := NewAnonymousIdentityService(cfg)
+	identity, err := identities.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(secret, purpose string, payload anonymousTokenPayload) string {
+		b, _ := json.Marshal(payload)
+		return signer.New(secret, purpose).Sign(b)
+	}
+	valid := anonymousTokenPayload{ID: "anon_victim", ExpiresAt: time.Now().Add(time.Hour).Unix()}
+
+	// Someone else's ID in a payload that keeps this token's signature
+	_, signature, _ := strings.Cut(identity.Token, ".")
+	swapped, _, _ := strings.Cut(sign(cfg.Secret, "anonymous-identity", valid), ".")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"no signature", strings.SplitN(identity.Token, ".", 2)[0]},
+		{"swapped payload", swapped + "." + signature},
+		{"truncated signature", identity.Token[:len(identity.Token)-2]},
+		{"another secret", sign("other-secret", "anonymous-identity", valid)},
+		{"signed for another purpose", sign(cfg.Secret, "oidc-state", valid)},
+		{"expired", sign(cfg.Secret, "anonymous-identity", anonymousTokenPayload{ID: "anon_old", ExpiresAt: time.Now().Add(-time.Second).Unix()})},
+		{"no expiry", sign(cfg.Secret, "anonymous-identity", anonymousTokenPayload{ID: "anon_forever"})},
+		{"no ID", sign(cfg.Secret, "anonymous-identity", anonymousTokenPayload{ExpiresAt: valid.ExpiresAt})},
+		{"not JSON", signer.New(cfg.Secret, "anonymous-identity").Sign([]byte("anon_victim"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if id, err := identities.Verify(tt.token); !errors.Is(err, ErrInvalidAnonymousToken) {
+				t.Errorf("Verify = %q, %v, want ErrInvalidAnonymousToken", id, err)
+			}
+		})
+	}
+
+	// Tokens do not survive a secret rotation
+	rotated := cfg
+	rotated.Secret = "rotated-secret"
+	if _, err := NewAnonymousIdentityService(rotated).Verify(identity.Token); !errors.Is(err, ErrInvalidAnonymousToken) {
+		t.Errorf("token after rotation: err = %v, want ErrInvalidAnonymousToken", err)
+	}
+}
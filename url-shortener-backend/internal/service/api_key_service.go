@@ -0,0 +1,208 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"gorm.io/gorm"
+)
+
+// API key scopes
+const (
+	ScopeLinksRead     = "links:read"
+	ScopeLinksWrite    = "links:write"
+	ScopeAnalyticsRead = "analytics:read"
+)
+
+// AllScopes lists every scope an API key can be granted
+var AllScopes = []string{ScopeLinksRead, ScopeLinksWrite, ScopeAnalyticsRead}
+
+// APIKeyPrefix starts every API key, so keys are easy to recognise in headers and secret scanners
+const APIKeyPrefix = "usk_"
+
+const (
+	apiKeyAlphabet       = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	apiKeyIDLength       = 8
+	apiKeySecretLength   = 32
+	apiKeyMaxNameLength  = 100
+	apiKeyLastUsedPeriod = time.Minute // last_used_at is written at most this often per key
+)
+
+var (
+	ErrInvalidAPIKey      = errors.New("invalid, expired or revoked API key")
+	ErrAPIKeyNotFound     = errors.New("API key not found")
+	ErrInvalidScope       = errors.New("invalid API key scope")
+	ErrInvalidAPIKeyInput = errors.New("invalid API key")
+)
+
+// CreateAPIKeyInput describes an API key to be created
+type CreateAPIKeyInput struct {
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time // Optional - the key never expires when nil
+}
+
+// APIKeyService manages personal API keys and authenticates requests made with them
+type APIKeyService interface {
+	CreateKey(userID uint, input CreateAPIKeyInput) (*model.APIKey, string, error)
+	ListKeys(userID uint) ([]model.APIKey, error)
+	RevokeKey(userID, keyID uint) error
+	Authenticate(rawKey string) (*model.APIKey, *model.User, error)
+}
+
+type apiKeyService struct {
+	repo     repository.APIKeyRepository
+	userRepo repository.UserRepository
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepository, userRepo repository.UserRepository) APIKeyService {
+	return &apiKeyService{repo: repo, userRepo: userRepo}
+}
+
+// CreateKey stores a new key and returns it together with the plaintext key,
+// which is not recoverable afterwards
+func (s *apiKeyService) CreateKey(userID uint, input CreateAPIKeyInput) (*model.APIKey, string, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > apiKeyMaxNameLength {
+		return nil, "", fmt.Errorf("%w: name must be 1-%d characters", ErrInvalidAPIKeyInput, apiKeyMaxNameLength)
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, "", fmt.Errorf("%w: expires_at must be in the future", ErrInvalidAPIKeyInput)
+	}
+	scopes, err := normalizeScopes(input.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	id, err := gonanoid.Generate(apiKeyAlphabet, apiKeyIDLength)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := gonanoid.Generate(apiKeyAlphabet, apiKeySecretLength)
+	if err != nil {
+		return nil, "", err
+	}
+
+	prefix := APIKeyPrefix + id
+	rawKey := prefix + "_" + secret
+	key := &model.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   hashToken(rawKey),
+		Scopes:    scopes,
+		ExpiresAt: input.ExpiresAt,
+	}
+	if err := s.repo.Create(key); err != nil {
+		return nil, "", err
+	}
+	return key, rawKey, nil
+}
+
+func (s *apiKeyService) ListKeys(userID uint) ([]model.APIKey, error) {
+	return s.repo.ListByUserID(userID)
+}
+
+func (s *apiKeyService) RevokeKey(userID, keyID uint) error {
+	revoked, err := s.repo.Revoke(keyID, userID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// Authenticate resolves a plaintext key to its record and owner
+func (s *apiKeyService) Authenticate(rawKey string) (*model.APIKey, *model.User, error) {
+	prefix, _, ok := splitAPIKey(rawKey)
+	if !ok {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.FindByPrefix(prefix)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hashToken(rawKey))) != 1 ||
+		key.RevokedAt != nil ||
+		(key.ExpiresAt != nil && !key.ExpiresAt.After(now)) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.userRepo.FindByID(key.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, err
+	}
+	if user.Disabled {
+		return nil, nil, ErrAccountDisabled
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyLastUsedPeriod {
+		if err := s.repo.TouchLastUsed(key.ID, now); err != nil {
+			log.Println("Failed to record API key use:", err)
+		}
+		key.LastUsedAt = &now
+	}
+	return key, user, nil
+}
+
+// IsAPIKey reports whether a credential looks like an API key rather than a JWT
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, APIKeyPrefix)
+}
+
+// splitAPIKey splits "usk_<id>_<secret>" into its stored prefix and secret
+func splitAPIKey(rawKey string) (prefix, secret string, ok bool) {
+	if !IsAPIKey(rawKey) {
+		return "", "", false
+	}
+	id, secret, found := strings.Cut(strings.TrimPrefix(rawKey, APIKeyPrefix), "_")
+	if !found || len(id) != apiKeyIDLength || len(secret) != apiKeySecretLength {
+		return "", "", false
+	}
+	return APIKeyPrefix + id, secret, true
+}
+
+// normalizeScopes validates and de-duplicates requested scopes
+func normalizeScopes(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+
+	var scopes []string
+	seen := make(map[string]bool)
+	for _, scope := range requested {
+		valid := false
+		for _, known := range AllScopes {
+			if scope == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("%w: %q (allowed: %s)", ErrInvalidScope, scope, strings.Join(AllScopes, ", "))
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+	"url-shortener/config"
+	"url-shortener/internal/repository"
+)
+
+// ClickCounter accumulates click increments for links without a click budget
+type ClickCounter interface {
+	Add(code string)
+}
+
+// ClickCounterStats reports the health of the click aggregation pipeline
+type ClickCounterStats struct {
+	Enqueued    int64 `json:"enqueued" example:"10234"`   // Clicks accepted into the queue
+	Delayed     int64 `json:"delayed" example:"12"`       // Clicks that had to wait for queue space
+	Dropped     int64 `json:"dropped" example:"0"`        // Clicks lost because the queue stayed full
+	Flushed     int64 `json:"flushed" example:"10200"`    // Clicks written to the database
+	Flushes     int64 `json:"flushes" example:"87"`       // Successful batch writes
+	FlushErrors int64 `json:"flush_errors" example:"0"`   // Failed batch writes (counts are retried)
+	Pending     int64 `json:"pending" example:"34"`       // Clicks aggregated but not yet written
+	QueueLength int   `json:"queue_length" example:"0"`   // Clicks waiting to be aggregated
+	QueueSize   int   `json:"queue_size" example:"10000"` // Queue capacity
+	LastFlushAt int64 `json:"last_flush_at" example:"0"`  // Unix time of the last successful flush
+}
+
+// ClickAggregator coalesces click increments per short code in memory and
+// writes them in batched transactions, instead of one UPDATE per redirect
+type ClickAggregator struct {
+	repo repository.URLRepository
+	cfg  config.ClickCounterConfig
+
+	queue chan string
+	done  chan struct{}
+	once  sync.Once
+	stop  context.CancelFunc
+
+	enqueued    atomic.Int64
+	delayed     atomic.Int64
+	dropped     atomic.Int64
+	flushed     atomic.Int64
+	flushes     atomic.Int64
+	flushErrors atomic.Int64
+	pending     atomic.Int64
+	lastFlushAt atomic.Int64
+}
+
+func NewClickAggregator(repo repository.URLRepository, cfg config.ClickCounterConfig) *ClickAggregator {
+	return &ClickAggregator{
+		repo:  repo,
+		cfg:   cfg,
+		queue: make(chan string, cfg.QueueSize),
+		done:  make(chan struct{}),
+	}
+}
+
+// Add queues a click. When the queue is full the caller waits up to the
+// enqueue timeout for space, and the click is dropped if none frees up.
+func (a *ClickAggregator) Add(code string) {
+	select {
+	case a.queue <- code:
+		a.enqueued.Add(1)
+		return
+	default:
+	}
+
+	a.delayed.Add(1)
+	timer := time.NewTimer(a.cfg.EnqueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case a.queue <- code:
+		a.enqueued.Add(1)
+	case <-timer.C:
+		a.dropped.Add(1)
+	}
+}
+
+// Start runs the aggregation loop in the background until Stop is called
+func (a *ClickAggregator) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	a.stop = cancel
+	go a.run(ctx)
+}
+
+// Stop flushes every queued and pending click, then returns
+func (a *ClickAggregator) Stop() {
+	a.once.Do(func() {
+		if a.stop != nil {
+			a.stop()
+			<-a.done
+		}
+	})
+}
+
+// Stats returns a snapshot of the pipeline counters
+func (a *ClickAggregator) Stats() ClickCounterStats {
+	return ClickCounterStats{
+		Enqueued:    a.enqueued.Load(),
+		Delayed:     a.delayed.Load(),
+		Dropped:     a.dropped.Load(),
+		Flushed:     a.flushed.Load(),
+		Flushes:     a.flushes.Load(),
+		FlushErrors: a.flushErrors.Load(),
+		Pending:     a.pending.Load(),
+		QueueLength: len(a.queue),
+		QueueSize:   cap(a.queue),
+		LastFlushAt: a.lastFlushAt.Load(),
+	}
+}
+
+func (a *ClickAggregator) run(ctx context.Context) {
+	defer close(a.done)
+
+	ticker := time.NewTicker(a.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	pending := make(map[string]int64)
+	for {
+		select {
+		case code := <-a.queue:
+			pending[code]++
+			a.pending.Add(1)
+			if len(pending) >= a.cfg.FlushThreshold {
+				pending = a.flush(pending)
+			}
+		case <-ticker.C:
+			pending = a.flush(pending)
+		case <-ctx.Done():
+			// Drain whatever is still queued before the final flush
+		drain:
+			for {
+				select {
+				case code := <-a.queue:
+					pending[code]++
+					a.pending.Add(1)
+				default:
+					break drain
+				}
+			}
+			if pending = a.flush(pending); len(pending) > 0 {
+				log.Printf("Click counter: %d click(s) could not be written on shutdown", a.pending.Load())
+			}
+			return
+		}
+	}
+}
+
+// flush writes the pending counts and returns the map to keep aggregating into.
+// On failure the counts are kept so the next flush retries them.
+func (a *ClickAggregator) flush(pending map[string]int64) map[string]int64 {
+	if len(pending) == 0 {
+		return pending
+	}
+
+	if err := a.repo.IncrementClicksBatch(pending); err != nil {
+		a.flushErrors.Add(1)
+		log.Println("Click counter: failed to flush click counts:", err)
+		return pending
+	}
+
+	var total int64
+	for _, n := range pending {
+		total += n
+	}
+	a.flushed.Add(total)
+	a.pending.Add(-total)
+	a.flushes.Add(1)
+	a.lastFlushAt.Store(time.Now().Unix())
+
+	return make(map[string]int64, len(pending))
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+	"url-shortener/config"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+)
+
+// ClickRecorder stores click events for analytics without blocking the caller
+type ClickRecorder interface {
+	Record(event *model.ClickEvent)
+}
+
+// ClickEventStats reports the health of the click event writer
+type ClickEventStats struct {
+	Enqueued    int64 `json:"enqueued" example:"10234"`   // Events accepted into the queue
+	Dropped     int64 `json:"dropped" example:"0"`        // Events lost because the queue was full
+	Written     int64 `json:"written" example:"10200"`    // Events inserted into the database
+	Failed      int64 `json:"failed" example:"0"`         // Events lost to failed inserts
+	QueueLength int   `json:"queue_length" example:"0"`   // Events waiting to be written
+	QueueSize   int   `json:"queue_size" example:"10000"` // Queue capacity
+}
+
+// ClickEventWriter inserts click events in batches from a bounded queue, so a
+// burst of redirects never starts more than one database writer
+type ClickEventWriter struct {
+	repo repository.AnalyticsRepository
+	cfg  config.AnalyticsConfig
+
+	queue chan *model.ClickEvent
+	done  chan struct{}
+	once  sync.Once
+	stop  context.CancelFunc
+
+	enqueued atomic.Int64
+	dropped  atomic.Int64
+	written  atomic.Int64
+	failed   atomic.Int64
+}
+
+func NewClickEventWriter(repo repository.AnalyticsRepository, cfg config.AnalyticsConfig) *ClickEventWriter {
+	return &ClickEventWriter{
+		repo:  repo,
+		cfg:   cfg,
+		queue: make(chan *model.ClickEvent, cfg.QueueSize),
+		done:  make(chan struct{}),
+	}
+}
+
+// Record queues an event, dropping it when the queue is full
+func (w *ClickEventWriter) Record(event *model.ClickEvent) {
+	select {
+	case w.queue <- event:
+		w.enqueued.Add(1)
+	default:
+		w.dropped.Add(1)
+	}
+}
+
+// Start runs the writer in the background until Stop is called
+func (w *ClickEventWriter) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.stop = cancel
+	go w.run(ctx)
+}
+
+// Stop writes every queued event, then returns
+func (w *ClickEventWriter) Stop() {
+	w.once.Do(func() {
+		if w.stop != nil {
+			w.stop()
+			<-w.done
+		}
+	})
+}
+
+// Stats returns a snapshot of the writer counters
+func (w *ClickEventWriter) Stats() ClickEventStats {
+	return ClickEventStats{
+		Enqueued:    w.enqueued.Load(),
+		Dropped:     w.dropped.Load(),
+		Written:     w.written.Load(),
+		Failed:      w.failed.Load(),
+		QueueLength: len(w.queue),
+		QueueSize:   cap(w.queue),
+	}
+}
+
+func (w *ClickEventWriter) run(ctx context.Context) {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*model.ClickEvent, 0, w.cfg.BatchSize)
+	for {
+		select {
+		case event := <-w.queue:
+			if batch = append(batch, event); len(batch) >= w.cfg.BatchSize {
+				batch = w.write(batch)
+			}
+		case <-ticker.C:
+			batch = w.write(batch)
+		case <-ctx.Done():
+			// Drain whatever is still queued before the final write
+			for {
+				select {
+				case event := <-w.queue:
+					if batch = append(batch, event); len(batch) >= w.cfg.BatchSize {
+						batch = w.write(batch)
+					}
+				default:
+					w.write(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// write inserts a batch and returns it emptied. Failed batches are dropped
+// rather than retried, so a database outage cannot grow memory without bound.
+func (w *ClickEventWriter) write(batch []*model.ClickEvent) []*model.ClickEvent {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	if err := w.repo.RecordClicks(batch); err != nil {
+		w.failed.Add(int64(len(batch)))
+		log.Printf("Click events: failed to write %d event(s): %v", len(batch), err)
+	} else {
+		w.written.Add(int64(len(batch)))
+	}
+
+	// The slice is reused, so it must not keep the written events alive
+	clear(batch)
+	return batch[:0]
+}
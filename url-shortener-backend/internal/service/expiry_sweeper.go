@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// ExpirySweeper periodically marks links that ran past their expiry date or
+// click budget, and purges them once the retention period is over. It also
+// deletes expired unclaimed anonymous links and expired refresh tokens.
+type ExpirySweeper struct {
+	urlService   URLService
+	tokenService TokenService
+	interval     time.Duration
+	retention    time.Duration
+}
+
+func NewExpirySweeper(urlService URLService, tokenService TokenService, interval, retention time.Duration) *ExpirySweeper {
+	return &ExpirySweeper{
+		urlService:   urlService,
+		tokenService: tokenService,
+		interval:     interval,
+		retention:    retention,
+	}
+}
+
+// Run sweeps on every tick until the context is cancelled
+func (s *ExpirySweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.sweep()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *ExpirySweeper) sweep() {
+	s.sweepLinks()
+
+	tokens, err := s.tokenService.PurgeExpiredTokens()
+	if err != nil {
+		log.Println("Expiry sweeper: failed to purge expired refresh tokens:", err)
+	} else if tokens > 0 {
+		log.Printf("Expiry sweeper: purged %d expired refresh token(s)", tokens)
+	}
+}
+
+func (s *ExpirySweeper) sweepLinks() {
+	marked, err := s.urlService.MarkExpiredURLs()
+	if err != nil {
+		log.Println("Expiry sweeper: failed to mark expired links:", err)
+		return
+	}
+	if marked > 0 {
+		log.Printf("Expiry sweeper: marked %d link(s) as expired", marked)
+	}
+
+	anonymous, err := s.urlService.PurgeExpiredAnonymousURLs()
+	if err != nil {
+		log.Println("Expiry sweeper: failed to purge expired anonymous links:", err)
+	} else if anonymous > 0 {
+		log.Printf("Expiry sweeper: purged %d expired unclaimed anonymous link(s)", anonymous)
+	}
+
+	if s.retention <= 0 {
+		return
+	}
+
+	purged, err := s.urlService.PurgeExpiredURLs(s.retention)
+	if err != nil {
+		log.Println("Expiry sweeper: failed to purge expired links:", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("Expiry sweeper: purged %d expired link(s)", purged)
+	}
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"url-shortener/config"
+	"url-shortener/internal/loginguard"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+)
+
+// LoginAttempt identifies a sign-in attempt for brute-force protection
+type LoginAttempt struct {
+	Account  string // Email given at login, or MFAAccount for the code step
+	ClientIP string
+}
+
+// MFAAccount is the account key of the two-factor step of a login, so code
+// guessing is counted separately from password guessing
+func MFAAccount(userID uint) string {
+	return fmt.Sprintf("mfa:%d", userID)
+}
+
+// LinkAccount is the account key of the password of a protected short link,
+// so guesses at it are throttled like sign-ins
+func LinkAccount(code string) string {
+	return "link:" + code
+}
+
+// LoginLockoutPage is one page of recorded lockouts
+type LoginLockoutPage struct {
+	Total    int64                `json:"total" example:"3"`
+	Lockouts []model.LoginLockout `json:"lockouts"`
+}
+
+// LoginGuard throttles failed sign-ins per account and per client IP. Check
+// counts an attempt before its credentials are verified, so parallel guesses
+// cannot all get past the limit; the caller then settles the charge with
+// exactly one of RecordFailure, RecordSuccess or Release. The returned
+// durations are how long the caller must wait before trying again. Store
+// errors are logged and let the attempt through.
+type LoginGuard interface {
+	Check(ctx context.Context, attempt LoginAttempt) (*LoginCharge, time.Duration)
+	RecordFailure(ctx context.Context, charge *LoginCharge) time.Duration
+	RecordSuccess(ctx context.Context, charge *LoginCharge)
+	Release(ctx context.Context, charge *LoginCharge)
+	ListLockouts(scope string, limit, offset int) (*LoginLockoutPage, error)
+}
+
+// LoginCharge is an attempt counted by Check. A nil charge settles as a no-op.
+type LoginCharge struct {
+	clientIP string
+	keys     []chargedKey
+}
+
+// chargedKey is one counter an attempt was charged to and its state after the charge
+type chargedKey struct {
+	guardKey
+	attempts loginguard.Attempts
+}
+
+type loginGuard struct {
+	store           loginguard.Store
+	lockouts        repository.LoginLockoutRepository
+	cfg             config.LoginGuardConfig
+	accountSchedule loginguard.Schedule
+	ipSchedule      loginguard.Schedule
+}
+
+func NewLoginGuard(store loginguard.Store, lockouts repository.LoginLockoutRepository, cfg config.LoginGuardConfig) LoginGuard {
+	g := &loginGuard{store: store, lockouts: lockouts, cfg: cfg}
+	g.accountSchedule = g.schedule(cfg.AccountFreeAttempts, cfg.AccountMaxAttempts)
+	g.ipSchedule = g.schedule(cfg.IPFreeAttempts, cfg.IPMaxAttempts)
+	return g
+}
+
+// guardKey is one counter an attempt is charged to
+type guardKey struct {
+	scope    string
+	subject  string
+	free     int
+	max      int
+	schedule loginguard.Schedule
+	storeKey string
+}
+
+func (g *loginGuard) keys(attempt LoginAttempt) []guardKey {
+	var keys []guardKey
+	if account := strings.ToLower(strings.TrimSpace(attempt.Account)); account != "" {
+		keys = append(keys, guardKey{
+			scope:    model.LockoutScopeAccount,
+			subject:  account,
+			free:     g.cfg.AccountFreeAttempts,
+			max:      g.cfg.AccountMaxAttempts,
+			schedule: g.accountSchedule,
+			storeKey: "account:" + account,
+		})
+	}
+	if attempt.ClientIP != "" {
+		keys = append(keys, guardKey{
+			scope:    model.LockoutScopeIP,
+			subject:  attempt.ClientIP,
+			free:     g.cfg.IPFreeAttempts,
+			max:      g.cfg.IPMaxAttempts,
+			schedule: g.ipSchedule,
+			storeKey: "ip:" + attempt.ClientIP,
+		})
+	}
+	return keys
+}
+
+// Check charges the attempt to the account and IP counters. It returns how
+// long the account or IP is still blocked, or 0 and the charge to settle once
+// the credentials are checked. A refused attempt is not charged.
+func (g *loginGuard) Check(ctx context.Context, attempt LoginAttempt) (*LoginCharge, time.Duration) {
+	now := time.Now()
+	charge := &LoginCharge{clientIP: attempt.ClientIP}
+	for _, key := range g.keys(attempt) {
+		attempts, charged, err := g.store.Charge(ctx, key.storeKey, now, key.schedule, g.cfg.FailureWindow)
+		if err != nil {
+			log.Println("Login guard: failed to charge attempt:", err)
+			continue
+		}
+		if !charged {
+			g.Release(ctx, charge)
+			return nil, attempts.LockedUntil.Sub(now)
+		}
+		charge.keys = append(charge.keys, chargedKey{guardKey: key, attempts: attempts})
+	}
+	return charge, 0
+}
+
+// RecordFailure keeps the charge as a failure and returns the block it
+// caused. Lockouts are recorded here, once the attempt is known to be wrong.
+func (g *loginGuard) RecordFailure(ctx context.Context, charge *LoginCharge) time.Duration {
+	if charge == nil {
+		return 0
+	}
+
+	var wait time.Duration
+	for _, key := range charge.keys {
+		delay, lockout := g.delay(key.attempts.Failures, key.free, key.max)
+		if lockout {
+			g.recordLockout(key.guardKey, charge.clientIP, key.attempts.Failures, key.attempts.LockedUntil)
+		}
+		if delay > wait {
+			wait = delay
+		}
+	}
+	return wait
+}
+
+// RecordSuccess clears the account's failures. The IP is only refunded the
+// attempt, so signing in to one account does not reset guessing at others.
+func (g *loginGuard) RecordSuccess(ctx context.Context, charge *LoginCharge) {
+	if charge == nil {
+		return
+	}
+
+	for _, key := range charge.keys {
+		if key.scope != model.LockoutScopeAccount {
+			g.refund(ctx, key.storeKey)
+			continue
+		}
+		if err := g.store.Reset(ctx, key.storeKey); err != nil {
+			log.Println("Login guard: failed to reset attempts:", err)
+		}
+	}
+}
+
+// Release refunds an attempt that ended before its credentials were judged
+func (g *loginGuard) Release(ctx context.Context, charge *LoginCharge) {
+	if charge == nil {
+		return
+	}
+
+	for _, key := range charge.keys {
+		g.refund(ctx, key.storeKey)
+	}
+}
+
+func (g *loginGuard) refund(ctx context.Context, storeKey string) {
+	if err := g.store.Refund(ctx, storeKey); err != nil {
+		log.Println("Login guard: failed to refund attempt:", err)
+	}
+}
+
+func (g *loginGuard) ListLockouts(scope string, limit, offset int) (*LoginLockoutPage, error) {
+	switch {
+	case limit == 0:
+		limit = DefaultPageSize
+	case limit < 0 || limit > MaxPageSize:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidListQuery, MaxPageSize)
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("%w: offset must not be negative", ErrInvalidListQuery)
+	}
+	if scope != "" && scope != model.LockoutScopeAccount && scope != model.LockoutScopeIP {
+		return nil, fmt.Errorf("%w: scope must be account or ip", ErrInvalidListQuery)
+	}
+
+	lockouts, total, err := g.lockouts.List(scope, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginLockoutPage{Total: total, Lockouts: lockouts}, nil
+}
+
+// schedule lists the blocks delay calls for, up to the failure count from
+// which the lockout stays at its cap
+func (g *loginGuard) schedule(free, max int) loginguard.Schedule {
+	var schedule loginguard.Schedule
+	for failures := 1; ; failures++ {
+		delay, _ := g.delay(failures, free, max)
+		schedule = append(schedule, delay)
+		if failures >= max && (delay <= 0 || delay >= g.cfg.LockoutMax) {
+			return schedule
+		}
+	}
+}
+
+// delay returns the block that follows the given number of failures and
+// whether it is a lockout rather than a backoff
+func (g *loginGuard) delay(failures, free, max int) (time.Duration, bool) {
+	switch {
+	case failures >= max:
+		return doubled(g.cfg.LockoutDuration, failures-max, g.cfg.LockoutMax), true
+	case failures > free:
+		return doubled(g.cfg.BackoffBase, failures-free-1, g.cfg.BackoffMax), false
+	default:
+		return 0, false
+	}
+}
+
+func (g *loginGuard) recordLockout(key guardKey, clientIP string, failures int, until time.Time) {
+	log.Printf("Login guard: %s %s locked until %s after %d failed attempts",
+		key.scope, key.subject, until.Format(time.RFC3339), failures)
+
+	err := g.lockouts.Create(&model.LoginLockout{
+		Scope:       key.scope,
+		Subject:     key.subject,
+		ClientIP:    clientIP,
+		Failures:    failures,
+		LockedUntil: until,
+	})
+	if err != nil {
+		log.Println("Login guard: failed to record lockout:", err)
+	}
+}
+
+// doubled returns base doubled n times, capped at max
+func doubled(base time.Duration, n int, max time.Duration) time.Duration {
+	d := base
+	for i := 0; i < n && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
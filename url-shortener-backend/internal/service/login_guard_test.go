@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+	"url-shortener/config"
+	"url-shortener/internal/loginguard"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+)
+
+// fakeLockouts keeps recorded lockouts in memory
+type fakeLockouts struct {
+	repository.LoginLockoutRepository
+	created []model.LoginLockout
+}
+
+func (f *fakeLockouts) Create(lockout *model.LoginLockout) error {
+	f.created = append(f.created, *lockout)
+	return nil
+}
+
+func testLoginGuardConfig() config.LoginGuardConfig {
+	return config.LoginGuardConfig{
+		AccountFreeAttempts: 2,
+		AccountMaxAttempts:  5,
+		IPFreeAttempts:      100,
+		IPMaxAttempts:       200,
+		BackoffBase:         time.Second,
+		BackoffMax:          4 * time.Second,
+		LockoutDuration:     time.Minute,
+		LockoutMax:          4 * time.Minute,
+		FailureWindow:       time.Hour,
+	}
+}
+
+func TestLoginGuardDelay(t *testing.T) {
+	g := NewLoginGuard(loginguard.NewMemoryStore(), &fakeLockouts{}, testLoginGuardConfig()).(*loginGuard)
+
+	tests := []struct {
+		failures    int
+		wantDelay   time.Duration
+		wantLockout bool
+	}{
+		{1, 0, false},
+		{2, 0, false},
+		{3, time.Second, false},
+		{4, 2 * time.Second, false},
+		{5, time.Minute, true},
+		{6, 2 * time.Minute, true},
+		{7, 4 * time.Minute, true},
+		{8, 4 * time.Minute, true}, // Capped at LockoutMax
+	}
+	for _, tt := range tests {
+		delay, lockout := g.delay(tt.failures, 2, 5)
+		if delay != tt.wantDelay || lockout != tt.wantLockout {
+			t.Errorf("delay(%d) = %s, %v, want %s, %v", tt.failures, delay, lockout, tt.wantDelay, tt.wantLockout)
+		}
+		if got := g.accountSchedule.Block(tt.failures); got != tt.wantDelay {
+			t.Errorf("schedule Block(%d) = %s, want %s", tt.failures, got, tt.wantDelay)
+		}
+	}
+
+	// The backoff is capped too
+	if delay, _ := g.delay(12, 2, 20); delay != 4*time.Second {
+		t.Errorf("delay past the backoff cap = %s, want 4s", delay)
+	}
+}
+
+func TestLoginGuardLockouts(t *testing.T) {
+	ctx := context.Background()
+	lockouts := &fakeLockouts{}
+	cfg := testLoginGuardConfig()
+	cfg.BackoffBase = time.Nanosecond // Lets the test retry right away
+	cfg.BackoffMax = time.Nanosecond
+	g := NewLoginGuard(loginguard.NewMemoryStore(), lockouts, cfg)
+	attempt := LoginAttempt{Account: "Alice@Example.com", ClientIP: "10.0.0.1"}
+
+	var wait time.Duration
+	for i := 1; i <= 5; i++ {
+		time.Sleep(time.Millisecond)
+		charge, refused := g.Check(ctx, attempt)
+		if refused > 0 {
+			t.Fatalf("attempt %d refused for %s", i, refused)
+		}
+		wait = g.RecordFailure(ctx, charge)
+	}
+
+	if wait != time.Minute {
+		t.Errorf("wait after the fifth failure = %s, want 1m", wait)
+	}
+	if len(lockouts.created) != 1 {
+		t.Fatalf("recorded %d lockouts, want 1", len(lockouts.created))
+	}
+	lockout := lockouts.created[0]
+	if lockout.Scope != model.LockoutScopeAccount || lockout.Subject != "alice@example.com" ||
+		lockout.ClientIP != "10.0.0.1" || lockout.Failures != 5 {
+		t.Errorf("lockout = %+v", lockout)
+	}
+
+	// Locked out: refused without a charge, even with another spelling of the email
+	charge, refused := g.Check(ctx, LoginAttempt{Account: "alice@example.com", ClientIP: "10.0.0.2"})
+	if charge != nil || refused <= 0 || refused > time.Minute {
+		t.Errorf("locked account: charge %v, wait %s", charge, refused)
+	}
+	if len(lockouts.created) != 1 {
+		t.Errorf("a refused attempt recorded a lockout")
+	}
+
+	// Other accounts on the same IP are not locked
+	if _, refused := g.Check(ctx, LoginAttempt{Account: "bob@example.com", ClientIP: "10.0.0.1"}); refused > 0 {
+		t.Errorf("other account refused for %s", refused)
+	}
+}
+
+func TestLoginGuardSettle(t *testing.T) {
+	ctx := context.Background()
+	cfg := testLoginGuardConfig()
+	cfg.IPFreeAttempts = 2
+	cfg.IPMaxAttempts = 5
+	g := NewLoginGuard(loginguard.NewMemoryStore(), &fakeLockouts{}, cfg)
+
+	// Two failures use up the free attempts of the account and the IP
+	for i := 0; i < 2; i++ {
+		charge, _ := g.Check(ctx, LoginAttempt{Account: "alice@example.com", ClientIP: "10.0.0.1"})
+		g.RecordFailure(ctx, charge)
+	}
+
+	// Released attempts are not counted, so the next one is still free
+	for i := 0; i < 4; i++ {
+		charge, wait := g.Check(ctx, LoginAttempt{Account: "carol@example.com", ClientIP: "10.0.0.9"})
+		if wait > 0 {
+			t.Fatalf("released attempt %d refused for %s", i+1, wait)
+		}
+		g.Release(ctx, charge)
+	}
+
+	// A success clears the account and refunds the IP
+	charge, wait := g.Check(ctx, LoginAttempt{Account: "alice@example.com", ClientIP: "10.0.0.1"})
+	if wait > 0 {
+		t.Fatalf("third attempt refused for %s", wait)
+	}
+	g.RecordSuccess(ctx, charge)
+
+	charge, wait = g.Check(ctx, LoginAttempt{Account: "alice@example.com", ClientIP: "10.0.0.2"})
+	if wait > 0 {
+		t.Fatalf("attempt after success refused for %s", wait)
+	}
+	if got := g.RecordFailure(ctx, charge); got != 0 {
+		t.Errorf("first failure after a success waits %s, want 0", got)
+	}
+
+	// Settling a refused attempt is a no-op
+	g.RecordFailure(ctx, nil)
+	g.RecordSuccess(ctx, nil)
+	g.Release(ctx, nil)
+}
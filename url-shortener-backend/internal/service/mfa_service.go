@@ -0,0 +1,380 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"image/png"
+	"math/big"
+	"strings"
+	"time"
+	"url-shortener/config"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	totpPeriod        = 30 // Seconds per TOTP step
+	totpSkew          = 1  // Steps of clock drift accepted either side
+	recoveryCodeCount = 10
+	recoveryCodeChars = "abcdefghjkmnpqrstuvwxyz23456789" // No 0/o, 1/l/i
+	qrCodeSize        = 200
+)
+
+var totpOpts = totp.ValidateOpts{
+	Period:    totpPeriod,
+	Skew:      totpSkew,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+var (
+	ErrMFAAlreadyEnabled       = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled           = errors.New("two-factor authentication is not enabled")
+	ErrMFAEnrollmentNotStarted = errors.New("start two-factor setup first")
+	ErrInvalidMFACode          = errors.New("invalid two-factor code")
+	ErrInvalidMFAToken         = errors.New("invalid or expired two-factor challenge")
+)
+
+// TOTPEnrollment is what an authenticator app needs to add the account
+type TOTPEnrollment struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXP"`                                         // Base32 secret for manual entry
+	URI    string `json:"otpauth_uri" example:"otpauth://totp/URL%20Shortener:john@example.com?..."` // otpauth:// URI encoded in the QR code
+	QRCode string `json:"qr_code" example:"data:image/png;base64,iVBORw0KGgo..."`                    // PNG data URI of the QR code
+}
+
+// MFAStatus summarises a user's two-factor setup
+type MFAStatus struct {
+	TOTPEnabled       bool  `json:"totp_enabled" example:"true"`
+	RecoveryCodesLeft int64 `json:"recovery_codes_left" example:"9"`
+}
+
+// MFAService manages TOTP two-factor authentication and the second login step
+type MFAService interface {
+	BeginTOTPEnrollment(userID uint) (*TOTPEnrollment, error)
+	ConfirmTOTPEnrollment(userID uint, code string) ([]string, error)
+	DisableTOTP(userID uint, password, code string) error
+	RegenerateRecoveryCodes(userID uint, code string) ([]string, error)
+	Status(userID uint) (*MFAStatus, error)
+	StartLoginChallenge(user *model.User) (string, error)
+	ChallengeUserID(mfaToken string) (uint, error)
+	CompleteLoginChallenge(mfaToken, code string) (*model.User, error)
+}
+
+type mfaService struct {
+	userRepo repository.UserRepository
+	codeRepo repository.RecoveryCodeRepository
+	tokens   TokenIssuer
+	issuer   string
+}
+
+func NewMFAService(userRepo repository.UserRepository, codeRepo repository.RecoveryCodeRepository, tokens TokenIssuer, cfg config.AccountConfig) MFAService {
+	return &mfaService{
+		userRepo: userRepo,
+		codeRepo: codeRepo,
+		tokens:   tokens,
+		issuer:   cfg.MFAIssuer,
+	}
+}
+
+// BeginTOTPEnrollment generates a new secret and keeps it pending until a
+// code from it is confirmed. Starting again replaces the pending secret.
+func (s *mfaService) BeginTOTPEnrollment(userID uint) (*TOTPEnrollment, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.issuer,
+		AccountName: user.Email,
+		Period:      totpPeriod,
+		Digits:      totpOpts.Digits,
+		Algorithm:   totpOpts.Algorithm,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := key.Image(qrCodeSize, qrCodeSize)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	user.TOTPPendingSecret = key.Secret()
+	if err := s.userRepo.Update(user.ID, map[string]interface{}{"totp_pending_secret": user.TOTPPendingSecret}); err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{
+		Secret: key.Secret(),
+		URI:    key.URL(),
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// ConfirmTOTPEnrollment enables two-factor authentication once the user
+// proves their app works, and returns the one-time recovery codes
+func (s *mfaService) ConfirmTOTPEnrollment(userID uint, code string) ([]string, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPPendingSecret == "" {
+		return nil, ErrMFAEnrollmentNotStarted
+	}
+
+	step, ok := matchTOTP(user.TOTPPendingSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, err := s.replaceRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	user.TOTPEnabled = true
+	user.TOTPSecret = user.TOTPPendingSecret
+	user.TOTPPendingSecret = ""
+	user.TOTPLastStep = step // The confirmation code cannot be replayed at login
+	err = s.userRepo.Update(user.ID, map[string]interface{}{
+		"totp_enabled":        user.TOTPEnabled,
+		"totp_secret":         user.TOTPSecret,
+		"totp_pending_secret": user.TOTPPendingSecret,
+		"totp_last_step":      user.TOTPLastStep,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP turns two-factor authentication off. It needs both the
+// password and a current code (or recovery code).
+func (s *mfaService) DisableTOTP(userID uint, password, code string) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrMFANotEnabled
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return ErrInvalidCredentials
+	}
+	if err := s.verifyCode(user, code); err != nil {
+		return err
+	}
+
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPPendingSecret = ""
+	user.TOTPLastStep = 0
+	err = s.userRepo.Update(user.ID, map[string]interface{}{
+		"totp_enabled":        user.TOTPEnabled,
+		"totp_secret":         user.TOTPSecret,
+		"totp_pending_secret": user.TOTPPendingSecret,
+		"totp_last_step":      user.TOTPLastStep,
+	})
+	if err != nil {
+		return err
+	}
+	return s.codeRepo.DeleteAll(user.ID)
+}
+
+// RegenerateRecoveryCodes invalidates the old recovery codes and returns a new set
+func (s *mfaService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrMFANotEnabled
+	}
+	if err := s.verifyCode(user, code); err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(user.ID)
+}
+
+func (s *mfaService) Status(userID uint) (*MFAStatus, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MFAStatus{TOTPEnabled: user.TOTPEnabled}
+	if user.TOTPEnabled {
+		if status.RecoveryCodesLeft, err = s.codeRepo.CountUnused(user.ID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// StartLoginChallenge returns the short-lived token a password login hands
+// out instead of a session when the account has two-factor authentication
+func (s *mfaService) StartLoginChallenge(user *model.User) (string, error) {
+	return s.tokens.MFAToken(user.ID, user.Username)
+}
+
+// ChallengeUserID returns the user a challenge token was issued to
+func (s *mfaService) ChallengeUserID(mfaToken string) (uint, error) {
+	userID, err := s.tokens.MFATokenUserID(mfaToken)
+	if err != nil {
+		return 0, ErrInvalidMFAToken
+	}
+	return userID, nil
+}
+
+// CompleteLoginChallenge checks the second factor for a challenge token and
+// returns the user to issue a session for
+func (s *mfaService) CompleteLoginChallenge(mfaToken, code string) (*model.User, error) {
+	userID, err := s.tokens.MFATokenUserID(mfaToken)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	user, err := s.findUser(userID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrInvalidMFAToken
+		}
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, ErrAccountDisabled
+	}
+	if !user.TOTPEnabled {
+		// Two-factor was turned off since the challenge was issued
+		return nil, ErrInvalidMFAToken
+	}
+
+	if err := s.verifyCode(user, code); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// verifyCode accepts a TOTP code from the user's app or an unused recovery
+// code. A TOTP code is accepted once; its step is recorded to stop replays.
+func (s *mfaService) verifyCode(user *model.User, code string) error {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if code == "" {
+		return ErrInvalidMFACode
+	}
+
+	if step, ok := matchTOTP(user.TOTPSecret, code, time.Now()); ok {
+		advanced, err := s.userRepo.AdvanceTOTPStep(user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !advanced {
+			return ErrInvalidMFACode
+		}
+		user.TOTPLastStep = step
+		return nil
+	}
+
+	used, err := s.codeRepo.Consume(user.ID, hashToken(normalizeRecoveryCode(code)), time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (s *mfaService) replaceRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	records := make([]model.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		records[i] = model.RecoveryCode{UserID: userID, CodeHash: hashToken(code)}
+	}
+
+	if err := s.codeRepo.ReplaceAll(userID, records); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *mfaService) findUser(userID uint) (*model.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// matchTOTP checks a code against the steps within the allowed clock skew
+// and returns the step it belongs to
+func matchTOTP(secret, code string, now time.Time) (int64, bool) {
+	if secret == "" || len(code) != otp.DigitsSix.Length() {
+		return 0, false
+	}
+
+	for offset := -totpSkew; offset <= totpSkew; offset++ {
+		at := now.Add(time.Duration(offset*totpPeriod) * time.Second)
+		expected, err := totp.GenerateCodeCustom(secret, at, totpOpts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return at.Unix() / totpPeriod, true
+		}
+	}
+	return 0, false
+}
+
+// newRecoveryCode returns a random code formatted as xxxxx-xxxxx
+func newRecoveryCode() (string, error) {
+	var b strings.Builder
+	max := big.NewInt(int64(len(recoveryCodeChars)))
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			b.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(recoveryCodeChars[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+// normalizeRecoveryCode accepts codes typed in any case, with or without the dash
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(code, "-", ""))
+	if len(code) != 10 {
+		return code
+	}
+	return code[:5] + "-" + code[5:]
+}
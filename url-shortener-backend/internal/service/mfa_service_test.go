@@ -0,0 +1,246 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/config"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func newTestMFAService(t *testing.T) (MFAService, *gorm.DB, *model.User) {
+	t.Helper()
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&model.User{}, &model.RecoveryCode{}); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &model.User{Username: "alice", Email: "alice@example.com", Password: string(hash)}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	mfa := NewMFAService(repository.NewUserRepository(db), repository.NewRecoveryCodeRepository(db),
+		fakeIssuer{}, config.AccountConfig{MFAIssuer: "URL Shortener"})
+	return mfa, db, user
+}
+
+// totpCode returns the code of the step that contains at
+func totpCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	code, err := totp.GenerateCodeCustom(secret, at, totpOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// enableTOTP enrolls the user and returns the secret and recovery codes
+func enableTOTP(t *testing.T, mfa MFAService, userID uint) (string, []string) {
+	t.Helper()
+	enrollment, err := mfa.BeginTOTPEnrollment(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, err := mfa.ConfirmTOTPEnrollment(userID, totpCode(t, enrollment.Secret, time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return enrollment.Secret, codes
+}
+
+func TestTOTPEnrollment(t *testing.T) {
+	mfa, _, user := newTestMFAService(t)
+
+	if _, err := mfa.ConfirmTOTPEnrollment(user.ID, "123456"); !errors.Is(err, ErrMFAEnrollmentNotStarted) {
+		t.Errorf("confirm before setup: err = %v, want ErrMFAEnrollmentNotStarted", err)
+	}
+
+	enrollment, err := mfa.BeginTOTPEnrollment(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(enrollment.URI, "otpauth://totp/") || !strings.HasPrefix(enrollment.QRCode, "data:image/png;base64,") {
+		t.Errorf("enrollment = %+v", enrollment)
+	}
+
+	// Not enabled until a code from the app is confirmed
+	if status, _ := mfa.Status(user.ID); status.TOTPEnabled {
+		t.Fatal("enabled before confirmation")
+	}
+	wrong := totpCode(t, enrollment.Secret, time.Now().Add(time.Hour))
+	if _, err := mfa.ConfirmTOTPEnrollment(user.ID, wrong); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("wrong code: err = %v, want ErrInvalidMFACode", err)
+	}
+
+	codes, err := mfa.ConfirmTOTPEnrollment(user.ID, totpCode(t, enrollment.Secret, time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Errorf("%d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+	status, err := mfa.Status(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.TOTPEnabled || status.RecoveryCodesLeft != recoveryCodeCount {
+		t.Errorf("status = %+v", status)
+	}
+
+	if _, err := mfa.BeginTOTPEnrollment(user.ID); !errors.Is(err, ErrMFAAlreadyEnabled) {
+		t.Errorf("second setup: err = %v, want ErrMFAAlreadyEnabled", err)
+	}
+}
+
+func TestTOTPReplay(t *testing.T) {
+	mfa, db, user := newTestMFAService(t)
+	secret, _ := enableTOTP(t, mfa, user.ID)
+	challenge, err := mfa.StartLoginChallenge(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stored model.User
+	db.First(&stored, user.ID)
+	confirmedStep := stored.TOTPLastStep
+
+	// The code used to confirm the setup cannot be replayed at login
+	confirmation := totpCode(t, secret, time.Unix(confirmedStep*totpPeriod, 0))
+	if _, err := mfa.CompleteLoginChallenge(challenge, confirmation); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("confirmation code: err = %v, want ErrInvalidMFACode", err)
+	}
+
+	// A code of the next step, within the allowed drift, works once
+	next := totpCode(t, secret, time.Unix((confirmedStep+1)*totpPeriod, 0))
+	if _, err := mfa.CompleteLoginChallenge(challenge, next); err != nil {
+		t.Fatalf("next step code: %v", err)
+	}
+	if _, err := mfa.CompleteLoginChallenge(challenge, next); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("replayed code: err = %v, want ErrInvalidMFACode", err)
+	}
+
+	// Codes from outside the drift window are refused
+	late := totpCode(t, secret, time.Now().Add(5*time.Minute))
+	if _, err := mfa.CompleteLoginChallenge(challenge, late); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("code from the future: err = %v, want ErrInvalidMFACode", err)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	mfa, db, user := newTestMFAService(t)
+	secret, codes := enableTOTP(t, mfa, user.ID)
+	challenge, err := mfa.StartLoginChallenge(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Codes are stored hashed
+	var stored model.RecoveryCode
+	db.First(&stored)
+	if stored.CodeHash == codes[0] || strings.Contains(stored.CodeHash, "-") {
+		t.Errorf("recovery code stored as %q", stored.CodeHash)
+	}
+
+	// Accepted in any case and without the dash, once
+	typed := strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))
+	signedIn, err := mfa.CompleteLoginChallenge(challenge, typed)
+	if err != nil {
+		t.Fatalf("recovery code: %v", err)
+	}
+	if signedIn.ID != user.ID {
+		t.Errorf("signed in as user %d", signedIn.ID)
+	}
+	if _, err := mfa.CompleteLoginChallenge(challenge, codes[0]); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("used recovery code: err = %v, want ErrInvalidMFACode", err)
+	}
+	if status, _ := mfa.Status(user.ID); status.RecoveryCodesLeft != recoveryCodeCount-1 {
+		t.Errorf("%d recovery codes left, want %d", status.RecoveryCodesLeft, recoveryCodeCount-1)
+	}
+
+	// Codes of another user do not work
+	other := &model.User{Username: "bob", Email: "bob@example.com", Password: "hash"}
+	if err := db.Create(other).Error; err != nil {
+		t.Fatal(err)
+	}
+	_, otherCodes := enableTOTP(t, mfa, other.ID)
+	if _, err := mfa.CompleteLoginChallenge(challenge, otherCodes[0]); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("another user's code: err = %v, want ErrInvalidMFACode", err)
+	}
+
+	// Regenerating needs a valid code and invalidates the old set
+	if _, err := mfa.RegenerateRecoveryCodes(user.ID, "000000"); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("regenerate with a wrong code: err = %v, want ErrInvalidMFACode", err)
+	}
+	fresh, err := mfa.RegenerateRecoveryCodes(user.ID, codes[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mfa.CompleteLoginChallenge(challenge, codes[2]); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("code of the old set: err = %v, want ErrInvalidMFACode", err)
+	}
+	if _, err := mfa.CompleteLoginChallenge(challenge, fresh[0]); err != nil {
+		t.Errorf("code of the new set: %v", err)
+	}
+
+	// Disabling needs the password and a code, and removes the codes
+	code := totpCode(t, secret, time.Now().Add(totpPeriod*time.Second))
+	if err := mfa.DisableTOTP(user.ID, "wrong-password", code); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("disable with a wrong password: err = %v, want ErrInvalidCredentials", err)
+	}
+	if err := mfa.DisableTOTP(user.ID, "password123", fresh[1]); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := mfa.Status(user.ID); status.TOTPEnabled || status.RecoveryCodesLeft != 0 {
+		t.Errorf("status after disabling = %+v", status)
+	}
+	var left int64
+	db.Model(&model.RecoveryCode{}).Where("user_id = ?", user.ID).Count(&left)
+	if left != 0 {
+		t.Errorf("%d recovery codes left after disabling", left)
+	}
+}
+
+func TestLoginChallenge(t *testing.T) {
+	mfa, db, user := newTestMFAService(t)
+	_, codes := enableTOTP(t, mfa, user.ID)
+	challenge, err := mfa.StartLoginChallenge(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if userID, err := mfa.ChallengeUserID(challenge); err != nil || userID != user.ID {
+		t.Errorf("ChallengeUserID = %d, %v", userID, err)
+	}
+	for _, token := range []string{"", "access:1", "mfa:99"} {
+		if _, err := mfa.CompleteLoginChallenge(token, codes[0]); !errors.Is(err, ErrInvalidMFAToken) {
+			t.Errorf("challenge %q: err = %v, want ErrInvalidMFAToken", token, err)
+		}
+	}
+	if _, err := mfa.CompleteLoginChallenge(challenge, ""); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("empty code: err = %v, want ErrInvalidMFACode", err)
+	}
+
+	// Disabled users cannot finish signing in
+	db.Model(user).Update("disabled", true)
+	if _, err := mfa.CompleteLoginChallenge(challenge, codes[0]); !errors.Is(err, ErrAccountDisabled) {
+		t.Errorf("disabled user: err = %v, want ErrAccountDisabled", err)
+	}
+	db.Model(user).Update("disabled", false)
+
+	// Challenges issued before two-factor was turned off are void
+	if err := mfa.DisableTOTP(user.ID, "password123", codes[1]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mfa.CompleteLoginChallenge(challenge, codes[2]); !errors.Is(err, ErrInvalidMFAToken) {
+		t.Errorf("challenge after disabling: err = %v, want ErrInvalidMFAToken", err)
+	}
+}
@@ -0,0 +1,503 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"url-shortener/config"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/signer"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+const (
+	// OIDCLoginCodeTTL is how long the frontend has to exchange the code it
+	// receives after a successful OIDC callback
+	OIDCLoginCodeTTL = 2 * time.Minute
+
+	oidcHTTPTimeout = 10 * time.Second
+)
+
+var (
+	ErrUnknownOIDCProvider  = errors.New("unknown sign-in provider")
+	ErrInvalidOIDCState     = errors.New("sign-in session expired or was tampered with, please try again")
+	ErrOIDCDenied           = errors.New("sign-in was cancelled at the provider")
+	ErrOIDCEmailNotVerified = errors.New("the provider did not return a verified email address")
+	ErrOIDCSignupDisabled   = errors.New("no account uses this email address")
+	ErrInvalidLoginCode     = errors.New("invalid or expired sign-in code")
+)
+
+// usernameChars are the characters kept when deriving a username from the provider
+var usernameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// OIDCProvider is a configured identity provider as shown on the login page
+type OIDCProvider struct {
+	Name        string `json:"name" example:"company"`
+	DisplayName string `json:"display_name" example:"Company SSO"`
+	LoginURL    string `json:"login_url" example:"/api/auth/oidc/company/login"`
+}
+
+// OIDCLoginStart is where to send the browser and the state it must carry back
+type OIDCLoginStart struct {
+	AuthURL      string    // Authorization endpoint of the provider
+	State        string    // Signed value for the state cookie
+	ExpiresAt    time.Time // When the state cookie stops being accepted
+	SecureCookie bool      // Whether the callback is served over HTTPS
+}
+
+// OIDCCallback holds the query parameters the provider redirects back with
+type OIDCCallback struct {
+	Code             string
+	State            string
+	Error            string
+	ErrorDescription string
+}
+
+// OIDCService signs users in with external OpenID Connect providers using
+// the authorization code flow with PKCE
+type OIDCService interface {
+	Providers() []OIDCProvider
+	StartLogin(provider string) (*OIDCLoginStart, error)
+	CompleteLogin(ctx context.Context, provider, stateCookie string, callback OIDCCallback) (string, error)
+	AppRedirect(loginCode string, err error) string
+	ExchangeLoginCode(code string) (*model.User, error)
+}
+
+// oidcState travels in a signed cookie between StartLogin and CompleteLogin
+type oidcState struct {
+	Provider string `json:"p"`
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"` // PKCE code verifier
+	Expires  int64  `json:"e"`
+}
+
+// oidcClaims are the ID token claims used to find or create the user
+type oidcClaims struct {
+	Email             string      `json:"email"`
+	EmailVerified     interface{} `json:"email_verified"` // Some providers send "true" as a string
+	PreferredUsername string      `json:"preferred_username"`
+}
+
+func (c oidcClaims) emailVerified() bool {
+	switch v := c.EmailVerified.(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	default:
+		return false
+	}
+}
+
+// oidcProvider discovers its endpoints on first use, so the service starts
+// even while a provider is unreachable
+type oidcProvider struct {
+	cfg         config.OIDCProviderConfig
+	redirectURL string
+
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+type oidcService struct {
+	userRepo     repository.UserRepository
+	identityRepo repository.UserIdentityRepository
+	tokenRepo    repository.UserTokenRepository
+	cfg          config.OIDCConfig
+	state        *signer.Signer
+	client       *http.Client
+	providers    map[string]*oidcProvider
+	order        []string
+}
+
+func NewOIDCService(userRepo repository.UserRepository, identityRepo repository.UserIdentityRepository, tokenRepo repository.UserTokenRepository, cfg config.OIDCConfig) OIDCService {
+	s := &oidcService{
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		tokenRepo:    tokenRepo,
+		cfg:          cfg,
+		state:        signer.New(cfg.StateSecret, "oidc-state"),
+		client:       &http.Client{Timeout: oidcHTTPTimeout},
+		providers:    make(map[string]*oidcProvider, len(cfg.Providers)),
+	}
+	for _, p := range cfg.Providers {
+		s.providers[p.Name] = &oidcProvider{
+			cfg:         p,
+			redirectURL: fmt.Sprintf("%s/api/auth/oidc/%s/callback", cfg.CallbackBaseURL, p.Name),
+		}
+		s.order = append(s.order, p.Name)
+	}
+	return s
+}
+
+func (s *oidcService) Providers() []OIDCProvider {
+	providers := make([]OIDCProvider, 0, len(s.order))
+	for _, name := range s.order {
+		p := s.providers[name]
+		providers = append(providers, OIDCProvider{
+			Name:        name,
+			DisplayName: p.cfg.DisplayName,
+			LoginURL:    "/api/auth/oidc/" + name + "/login",
+		})
+	}
+	return providers
+}
+
+// StartLogin builds the authorization request. State, nonce and the PKCE
+// verifier are kept in a signed cookie rather than on the server.
+func (s *oidcService) StartLogin(provider string) (*OIDCLoginStart, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return nil, ErrUnknownOIDCProvider
+	}
+	oauth, _, err := p.client(s.client)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := gonanoid.New(32)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := gonanoid.New(32)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier := oauth2.GenerateVerifier()
+	expiresAt := time.Now().Add(s.cfg.StateTTL)
+	payload, err := json.Marshal(oidcState{
+		Provider: provider,
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+		Expires:  expiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCLoginStart{
+		AuthURL:      oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)),
+		State:        s.state.Sign(payload),
+		ExpiresAt:    expiresAt,
+		SecureCookie: strings.HasPrefix(s.cfg.CallbackBaseURL, "https://"),
+	}, nil
+}
+
+// CompleteLogin handles the provider's redirect: it checks the state,
+// redeems the code, verifies the ID token and signs the matching user in.
+// It returns a single-use code the frontend exchanges for tokens.
+func (s *oidcService) CompleteLogin(ctx context.Context, provider, stateCookie string, callback OIDCCallback) (string, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return "", ErrUnknownOIDCProvider
+	}
+
+	saved, err := s.openState(stateCookie)
+	if err != nil || saved.Provider != provider ||
+		subtle.ConstantTimeCompare([]byte(saved.State), []byte(callback.State)) != 1 {
+		return "", ErrInvalidOIDCState
+	}
+	if callback.Error != "" {
+		if callback.Error == "access_denied" {
+			return "", ErrOIDCDenied
+		}
+		return "", fmt.Errorf("provider %s returned %s: %s", provider, callback.Error, callback.ErrorDescription)
+	}
+	if callback.Code == "" {
+		return "", ErrInvalidOIDCState
+	}
+
+	oauth, verifier, err := p.client(s.client)
+	if err != nil {
+		return "", err
+	}
+
+	ctx = oidc.ClientContext(ctx, s.client)
+	token, err := oauth.Exchange(ctx, callback.Code, oauth2.VerifierOption(saved.Verifier))
+	if err != nil {
+		return "", fmt.Errorf("code exchange with %s failed: %w", provider, err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return "", fmt.Errorf("provider %s returned no id_token", provider)
+	}
+
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return "", fmt.Errorf("invalid ID token from %s: %w", provider, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(saved.Nonce)) != 1 {
+		return "", fmt.Errorf("ID token from %s has the wrong nonce", provider)
+	}
+
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return "", err
+	}
+
+	user, err := s.resolveUser(provider, idToken.Subject, claims)
+	if err != nil {
+		return "", err
+	}
+	return s.issueLoginCode(user)
+}
+
+// AppRedirect is the frontend URL the callback sends the browser to: the
+// login code on success, or a message the login page can show
+func (s *oidcService) AppRedirect(loginCode string, err error) string {
+	if err == nil {
+		return s.cfg.AppURL + "/oidc/callback?code=" + url.QueryEscape(loginCode)
+	}
+
+	message := "Sign-in failed, please try again"
+	for _, known := range []error{ErrUnknownOIDCProvider, ErrInvalidOIDCState, ErrOIDCDenied, ErrOIDCEmailNotVerified, ErrOIDCSignupDisabled, ErrAccountDisabled} {
+		if errors.Is(err, known) {
+			message = known.Error()
+			break
+		}
+	}
+	return s.cfg.AppURL + "/login?oidc_error=" + url.QueryEscape(message)
+}
+
+// ExchangeLoginCode redeems the code from AppRedirect and returns the user to
+// start a session for
+func (s *oidcService) ExchangeLoginCode(code string) (*model.User, error) {
+	record, err := s.tokenRepo.FindByHash(model.TokenPurposeOIDCLogin, hashToken(code))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidLoginCode
+		}
+		return nil, err
+	}
+
+	consumed, err := s.tokenRepo.Consume(record, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, ErrInvalidLoginCode
+	}
+
+	user, err := s.userRepo.FindByID(record.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidLoginCode
+		}
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, ErrAccountDisabled
+	}
+	return user, nil
+}
+
+// resolveUser finds the user linked to the external identity. An unlinked
+// identity is linked to the user with the same verified email, or to a new
+// account when sign-up is allowed.
+func (s *oidcService) resolveUser(provider, subject string, claims oidcClaims) (*model.User, error) {
+	now := time.Now()
+
+	identity, err := s.identityRepo.FindBySubject(provider, subject)
+	switch {
+	case err == nil:
+		user, err := s.userRepo.FindByID(identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user.Disabled {
+			return nil, ErrAccountDisabled
+		}
+		if err := s.identityRepo.TouchLastLogin(identity.ID, now); err != nil {
+			log.Printf("Failed to update last login of identity %d: %v", identity.ID, err)
+		}
+		return user, nil
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	email := strings.TrimSpace(claims.Email)
+	if email == "" || !claims.emailVerified() {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	user, err := s.userRepo.FindByEmail(email)
+	switch {
+	case err == nil:
+		if user.Disabled {
+			return nil, ErrAccountDisabled
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if !s.cfg.AllowSignup {
+			return nil, ErrOIDCSignupDisabled
+		}
+		if user, err = s.createUser(email, claims); err != nil {
+			return nil, err
+		}
+		log.Printf("Created user %d from %s sign-in", user.ID, provider)
+	default:
+		return nil, err
+	}
+
+	// The provider vouches for the address
+	if user.EmailVerifiedAt == nil && user.Email == email {
+		user.EmailVerifiedAt = &now
+		if err := s.userRepo.Update(user.ID, map[string]interface{}{"email_verified_at": user.EmailVerifiedAt}); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.identityRepo.Create(&model.UserIdentity{
+		UserID:      user.ID,
+		Provider:    provider,
+		Subject:     subject,
+		Email:       email,
+		LastLoginAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Linked %s identity to user %d", provider, user.ID)
+	return user, nil
+}
+
+// createUser registers an account for a first-time OIDC user. It gets a
+// random password; "forgot password" sets a real one if ever needed.
+func (s *oidcService) createUser(email string, claims oidcClaims) (*model.User, error) {
+	username, err := s.freeUsername(claims.PreferredUsername, email)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := gonanoid.New(32)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, errors.New("failed to hash password")
+	}
+
+	user := &model.User{
+		Username: username,
+		Email:    email,
+		Password: string(hashedPassword),
+		Role:     model.RoleUser,
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// freeUsername derives an unused username from the provider's preferred
+// username or the email's local part
+func (s *oidcService) freeUsername(preferred, email string) (string, error) {
+	base := usernameChars.ReplaceAllString(preferred, "")
+	if base == "" {
+		local, _, _ := strings.Cut(email, "@")
+		base = usernameChars.ReplaceAllString(local, "")
+	}
+	if base == "" {
+		base = "user"
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		_, err := s.userRepo.FindByUsername(candidate)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		suffix, err := gonanoid.Generate("0123456789", 4)
+		if err != nil {
+			return "", err
+		}
+		candidate = base + "-" + suffix
+	}
+	return "", fmt.Errorf("could not find a free username for %q", base)
+}
+
+func (s *oidcService) issueLoginCode(user *model.User) (string, error) {
+	code, err := gonanoid.New(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.tokenRepo.Replace(&model.UserToken{
+		UserID:    user.ID,
+		Purpose:   model.TokenPurposeOIDCLogin,
+		TokenHash: hashToken(code),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(OIDCLoginCodeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+func (s *oidcService) openState(value string) (*oidcState, error) {
+	payload, err := s.state.Open(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var state oidcState
+	if err := json.Unmarshal(payload, &state); err != nil {
+		return nil, err
+	}
+	if time.Now().Unix() > state.Expires {
+		return nil, ErrInvalidOIDCState
+	}
+	return &state, nil
+}
+
+// client returns the OAuth2 config and ID token verifier, running discovery
+// on first use. A failed discovery is retried on the next login.
+func (p *oidcProvider) client(httpClient *http.Client) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oauth != nil {
+		return p.oauth, p.verifier, nil
+	}
+
+	// The provider keeps this context to fetch signing keys later, so it must not be a request context
+	ctx := oidc.ClientContext(context.Background(), httpClient)
+	provider, err := oidc.NewProvider(ctx, p.cfg.Issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("OIDC discovery for %s failed: %w", p.cfg.Name, err)
+	}
+
+	p.oauth = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  p.redirectURL,
+		Scopes:       p.cfg.Scopes,
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID})
+	return p.oauth, p.verifier, nil
+}
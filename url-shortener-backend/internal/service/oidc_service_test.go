@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/config"
+	"url-shortener/internal/mockoidc"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/signer"
+
+	"gorm.io/gorm"
+)
+
+const testOIDCSecret = "test-state-secret"
+
+// newTestOIDCService signs in against a mock issuer registered as two
+// providers, "mock" and "other"
+func newTestOIDCService(t *testing.T, allowSignup bool) (OIDCService, *gorm.DB) {
+	t.Helper()
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&model.User{}, &model.UserIdentity{}, &model.UserToken{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The issuer URL must be known before the provider is created
+	server := httptest.NewUnstartedServer(nil)
+	issuer, err := mockoidc.New("http://"+server.Listener.Addr().String(), "url-shortener")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Config.Handler = issuer
+	server.Start()
+	t.Cleanup(server.Close)
+
+	provider := config.OIDCProviderConfig{Issuer: issuer.Issuer(), ClientID: issuer.ClientID(), Scopes: []string{"openid", "email"}}
+	mock, other := provider, provider
+	mock.Name, other.Name = "mock", "other"
+	cfg := config.OIDCConfig{
+		Providers:       []config.OIDCProviderConfig{mock, other},
+		CallbackBaseURL: "https://api.example.com",
+		AppURL:          "https://app.example.com",
+		StateSecret:     testOIDCSecret,
+		StateTTL:        10 * time.Minute,
+		AllowSignup:     allowSignup,
+	}
+	oidc := NewOIDCService(repository.NewUserRepository(db), repository.NewUserIdentityRepository(db), repository.NewUserTokenRepository(db), cfg)
+	return oidc, db
+}
+
+// authorize sends the browser to the provider, changing the authorization
+// request first, and returns the callback the provider redirects to
+func authorize(t *testing.T, authURL string, change func(url.Values)) OIDCCallback {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	query.Set("login_hint", "carol@example.com")
+	if change != nil {
+		change(query)
+	}
+	u.RawQuery = query.Encode()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: status %d", resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(location.String(), "https://api.example.com/api/auth/oidc/") {
+		t.Fatalf("redirected to %s", location)
+	}
+	callback := location.Query()
+	return OIDCCallback{
+		Code:             callback.Get("code"),
+		State:            callback.Get("state"),
+		Error:            callback.Get("error"),
+		ErrorDescription: callback.Get("error_description"),
+	}
+}
+
+func startOIDCLogin(t *testing.T, oidc OIDCService, provider string) *OIDCLoginStart {
+	t.Helper()
+	start, err := oidc.StartLogin(provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return start
+}
+
+func countUsers(t *testing.T, db *gorm.DB) int64 {
+	t.Helper()
+	var n int64
+	if err := db.Model(&model.User{}).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestOIDCLogin(t *testing.T) {
+	oidc, db := newTestOIDCService(t, true)
+
+	start := startOIDCLogin(t, oidc, "mock")
+	authURL, _ := url.Parse(start.AuthURL)
+	query := authURL.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" || query.Get("nonce") == "" || query.Get("state") == "" {
+		t.Errorf("authorization request %s", start.AuthURL)
+	}
+	// The verifier stays in the cookie, out of the URL
+	if strings.Contains(start.AuthURL, "code_verifier") || !start.SecureCookie {
+		t.Errorf("AuthURL %s, SecureCookie %v", start.AuthURL, start.SecureCookie)
+	}
+
+	callback := authorize(t, start.AuthURL, nil)
+	loginCode, err := oidc.CompleteLogin(context.Background(), "mock", start.State, callback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := oidc.ExchangeLoginCode(loginCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "carol@example.com" || user.Username != "carol" || user.EmailVerifiedAt == nil {
+		t.Errorf("new user = %+v", user)
+	}
+	if _, err := oidc.ExchangeLoginCode(loginCode); !errors.Is(err, ErrInvalidLoginCode) {
+		t.Errorf("reused login code: err = %v, want ErrInvalidLoginCode", err)
+	}
+
+	// The authorization code works once
+	if _, err := oidc.CompleteLogin(context.Background(), "mock", start.State, callback); err == nil {
+		t.Error("authorization code was redeemed twice")
+	}
+
+	// Signing in again finds the linked identity
+	start = startOIDCLogin(t, oidc, "mock")
+	loginCode, err = oidc.CompleteLogin(context.Background(), "mock", start.State, authorize(t, start.AuthURL, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := oidc.ExchangeLoginCode(loginCode); err != nil || again.ID != user.ID {
+		t.Errorf("second sign-in: user %v, err %v", again, err)
+	}
+	if n := countUsers(t, db); n != 1 {
+		t.Errorf("%d users, want 1", n)
+	}
+}
+
+func TestOIDCState(t *testing.T) {
+	oidc, _ := newTestOIDCService(t, true)
+	start := startOIDCLogin(t, oidc, "mock")
+	callback := authorize(t, start.AuthURL, nil)
+
+	var saved oidcState
+	payload, err := signer.New(testOIDCSecret, "oidc-state").Open(start.State)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(payload, &saved); err != nil {
+		t.Fatal(err)
+	}
+	sign := func(secret string, change func(*oidcState)) string {
+		state := saved
+		change(&state)
+		payload, _ := json.Marshal(state)
+		return signer.New(secret, "oidc-state").Sign(payload)
+	}
+	// A longer-lived payload with this login's signature
+	_, signature, _ := strings.Cut(start.State, ".")
+	altered, _, _ := strings.Cut(sign("forged", func(s *oidcState) { s.Expires += 3600 }), ".")
+	otherState := callback
+	otherState.State = "not-the-state"
+	noCode := callback
+	noCode.Code = ""
+
+	tests := []struct {
+		name     string
+		provider string
+		cookie   string
+		callback OIDCCallback
+	}{
+		{"no cookie", "mock", "", callback},
+		{"altered cookie", "mock", altered + "." + signature, callback},
+		{"cookie signed with another secret", "mock", sign("forged", func(s *oidcState) {}), callback},
+		{"expired cookie", "mock", sign(testOIDCSecret, func(s *oidcState) { s.Expires = time.Now().Add(-time.Second).Unix() }), callback},
+		{"state from another login", "mock", start.State, otherState},
+		{"cookie of another provider", "other", start.State, callback},
+		{"no code", "mock", start.State, noCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := oidc.CompleteLogin(context.Background(), tt.provider, tt.cookie, tt.callback); !errors.Is(err, ErrInvalidOIDCState) {
+				t.Errorf("err = %v, want ErrInvalidOIDCState", err)
+			}
+		})
+	}
+
+	// None of them spent the code
+	if _, err := oidc.CompleteLogin(context.Background(), "mock", start.State, callback); err != nil {
+		t.Errorf("valid callback after refused ones: %v", err)
+	}
+
+	if _, err := oidc.StartLogin("unknown"); !errors.Is(err, ErrUnknownOIDCProvider) {
+		t.Errorf("unknown provider: err = %v, want ErrUnknownOIDCProvider", err)
+	}
+	if _, err := oidc.CompleteLogin(context.Background(), "unknown", start.State, callback); !errors.Is(err, ErrUnknownOIDCProvider) {
+		t.Errorf("unknown provider callback: err = %v, want ErrUnknownOIDCProvider", err)
+	}
+}
+
+func TestOIDCDenied(t *testing.T) {
+	oidc, _ := newTestOIDCService(t, true)
+	start := startOIDCLogin(t, oidc, "mock")
+	callback := authorize(t, start.AuthURL, func(q url.Values) { q.Set("deny", "1") })
+
+	_, err := oidc.CompleteLogin(context.Background(), "mock", start.State, callback)
+	if !errors.Is(err, ErrOIDCDenied) {
+		t.Fatalf("err = %v, want ErrOIDCDenied", err)
+	}
+	if redirect := oidc.AppRedirect("", err); redirect != "https://app.example.com/login?oidc_error="+url.QueryEscape(ErrOIDCDenied.Error()) {
+		t.Errorf("AppRedirect = %s", redirect)
+	}
+
+	// An error with someone else's state is a state error, not a denial
+	callback.State = "not-the-state"
+	if _, err := oidc.CompleteLogin(context.Background(), "mock", start.State, callback); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Errorf("err = %v, want ErrInvalidOIDCState", err)
+	}
+}
+
+func TestOIDCNonceAndPKCE(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(url.Values)
+		want   string
+	}{
+		// An ID token issued for another authorization request
+		{"wrong nonce", func(q url.Values) { q.Set("nonce", "replayed-nonce") }, "wrong nonce"},
+		{"no nonce", func(q url.Values) { q.Del("nonce") }, "wrong nonce"},
+		// A code intercepted from another login cannot be redeemed with this verifier
+		{"wrong code challenge", func(q url.Values) { q.Set("code_challenge", "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM") }, "code exchange"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oidc, db := newTestOIDCService(t, true)
+			start := startOIDCLogin(t, oidc, "mock")
+			callback := authorize(t, start.AuthURL, tt.change)
+
+			_, err := oidc.CompleteLogin(context.Background(), "mock", start.State, callback)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+			if n := countUsers(t, db); n != 0 {
+				t.Errorf("%d users created", n)
+			}
+		})
+	}
+}
+
+func TestOIDCAccountLinking(t *testing.T) {
+	oidc, db := newTestOIDCService(t, false)
+	complete := func(change func(url.Values)) (*model.User, error) {
+		t.Helper()
+		start := startOIDCLogin(t, oidc, "mock")
+		loginCode, err := oidc.CompleteLogin(context.Background(), "mock", start.State, authorize(t, start.AuthURL, change))
+		if err != nil {
+			return nil, err
+		}
+		return oidc.ExchangeLoginCode(loginCode)
+	}
+
+	// No account and sign-up is off
+	if _, err := complete(nil); !errors.Is(err, ErrOIDCSignupDisabled) {
+		t.Errorf("unknown email: err = %v, want ErrOIDCSignupDisabled", err)
+	}
+
+	existing := &model.User{Username: "carol", Email: "carol@example.com", Password: "hash"}
+	if err := db.Create(existing).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// An unverified email does not take over the account
+	if _, err := completeUnverified(t, oidc); !errors.Is(err, ErrOIDCEmailNotVerified) {
+		t.Errorf("unverified email: err = %v, want ErrOIDCEmailNotVerified", err)
+	}
+
+	// A verified one links to it
+	user, err := complete(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != existing.ID || user.EmailVerifiedAt == nil {
+		t.Errorf("linked user = %+v", user)
+	}
+	var identities int64
+	db.Model(&model.UserIdentity{}).Where("user_id = ? AND provider = ?", existing.ID, "mock").Count(&identities)
+	if identities != 1 {
+		t.Errorf("%d identities linked, want 1", identities)
+	}
+
+	// Disabled accounts cannot sign in
+	db.Model(existing).Update("disabled", true)
+	if _, err := complete(nil); !errors.Is(err, ErrAccountDisabled) {
+		t.Errorf("disabled account: err = %v, want ErrAccountDisabled", err)
+	}
+}
+
+// completeUnverified signs in through the mock's login form with the
+// "email verified" box unticked
+func completeUnverified(t *testing.T, oidc OIDCService) (string, error) {
+	t.Helper()
+	start := startOIDCLogin(t, oidc, "mock")
+	u, err := url.Parse(start.AuthURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	form := u.Query()
+	form.Set("login_hint", "carol@example.com")
+	u.RawQuery = ""
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.PostForm(u.String(), form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback := OIDCCallback{Code: location.Query().Get("code"), State: location.Query().Get("state")}
+	return oidc.CompleteLogin(context.Background(), "mock", start.State, callback)
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// What happens to a user's links when they delete their account
+const (
+	LinkPolicyDelete = "delete" // Links and their click history are removed
+	LinkPolicyOrphan = "orphan" // Links keep working without an owner
+)
+
+var (
+	ErrUsernameTaken     = errors.New("username already exists")
+	ErrEmailTaken        = errors.New("email is already in use")
+	ErrInvalidUsername   = errors.New("username must not be empty")
+	ErrInvalidLinkPolicy = fmt.Errorf("link_policy must be %s or %s", LinkPolicyDelete, LinkPolicyOrphan)
+	ErrPasswordRequired  = errors.New("current_password is required to change the email address")
+)
+
+// UpdateProfileInput changes the user's profile; nil fields are left unchanged
+type UpdateProfileInput struct {
+	Username        *string
+	Email           *string
+	CurrentPassword string // Required when the email changes
+}
+
+// AccountDeletion reports what deleting an account did with its links
+type AccountDeletion struct {
+	LinkPolicy string `json:"link_policy" example:"orphan"`
+	Links      int    `json:"links" example:"12"`
+}
+
+// AccountExport is everything the service stores about a user and their links
+type AccountExport struct {
+	ExportedAt time.Time   `json:"exported_at" example:"2026-10-17T12:00:00Z"`
+	Profile    model.User  `json:"profile"`
+	Links      []model.URL `json:"links"` // Including deleted links
+}
+
+// ProfileService lets signed-in users manage their own account
+type ProfileService interface {
+	GetProfile(userID uint) (*model.User, error)
+	UpdateProfile(userID uint, input UpdateProfileInput) (*model.User, error)
+	ChangePassword(userID uint, currentPassword, newPassword string) (*model.User, error)
+	DeleteAccount(userID uint, password, linkPolicy string) (*AccountDeletion, error)
+	Export(userID uint) (*AccountExport, error)
+}
+
+type profileService struct {
+	userRepo repository.UserRepository
+	urlRepo  repository.URLRepository
+	sessions TokenService
+	accounts AccountService
+}
+
+func NewProfileService(userRepo repository.UserRepository, urlRepo repository.URLRepository, sessions TokenService, accounts AccountService) ProfileService {
+	return &profileService{
+		userRepo: userRepo,
+		urlRepo:  urlRepo,
+		sessions: sessions,
+		accounts: accounts,
+	}
+}
+
+func (s *profileService) GetProfile(userID uint) (*model.User, error) {
+	return s.findUser(userID)
+}
+
+// UpdateProfile changes the username and email. A new email address has to
+// be verified again, and changing it needs the current password.
+func (s *profileService) UpdateProfile(userID uint, input UpdateProfileInput) (*model.User, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Username != nil {
+		username := strings.TrimSpace(*input.Username)
+		if username == "" {
+			return nil, ErrInvalidUsername
+		}
+		if username != user.Username {
+			if err := s.ensureFree(s.userRepo.FindByUsername, username, ErrUsernameTaken); err != nil {
+				return nil, err
+			}
+			user.Username = username
+		}
+	}
+
+	emailChanged := false
+	if input.Email != nil {
+		email := strings.TrimSpace(*input.Email)
+		if !strings.EqualFold(email, user.Email) {
+			if input.CurrentPassword == "" {
+				return nil, ErrPasswordRequired
+			}
+			if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.CurrentPassword)); err != nil {
+				return nil, ErrInvalidCredentials
+			}
+			if err := s.ensureFree(s.userRepo.FindByEmail, email, ErrEmailTaken); err != nil {
+				return nil, err
+			}
+			emailChanged = true
+		}
+		user.Email = email
+		if emailChanged {
+			user.EmailVerifiedAt = nil
+		}
+	}
+
+	err = s.userRepo.Update(user.ID, map[string]interface{}{
+		"username":          user.Username,
+		"email":             user.Email,
+		"email_verified_at": user.EmailVerifiedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if emailChanged {
+		if err := s.accounts.SendVerificationEmail(user.ID); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+		}
+	}
+	return user, nil
+}
+
+// ChangePassword sets a new password and signs the user out everywhere. The
+// caller starts a fresh session for the current client.
+func (s *profileService) ChangePassword(userID uint, currentPassword, newPassword string) (*model.User, error) {
+	if len(newPassword) < MinPasswordLength {
+		return nil, ErrWeakPassword
+	}
+
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, errors.New("failed to hash password")
+	}
+	user.Password = string(hashedPassword)
+	if err := s.userRepo.Update(user.ID, map[string]interface{}{"password": user.Password}); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.sessions.LogoutAll(user.ID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// DeleteAccount removes the user after checking their password. Links are
+// handled first, so a failed deletion can simply be retried.
+func (s *profileService) DeleteAccount(userID uint, password, linkPolicy string) (*AccountDeletion, error) {
+	if linkPolicy != LinkPolicyDelete && linkPolicy != LinkPolicyOrphan {
+		return nil, ErrInvalidLinkPolicy
+	}
+
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	var codes []string
+	if linkPolicy == LinkPolicyDelete {
+		codes, err = s.urlRepo.DeleteByUserID(user.ID)
+	} else {
+		codes, err = s.urlRepo.OrphanByUserID(user.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.Delete(user.ID); err != nil {
+		return nil, err
+	}
+
+	log.Printf("User %d deleted their account (%d link(s) %s)", user.ID, len(codes), pastTense(linkPolicy))
+	return &AccountDeletion{LinkPolicy: linkPolicy, Links: len(codes)}, nil
+}
+
+// Export returns the user's profile and all of their links
+func (s *profileService) Export(userID uint) (*AccountExport, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := s.urlRepo.ListByUserIDWithDeleted(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if links == nil {
+		links = []model.URL{}
+	}
+
+	return &AccountExport{ExportedAt: time.Now().UTC(), Profile: *user, Links: links}, nil
+}
+
+func (s *profileService) findUser(userID uint) (*model.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// ensureFree returns taken if find locates another user with the value
+func (s *profileService) ensureFree(find func(string) (*model.User, error), value string, taken error) error {
+	_, err := find(value)
+	switch {
+	case err == nil:
+		return taken
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil
+	default:
+		return err
+	}
+}
+
+func pastTense(linkPolicy string) string {
+	if linkPolicy == LinkPolicyDelete {
+		return "deleted"
+	}
+	return "orphaned"
+}
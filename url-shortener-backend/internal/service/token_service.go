@@ -0,0 +1,234 @@
+package service
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used; the session has been revoked")
+)
+
+// maxDeviceLabelLength caps client-supplied device labels
+const maxDeviceLabelLength = 100
+
+// RefreshTokenTTL is how long a refresh token stays valid
+const RefreshTokenTTL = 7 * 24 * time.Hour
+
+// TokenIssuer signs and verifies JWTs (implemented by middleware.JWTIssuer)
+type TokenIssuer interface {
+	AccessToken(userID uint, username string) (string, error)
+	RefreshToken(userID uint, username, tokenID string, expiresAt time.Time) (string, error)
+	RefreshTokenID(refreshToken string) (string, error)
+	MFAToken(userID uint, username string) (string, error)
+	MFATokenUserID(mfaToken string) (uint, error)
+}
+
+// TokenPair is the access and refresh token handed to a client after login or refresh
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// TokenService issues access tokens and manages the lifecycle of refresh tokens
+type TokenService interface {
+	IssueTokens(user *model.User, deviceLabel, userAgent string) (*TokenPair, error)
+	Refresh(refreshToken string) (*TokenPair, error)
+	Logout(refreshToken string) error
+	LogoutAll(userID uint) (int64, error)
+	PurgeExpiredTokens() (int64, error)
+}
+
+type tokenService struct {
+	repo     repository.RefreshTokenRepository
+	userRepo repository.UserRepository
+	issuer   TokenIssuer
+}
+
+func NewTokenService(repo repository.RefreshTokenRepository, userRepo repository.UserRepository, issuer TokenIssuer) TokenService {
+	return &tokenService{repo: repo, userRepo: userRepo, issuer: issuer}
+}
+
+// IssueTokens starts a new session (token family) for the user
+func (s *tokenService) IssueTokens(user *model.User, deviceLabel, userAgent string) (*TokenPair, error) {
+	familyID, err := gonanoid.New()
+	if err != nil {
+		return nil, err
+	}
+
+	if deviceLabel == "" {
+		deviceLabel = defaultDeviceLabel(userAgent)
+	}
+	if len(deviceLabel) > maxDeviceLabelLength {
+		deviceLabel = deviceLabel[:maxDeviceLabelLength]
+	}
+
+	record := &model.RefreshToken{
+		UserID:      user.ID,
+		FamilyID:    familyID,
+		DeviceLabel: deviceLabel,
+		UserAgent:   userAgent,
+	}
+	pair, err := s.newTokenPair(user, record)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(record); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Refresh rotates a refresh token. The presented token is revoked and replaced;
+// presenting a token that was already rotated revokes its whole family.
+func (s *tokenService) Refresh(refreshToken string) (*TokenPair, error) {
+	current, err := s.findRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if current.RevokedAt != nil {
+		if current.ReplacedBy != "" {
+			// A rotated token came back: it was copied, so end the session
+			return nil, s.revokeReusedFamily(current)
+		}
+		// Revoked by logout
+		return nil, ErrInvalidRefreshToken
+	}
+	if !current.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.userRepo.FindByID(current.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, ErrAccountDisabled
+	}
+
+	next := &model.RefreshToken{
+		UserID:      current.UserID,
+		FamilyID:    current.FamilyID,
+		DeviceLabel: current.DeviceLabel,
+		UserAgent:   current.UserAgent,
+	}
+	pair, err := s.newTokenPair(user, next)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated, err := s.repo.Rotate(current, next)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Another request rotated this token first
+		return nil, s.revokeReusedFamily(current)
+	}
+	return pair, nil
+}
+
+// Logout revokes the session the refresh token belongs to
+func (s *tokenService) Logout(refreshToken string) error {
+	current, err := s.findRefreshToken(refreshToken)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.repo.RevokeFamily(current.FamilyID)
+	return err
+}
+
+// LogoutAll revokes every refresh token of the user and returns how many were active
+func (s *tokenService) LogoutAll(userID uint) (int64, error) {
+	return s.repo.RevokeAllForUser(userID)
+}
+
+func (s *tokenService) PurgeExpiredTokens() (int64, error) {
+	return s.repo.PurgeExpired(time.Now())
+}
+
+// newTokenPair signs an access token and a refresh token for the record,
+// filling in the record's jti, hash and expiry
+func (s *tokenService) newTokenPair(user *model.User, record *model.RefreshToken) (*TokenPair, error) {
+	jti, err := gonanoid.New()
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.issuer.AccessToken(user.ID, user.Username)
+	if err != nil {
+		return nil, errors.New("failed to generate access token")
+	}
+
+	expiresAt := time.Now().Add(RefreshTokenTTL)
+	refreshToken, err := s.issuer.RefreshToken(user.ID, user.Username, jti, expiresAt)
+	if err != nil {
+		return nil, errors.New("failed to generate refresh token")
+	}
+
+	record.JTI = jti
+	record.TokenHash = hashToken(refreshToken)
+	record.ExpiresAt = expiresAt
+	return &TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// findRefreshToken verifies a refresh token and loads its stored record.
+// Tokens without a jti, such as access tokens, are rejected.
+func (s *tokenService) findRefreshToken(refreshToken string) (*model.RefreshToken, error) {
+	jti, err := s.issuer.RefreshTokenID(refreshToken)
+	if err != nil || jti == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	record, err := s.repo.FindByJTI(jti)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(record.TokenHash), []byte(hashToken(refreshToken))) != 1 {
+		return nil, ErrInvalidRefreshToken
+	}
+	return record, nil
+}
+
+func (s *tokenService) revokeReusedFamily(token *model.RefreshToken) error {
+	revoked, err := s.repo.RevokeFamily(token.FamilyID)
+	if err != nil {
+		return err
+	}
+	log.Printf("Refresh token reuse detected for user %d (family %s), revoked %d token(s)", token.UserID, token.FamilyID, revoked)
+	return ErrRefreshTokenReused
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// defaultDeviceLabel describes the client from its User-Agent, e.g. "Chrome on macOS"
+func defaultDeviceLabel(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+	info := parseUserAgent(userAgent)
+	return info.Browser + " on " + info.OS
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// fakeIssuer signs nothing: a refresh token is "refresh:<jti>:<expiry>", so
+// two tokens with the same jti still differ, and an MFA token is "mfa:<user ID>"
+type fakeIssuer struct{}
+
+func (fakeIssuer) AccessToken(userID uint, _ string) (string, error) {
+	return "access:" + strconv.FormatUint(uint64(userID), 10), nil
+}
+
+func (fakeIssuer) RefreshToken(_ uint, _, tokenID string, expiresAt time.Time) (string, error) {
+	return "refresh:" + tokenID + ":" + strconv.FormatInt(expiresAt.UnixNano(), 10), nil
+}
+
+func (fakeIssuer) RefreshTokenID(refreshToken string) (string, error) {
+	parts := strings.Split(refreshToken, ":")
+	if len(parts) != 3 || parts[0] != "refresh" {
+		return "", errors.New("not a refresh token")
+	}
+	return parts[1], nil
+}
+
+func (fakeIssuer) MFAToken(userID uint, _ string) (string, error) {
+	return "mfa:" + strconv.FormatUint(uint64(userID), 10), nil
+}
+
+func (fakeIssuer) MFATokenUserID(mfaToken string) (uint, error) {
+	id, err := strconv.ParseUint(strings.TrimPrefix(mfaToken, "mfa:"), 10, 64)
+	if err != nil || !strings.HasPrefix(mfaToken, "mfa:") {
+		return 0, errors.New("not an MFA token")
+	}
+	return uint(id), nil
+}
+
+func newTestTokenService(t *testing.T) (TokenService, *gorm.DB, *model.User) {
+	t.Helper()
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&model.User{}, &model.RefreshToken{}); err != nil {
+		t.Fatal(err)
+	}
+	user := &model.User{Username: "alice", Email: "alice@example.com", Password: "hash"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	tokens := NewTokenService(repository.NewRefreshTokenRepository(db), repository.NewUserRepository(db), fakeIssuer{})
+	return tokens, db, user
+}
+
+// activeTokens counts the unrevoked refresh tokens of a family
+func activeTokens(t *testing.T, db *gorm.DB, familyID string) int64 {
+	t.Helper()
+	var n int64
+	if err := db.Model(&model.RefreshToken{}).Where("family_id = ? AND revoked_at IS NULL", familyID).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func familyOf(t *testing.T, db *gorm.DB, refreshToken string) string {
+	t.Helper()
+	jti, err := fakeIssuer{}.RefreshTokenID(refreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record model.RefreshToken
+	if err := db.Where("jti = ?", jti).First(&record).Error; err != nil {
+		t.Fatal(err)
+	}
+	return record.FamilyID
+}
+
+func TestIssueTokens(t *testing.T) {
+	tokens, db, user := newTestTokenService(t)
+
+	pair, err := tokens.IssueTokens(user, "", "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair.AccessToken != "access:1" {
+		t.Errorf("AccessToken = %q", pair.AccessToken)
+	}
+
+	var record model.RefreshToken
+	if err := db.First(&record).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Only a hash of the token is stored
+	if record.TokenHash != hashToken(pair.RefreshToken) {
+		t.Errorf("stored hash %q does not match the token", record.TokenHash)
+	}
+	if record.DeviceLabel != "Chrome on macOS" {
+		t.Errorf("DeviceLabel = %q, want it taken from the User-Agent", record.DeviceLabel)
+	}
+	if got := time.Until(record.ExpiresAt); got < RefreshTokenTTL-time.Minute || got > RefreshTokenTTL {
+		t.Errorf("expires in %s, want %s", got, RefreshTokenTTL)
+	}
+
+	// Every login is its own session; long labels are cut
+	other, err := tokens.IssueTokens(user, strings.Repeat("x", 2*maxDeviceLabelLength), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if familyOf(t, db, other.RefreshToken) == record.FamilyID {
+		t.Error("two logins share a session")
+	}
+	var labelled model.RefreshToken
+	db.Where("family_id = ?", familyOf(t, db, other.RefreshToken)).First(&labelled)
+	if len(labelled.DeviceLabel) != maxDeviceLabelLength {
+		t.Errorf("device label is %d characters, want %d", len(labelled.DeviceLabel), maxDeviceLabelLength)
+	}
+}
+
+func TestRefreshRotation(t *testing.T) {
+	tokens, db, user := newTestTokenService(t)
+	first, err := tokens.IssueTokens(user, "laptop", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	family := familyOf(t, db, first.RefreshToken)
+
+	second, err := tokens.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("refresh returned the same token")
+	}
+	if familyOf(t, db, second.RefreshToken) != family {
+		t.Error("rotated token is in another session")
+	}
+	if n := activeTokens(t, db, family); n != 1 {
+		t.Errorf("%d active tokens after a rotation, want 1", n)
+	}
+
+	// The old token records its replacement
+	jti, _ := fakeIssuer{}.RefreshTokenID(first.RefreshToken)
+	nextJTI, _ := fakeIssuer{}.RefreshTokenID(second.RefreshToken)
+	var old model.RefreshToken
+	db.Where("jti = ?", jti).First(&old)
+	if old.RevokedAt == nil || old.ReplacedBy != nextJTI {
+		t.Errorf("old token: RevokedAt %v, ReplacedBy %q", old.RevokedAt, old.ReplacedBy)
+	}
+
+	// The new one rotates again
+	if _, err := tokens.Refresh(second.RefreshToken); err != nil {
+		t.Errorf("second rotation: %v", err)
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	tokens, db, user := newTestTokenService(t)
+	stolen, err := tokens.IssueTokens(user, "laptop", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := tokens.IssueTokens(user, "phone", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	family := familyOf(t, db, stolen.RefreshToken)
+
+	current, err := tokens.Refresh(stolen.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The rotated token comes back: the whole session ends
+	if _, err := tokens.Refresh(stolen.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reused token: err = %v, want ErrRefreshTokenReused", err)
+	}
+	if n := activeTokens(t, db, family); n != 0 {
+		t.Errorf("%d active tokens left in the session, want 0", n)
+	}
+	if _, err := tokens.Refresh(current.RefreshToken); err == nil {
+		t.Error("latest token of a revoked session still refreshes")
+	}
+
+	// Other sessions of the user are not affected
+	if _, err := tokens.Refresh(other.RefreshToken); err != nil {
+		t.Errorf("other session: %v", err)
+	}
+}
+
+func TestRefreshRejects(t *testing.T) {
+	tokens, db, user := newTestTokenService(t)
+	pair, err := tokens.IssueTokens(user, "laptop", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jti, _ := fakeIssuer{}.RefreshTokenID(pair.RefreshToken)
+
+	// A known jti with another token body, an access token, an unknown jti, nothing
+	for _, token := range []string{"refresh:" + jti + ":0", pair.AccessToken, "refresh:unknown:0", ""} {
+		if _, err := tokens.Refresh(token); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("Refresh(%q): err = %v, want ErrInvalidRefreshToken", token, err)
+		}
+	}
+	// A forged token must not revoke the real one
+	if n := activeTokens(t, db, familyOf(t, db, pair.RefreshToken)); n != 1 {
+		t.Errorf("%d active tokens after forged refreshes, want 1", n)
+	}
+
+	// Disabled users cannot refresh
+	db.Model(user).Update("disabled", true)
+	if _, err := tokens.Refresh(pair.RefreshToken); !errors.Is(err, ErrAccountDisabled) {
+		t.Errorf("disabled user: err = %v, want ErrAccountDisabled", err)
+	}
+	db.Model(user).Update("disabled", false)
+
+	// Expired tokens are refused
+	db.Model(&model.RefreshToken{}).Where("jti = ?", jti).Update("expires_at", time.Now().Add(-time.Minute))
+	if _, err := tokens.Refresh(pair.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("expired token: err = %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	tokens, db, user := newTestTokenService(t)
+	laptop, err := tokens.IssueTokens(user, "laptop", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	phone, err := tokens.IssueTokens(user, "phone", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := tokens.Refresh(laptop.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Logging out with an older token of the session ends the whole session
+	if err := tokens.Logout(laptop.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if n := activeTokens(t, db, familyOf(t, db, laptop.RefreshToken)); n != 0 {
+		t.Errorf("%d active tokens after logout, want 0", n)
+	}
+	// A token revoked by logout is invalid, not reuse
+	if _, err := tokens.Refresh(rotated.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("refresh after logout: err = %v, want ErrInvalidRefreshToken", err)
+	}
+	if err := tokens.Logout("refresh:unknown:0"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("logout with an unknown token: err = %v, want ErrInvalidRefreshToken", err)
+	}
+
+	// Logging out everywhere ends the other sessions
+	second, err := tokens.IssueTokens(user, "tablet", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := tokens.LogoutAll(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked != 2 {
+		t.Errorf("LogoutAll revoked %d tokens, want 2", revoked)
+	}
+	for _, pair := range []*TokenPair{phone, second} {
+		if _, err := tokens.Refresh(pair.RefreshToken); err == nil {
+			t.Error("session still refreshes after LogoutAll")
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"url-shortener/internal/linkfile"
+	"url-shortener/internal/model"
+)
+
+// How a batch create treats items that fail
+const (
+	BatchModeAtomic  = "atomic"  // Nothing is created unless every item is valid
+	BatchModePartial = "partial" // Valid items are created, failed ones are reported
+)
+
+var (
+	ErrInvalidBatch   = errors.New("invalid batch")
+	ErrDuplicateAlias = errors.New("custom alias is used more than once in the batch")
+	ErrBatchAborted   = errors.New("not created because another item in the batch failed")
+)
+
+// ErrorCodeInternal is the code of errors not caused by the link itself
+const ErrorCodeInternal = "internal_error"
+
+// LinkErrorCode gives clients a stable code for why a link in a batch or an
+// import was not created
+func LinkErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidURL):
+		return "invalid_url"
+	case errors.Is(err, ErrInvalidAlias):
+		return "invalid_alias"
+	case errors.Is(err, ErrAliasReserved):
+		return "alias_reserved"
+	case errors.Is(err, ErrAliasTaken):
+		return "alias_taken"
+	case errors.Is(err, ErrDuplicateAlias):
+		return "duplicate_alias"
+	case errors.Is(err, ErrInvalidExpiry):
+		return "invalid_expiry"
+	case errors.Is(err, ErrInvalidRedirectType):
+		return "invalid_redirect_type"
+	case errors.Is(err, ErrInvalidTags):
+		return "invalid_tags"
+	case errors.Is(err, ErrBatchAborted):
+		return "batch_aborted"
+	case errors.Is(err, ErrImportPasswordMissing):
+		return "password_required"
+	case errors.Is(err, linkfile.ErrInvalidRecord):
+		return "invalid_row"
+	default:
+		return ErrorCodeInternal
+	}
+}
+
+// BatchItemResult is the outcome of one item of a batch create, in request order
+type BatchItemResult struct {
+	Index int
+	URL   *model.URL // Set when the link was created
+	Err   error      // Set when it was not
+}
+
+// CreateShortURLs creates several links for a user. In atomic mode the links
+// are inserted in one transaction and a single failed item fails them all;
+// in partial mode each valid item is created on its own. An error is only
+// returned for problems with the batch as a whole.
+func (s *urlService) CreateShortURLs(userID uint, items []CreateURLInput, mode string) ([]BatchItemResult, error) {
+	if mode == "" {
+		mode = BatchModeAtomic
+	}
+	if mode != BatchModeAtomic && mode != BatchModePartial {
+		return nil, fmt.Errorf("%w: mode must be %s or %s", ErrInvalidBatch, BatchModeAtomic, BatchModePartial)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%w: items must not be empty", ErrInvalidBatch)
+	}
+	if len(items) > s.cfg.BatchMaxItems {
+		return nil, fmt.Errorf("%w: at most %d items can be created at once", ErrInvalidBatch, s.cfg.BatchMaxItems)
+	}
+
+	results := make([]BatchItemResult, len(items))
+	pending := make(map[string]bool, len(items))
+	failed := false
+	for i, input := range items {
+		input.UserID = &userID
+		input.AnonymousID = nil
+
+		results[i].Index = i
+		urlEntry, err := s.prepareURL(input, pending)
+		if err != nil {
+			results[i].Err = err
+			failed = true
+			continue
+		}
+		pending[urlEntry.ShortCode] = true
+
+		if mode == BatchModePartial {
+			if err := s.repo.Create(urlEntry); err != nil {
+				results[i].Err = s.insertError(urlEntry, input.CustomAlias, err)
+				failed = true
+				continue
+			}
+		}
+		results[i].URL = urlEntry
+	}
+
+	if mode == BatchModePartial {
+		return results, nil
+	}
+	if failed {
+		abortBatch(results)
+		return results, nil
+	}
+	return s.insertBatch(items, results)
+}
+
+// insertBatch saves the prepared links of an atomic batch in one transaction
+func (s *urlService) insertBatch(items []CreateURLInput, results []BatchItemResult) ([]BatchItemResult, error) {
+	urls := make([]*model.URL, len(results))
+	for i := range results {
+		urls[i] = results[i].URL
+	}
+	err := s.repo.CreateBatch(urls)
+	if err == nil {
+		return results, nil
+	}
+
+	// Report aliases other requests took since they were checked
+	taken := false
+	for i := range results {
+		if errors.Is(s.insertError(results[i].URL, items[i].CustomAlias, err), ErrAliasTaken) {
+			results[i].Err = ErrAliasTaken
+			taken = true
+		}
+	}
+	if !taken {
+		return nil, err
+	}
+	abortBatch(results)
+	return results, nil
+}
+
+// abortBatch drops the unsaved links of a failed atomic batch and marks the
+// items that had no error of their own
+func abortBatch(results []BatchItemResult) {
+	for i := range results {
+		if results[i].Err == nil {
+			results[i].Err = ErrBatchAborted
+		}
+		results[i].URL = nil
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"url-shortener/config"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// racingRepo lets another request take the last link's short code between
+// the batch's checks and its insert
+type racingRepo struct {
+	repository.URLRepository
+	db *gorm.DB
+}
+
+func (r racingRepo) CreateBatch(urls []*model.URL) error {
+	other := uint(2)
+	last := urls[len(urls)-1]
+	if err := r.db.Create(&model.URL{ShortCode: last.ShortCode, OriginalURL: "https://example.com/other", UserID: &other}).Error; err != nil {
+		return err
+	}
+	return r.URLRepository.CreateBatch(urls)
+}
+
+func newBatchTest(t *testing.T, racing bool) (URLService, *gorm.DB) {
+	t.Helper()
+	db := newTestDB(t)
+	var repo repository.URLRepository = repository.NewURLRepository(db.Session(&gorm.Session{CreateBatchSize: 1}))
+	if racing {
+		repo = racingRepo{URLRepository: repo, db: db}
+	}
+	cfg := config.LoadURLConfig()
+	cfg.BatchMaxItems = 3
+	urls := NewURLService(repo, repository.NewLinkClaimRepository(db), cfg, testAnonymousConfig(), noClicks{})
+	return urls, db
+}
+
+// linksOf returns the short codes of the user's links
+func linksOf(t *testing.T, db *gorm.DB, userID uint) []string {
+	t.Helper()
+	var codes []string
+	if err := db.Model(&model.URL{}).Where("user_id = ?", userID).Order("id").Pluck("short_code", &codes).Error; err != nil {
+		t.Fatal(err)
+	}
+	return codes
+}
+
+func errorCodes(results []BatchItemResult) []string {
+	codes := make([]string, len(results))
+	for i, result := range results {
+		if result.Err != nil {
+			codes[i] = LinkErrorCode(result.Err)
+		}
+	}
+	return codes
+}
+
+func TestCreateShortURLsModes(t *testing.T) {
+	items := []CreateURLInput{
+		{OriginalURL: "https://example.com/1", CustomAlias: "one"},
+		{OriginalURL: "not a url"},
+		{OriginalURL: "https://example.com/3", CustomAlias: "one"},
+	}
+
+	tests := []struct {
+		mode      string
+		wantCodes []string
+		wantLinks []string
+	}{
+		{"", []string{"batch_aborted", "invalid_url", "duplicate_alias"}, nil},
+		{BatchModeAtomic, []string{"batch_aborted", "invalid_url", "duplicate_alias"}, nil},
+		{BatchModePartial, []string{"", "invalid_url", "duplicate_alias"}, []string{"one"}},
+	}
+	for _, tt := range tests {
+		t.Run("mode="+tt.mode, func(t *testing.T) {
+			urls, db := newBatchTest(t, false)
+			results, err := urls.CreateShortURLs(1, items, tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := errorCodes(results); !slices.Equal(got, tt.wantCodes) {
+				t.Errorf("error codes %q, want %q", got, tt.wantCodes)
+			}
+			for i, result := range results {
+				if result.Index != i {
+					t.Errorf("result %d has index %d", i, result.Index)
+				}
+			}
+			if got := linksOf(t, db, 1); !slices.Equal(got, tt.wantLinks) {
+				t.Errorf("links %v, want %v", got, tt.wantLinks)
+			}
+		})
+	}
+}
+
+func TestCreateShortURLsInvalidBatch(t *testing.T) {
+	urls, db := newBatchTest(t, false)
+	item := CreateURLInput{OriginalURL: "https://example.com"}
+
+	for name, call := range map[string]func() ([]BatchItemResult, error){
+		"unknown mode": func() ([]BatchItemResult, error) { return urls.CreateShortURLs(1, []CreateURLInput{item}, "best-effort") },
+		"no items":     func() ([]BatchItemResult, error) { return urls.CreateShortURLs(1, nil, "") },
+		"too many":     func() ([]BatchItemResult, error) { return urls.CreateShortURLs(1, []CreateURLInput{item, item, item, item}, "") },
+	} {
+		if _, err := call(); !errors.Is(err, ErrInvalidBatch) {
+			t.Errorf("%s: err = %v, want ErrInvalidBatch", name, err)
+		}
+	}
+	if links := linksOf(t, db, 1); len(links) != 0 {
+		t.Errorf("links %v created by refused batches", links)
+	}
+}
+
+func TestCreateShortURLsAtomicRollback(t *testing.T) {
+	t.Run("alias taken during the insert", func(t *testing.T) {
+		urls, db := newBatchTest(t, true)
+		results, err := urls.CreateShortURLs(1, []CreateURLInput{
+			{OriginalURL: "https://example.com/1"},
+			{OriginalURL: "https://example.com/2", CustomAlias: "two"},
+			{OriginalURL: "https://example.com/3", CustomAlias: "three"},
+		}, BatchModeAtomic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"batch_aborted", "batch_aborted", "alias_taken"}
+		if got := errorCodes(results); !slices.Equal(got, want) {
+			t.Errorf("error codes %q, want %q", got, want)
+		}
+		for _, result := range results {
+			if result.URL != nil {
+				t.Errorf("item %d reports the unsaved link %s", result.Index, result.URL.ShortCode)
+			}
+		}
+		if links := linksOf(t, db, 1); len(links) != 0 {
+			t.Errorf("links %v kept from a rolled back batch", links)
+		}
+	})
+
+	t.Run("generated code taken during the insert", func(t *testing.T) {
+		urls, db := newBatchTest(t, true)
+		results, err := urls.CreateShortURLs(1, []CreateURLInput{
+			{OriginalURL: "https://example.com/1", CustomAlias: "one"},
+			{OriginalURL: "https://example.com/2"},
+		}, BatchModeAtomic)
+		if err == nil {
+			t.Fatalf("results %q, want an error", errorCodes(results))
+		}
+		if links := linksOf(t, db, 1); len(links) != 0 {
+			t.Errorf("links %v kept from a rolled back batch", links)
+		}
+	})
+
+	t.Run("partial mode is not rolled back", func(t *testing.T) {
+		urls, db := newBatchTest(t, false)
+		if _, err := urls.CreateShortURL(CreateURLInput{OriginalURL: "https://example.com/old", CustomAlias: "two"}); err != nil {
+			t.Fatal(err)
+		}
+		results, err := urls.CreateShortURLs(1, []CreateURLInput{
+			{OriginalURL: "https://example.com/1", CustomAlias: "one"},
+			{OriginalURL: "https://example.com/2", CustomAlias: "two"},
+		}, BatchModePartial)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := errorCodes(results), []string{"", "alias_taken"}; !slices.Equal(got, want) {
+			t.Errorf("error codes %q, want %q", got, want)
+		}
+		if links := linksOf(t, db, 1); !slices.Equal(links, []string{"one"}) {
+			t.Errorf("links %v, want [one]", links)
+		}
+	})
+}
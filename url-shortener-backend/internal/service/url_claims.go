@@ -0,0 +1,156 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+)
+
+var ErrInvalidClaim = errors.New("invalid claim")
+
+// ClaimInput asks to move links from an anonymous identity to a user
+type ClaimInput struct {
+	UserID      uint
+	AnonymousID string
+	ShortCodes  []string // Optional - every claimable link when empty
+	ClientIP    string   // Recorded in the audit trail
+}
+
+// ClaimPreview lists the links an anonymous identity can hand over
+type ClaimPreview struct {
+	Count int         `json:"count" example:"3"`
+	Links []model.URL `json:"links"`
+}
+
+// ClaimResult reports what a claim did. Repeating a claim is safe: links
+// moved the first time show up in AlreadyClaimed.
+type ClaimResult struct {
+	Claimed        int         `json:"claimed" example:"2"`
+	Links          []model.URL `json:"links"`                              // The links claimed by this request
+	AlreadyClaimed []string    `json:"already_claimed" example:"abc12345"` // Requested codes this user claimed before
+	NotClaimable   []string    `json:"not_claimable" example:"xyz67890"`   // Requested codes that do not belong to the anonymous identity
+}
+
+// LinkClaimQuery selects a page of the claim audit trail
+type LinkClaimQuery struct {
+	UserID      uint
+	AnonymousID string
+	ShortCode   string
+	Limit       int // 0 uses DefaultPageSize
+	Offset      int
+}
+
+// LinkClaimPage is one page of the claim audit trail
+type LinkClaimPage struct {
+	Total  int64             `json:"total" example:"12"`
+	Claims []model.LinkClaim `json:"claims"`
+}
+
+// PreviewClaim lists the unclaimed links of an anonymous identity
+func (s *urlService) PreviewClaim(anonymousID string) (*ClaimPreview, error) {
+	links, err := s.repo.ListByAnonymousID(anonymousID)
+	if err != nil {
+		return nil, err
+	}
+	if links == nil {
+		links = []model.URL{}
+	}
+	return &ClaimPreview{Count: len(links), Links: links}, nil
+}
+
+// ClaimAnonymousURLs moves the chosen links (or all of them) from the
+// anonymous identity to the user in one transaction and records each claim
+func (s *urlService) ClaimAnonymousURLs(input ClaimInput) (*ClaimResult, error) {
+	codes := uniqueCodes(input.ShortCodes)
+	if len(codes) > MaxPageSize {
+		return nil, fmt.Errorf("%w: at most %d short codes per request", ErrInvalidClaim, MaxPageSize)
+	}
+
+	links, err := s.repo.ClaimAnonymousURLs(input.UserID, input.AnonymousID, codes, input.ClientIP)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ClaimResult{
+		Claimed:        len(links),
+		Links:          links,
+		AlreadyClaimed: []string{},
+		NotClaimable:   []string{},
+	}
+
+	// Sort the requested codes that were not claimed now into repeats and strangers
+	if len(codes) > 0 {
+		claimedNow := make(map[string]bool, len(links))
+		for _, link := range links {
+			claimedNow[link.ShortCode] = true
+		}
+		var rest []string
+		for _, code := range codes {
+			if !claimedNow[code] {
+				rest = append(rest, code)
+			}
+		}
+
+		before, err := s.claims.ClaimedCodes(input.UserID, input.AnonymousID, rest)
+		if err != nil {
+			return nil, err
+		}
+		claimedBefore := make(map[string]bool, len(before))
+		for _, code := range before {
+			claimedBefore[code] = true
+		}
+		for _, code := range rest {
+			if claimedBefore[code] {
+				result.AlreadyClaimed = append(result.AlreadyClaimed, code)
+			} else {
+				result.NotClaimable = append(result.NotClaimable, code)
+			}
+		}
+	}
+
+	if result.Claimed > 0 {
+		log.Printf("User %d claimed %d link(s) from %s", input.UserID, result.Claimed, input.AnonymousID)
+	}
+	return result, nil
+}
+
+// ListLinkClaims returns a page of the claim audit trail, newest first
+func (s *urlService) ListLinkClaims(query LinkClaimQuery) (*LinkClaimPage, error) {
+	switch {
+	case query.Limit == 0:
+		query.Limit = DefaultPageSize
+	case query.Limit < 0 || query.Limit > MaxPageSize:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidListQuery, MaxPageSize)
+	}
+	if query.Offset < 0 {
+		return nil, fmt.Errorf("%w: offset must not be negative", ErrInvalidListQuery)
+	}
+
+	claims, total, err := s.claims.List(repository.LinkClaimFilter{
+		UserID:      query.UserID,
+		AnonymousID: query.AnonymousID,
+		ShortCode:   query.ShortCode,
+	}, query.Limit, query.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return &LinkClaimPage{Total: total, Claims: claims}, nil
+}
+
+// uniqueCodes trims the codes and drops blanks and duplicates, keeping the order
+func uniqueCodes(codes []string) []string {
+	seen := make(map[string]bool, len(codes))
+	var unique []string
+	for _, code := range codes {
+		code = strings.TrimSpace(code)
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		unique = append(unique, code)
+	}
+	return unique
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"url-shortener/internal/model"
+)
+
+// createClaimableLinks creates n anonymous links of the identity and returns their codes
+func createClaimableLinks(t *testing.T, urls URLService, anonymousID string, n int) []string {
+	t.Helper()
+	var codes []string
+	for i := 0; i < n; i++ {
+		link, err := createAnonymousLink(urls, anonymousID, "10.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		codes = append(codes, link.ShortCode)
+	}
+	return codes
+}
+
+func TestPreviewClaim(t *testing.T) {
+	anon := testAnonymousConfig()
+	anon.MaxLinksPerIP = 0
+	urls, _ := newTestURLService(t, anon)
+	codes := createClaimableLinks(t, urls, "anon_a", 2)
+	createClaimableLinks(t, urls, "anon_b", 1)
+
+	preview, err := urls.PreviewClaim("anon_a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Count != 2 || len(preview.Links) != 2 {
+		t.Fatalf("preview = %d links, want 2", preview.Count)
+	}
+
+	// Claimed links drop out of the preview
+	if _, err := urls.ClaimAnonymousURLs(ClaimInput{UserID: 1, AnonymousID: "anon_a", ShortCodes: codes[:1]}); err != nil {
+		t.Fatal(err)
+	}
+	preview, err = urls.PreviewClaim("anon_a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Count != 1 || preview.Links[0].ShortCode != codes[1] {
+		t.Errorf("preview after a claim = %+v, want only %s", preview.Links, codes[1])
+	}
+
+	// An identity without links gets an empty list, not null
+	preview, err = urls.PreviewClaim("anon_none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Count != 0 || preview.Links == nil {
+		t.Errorf("empty preview = %+v", preview)
+	}
+}
+
+func TestClaimAnonymousURLs(t *testing.T) {
+	anon := testAnonymousConfig()
+	anon.MaxLinksPerID = 3
+	anon.MaxLinksPerIP = 0
+	urls, db := newTestURLService(t, anon)
+	codes := createClaimableLinks(t, urls, "anon_a", 3)
+	other := createClaimableLinks(t, urls, "anon_b", 1)
+
+	// A subset: the other links stay anonymous
+	result, err := urls.ClaimAnonymousURLs(ClaimInput{
+		UserID:      1,
+		AnonymousID: "anon_a",
+		ShortCodes:  []string{codes[0], " " + codes[1] + " ", codes[0], other[0], "missing"},
+		ClientIP:    "10.0.0.9",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Claimed != 2 || len(result.Links) != 2 {
+		t.Fatalf("claimed %d links, want 2", result.Claimed)
+	}
+	for _, link := range result.Links {
+		if link.UserID == nil || *link.UserID != 1 || link.ExpiresAt != nil || link.AnonymousTTL {
+			t.Errorf("claimed link %s: UserID %v, ExpiresAt %v, AnonymousTTL %v", link.ShortCode, link.UserID, link.ExpiresAt, link.AnonymousTTL)
+		}
+	}
+	if len(result.NotClaimable) != 2 || result.NotClaimable[0] != other[0] || result.NotClaimable[1] != "missing" {
+		t.Errorf("NotClaimable = %v, want [%s missing]", result.NotClaimable, other[0])
+	}
+
+	var unclaimed model.URL
+	if err := db.Where("short_code = ?", codes[2]).First(&unclaimed).Error; err != nil {
+		t.Fatal(err)
+	}
+	if unclaimed.UserID != nil {
+		t.Errorf("link outside the subset was claimed")
+	}
+
+	// Repeating the claim changes nothing and reports the links as already claimed
+	result, err = urls.ClaimAnonymousURLs(ClaimInput{UserID: 1, AnonymousID: "anon_a", ShortCodes: codes[:2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Claimed != 0 || len(result.AlreadyClaimed) != 2 || len(result.NotClaimable) != 0 {
+		t.Errorf("repeated claim = %+v", result)
+	}
+
+	// Another user cannot take them over
+	result, err = urls.ClaimAnonymousURLs(ClaimInput{UserID: 2, AnonymousID: "anon_a", ShortCodes: codes[:2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Claimed != 0 || len(result.NotClaimable) != 2 {
+		t.Errorf("claim by another user = %+v", result)
+	}
+
+	// Without codes the rest is claimed
+	result, err = urls.ClaimAnonymousURLs(ClaimInput{UserID: 1, AnonymousID: "anon_a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Claimed != 1 || result.Links[0].ShortCode != codes[2] {
+		t.Errorf("claim all = %+v", result)
+	}
+
+	// Claiming does not give the creation quota back
+	if _, err := createAnonymousLink(urls, "anon_a", "10.0.0.1"); !errors.Is(err, ErrAnonymousQuotaExceeded) {
+		t.Errorf("after claiming: err = %v, want ErrAnonymousQuotaExceeded", err)
+	}
+
+	// Too many codes in one request
+	tooMany := make([]string, MaxPageSize+1)
+	for i := range tooMany {
+		tooMany[i] = "code" + strconv.Itoa(i)
+	}
+	if _, err := urls.ClaimAnonymousURLs(ClaimInput{UserID: 1, AnonymousID: "anon_a", ShortCodes: tooMany}); !errors.Is(err, ErrInvalidClaim) {
+		t.Errorf("too many codes: err = %v, want ErrInvalidClaim", err)
+	}
+}
+
+func TestClaimAuditTrail(t *testing.T) {
+	anon := testAnonymousConfig()
+	anon.MaxLinksPerIP = 0
+	urls, _ := newTestURLService(t, anon)
+	codes := createClaimableLinks(t, urls, "anon_a", 2)
+
+	if _, err := urls.ClaimAnonymousURLs(ClaimInput{UserID: 1, AnonymousID: "anon_a", ClientIP: "10.0.0.9"}); err != nil {
+		t.Fatal(err)
+	}
+	// A repeated claim records nothing
+	if _, err := urls.ClaimAnonymousURLs(ClaimInput{UserID: 1, AnonymousID: "anon_a", ShortCodes: codes}); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := urls.ListLinkClaims(LinkClaimQuery{UserID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 || len(page.Claims) != 2 {
+		t.Fatalf("audit trail has %d claims, want 2", page.Total)
+	}
+	seen := map[string]bool{}
+	for _, claim := range page.Claims {
+		if claim.UserID != 1 || claim.AnonymousID != "anon_a" || claim.ClientIP != "10.0.0.9" {
+			t.Errorf("claim = %+v", claim)
+		}
+		seen[claim.ShortCode] = true
+	}
+	if !seen[codes[0]] || !seen[codes[1]] {
+		t.Errorf("audit trail codes = %v, want %v", seen, codes)
+	}
+
+	page, err = urls.ListLinkClaims(LinkClaimQuery{ShortCode: codes[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 {
+		t.Errorf("claims of %s = %d, want 1", codes[0], page.Total)
+	}
+
+	if _, err := urls.ListLinkClaims(LinkClaimQuery{Limit: MaxPageSize + 1}); !errors.Is(err, ErrInvalidListQuery) {
+		t.Errorf("oversized page: err = %v, want ErrInvalidListQuery", err)
+	}
+}
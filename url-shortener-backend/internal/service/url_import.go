@@ -0,0 +1,394 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+	"url-shortener/config"
+	"url-shortener/internal/linkfile"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// What an import does with a row whose short code is taken or not allowed here
+const (
+	ImportConflictSkip   = "skip"   // The row is reported as a conflict and not imported
+	ImportConflictRename = "rename" // The link is created with a new random code
+)
+
+// importProgressRows is how often a running job saves its progress
+const importProgressRows = 200
+
+var (
+	ErrInvalidImport         = errors.New("invalid import")
+	ErrImportTooLarge        = errors.New("import file is too large")
+	ErrImportLimit           = errors.New("too many imports in progress")
+	ErrImportNotFound        = errors.New("import not found")
+	ErrImportPasswordMissing = errors.New("link was password protected; add a password column to import it")
+)
+
+// ImportURLInput is one link read from an import file
+type ImportURLInput struct {
+	CreateURLInput
+	ShortCode string // Kept when free; a random code is generated when empty
+	Clicks    int64
+	Disabled  bool
+	CreatedAt *time.Time
+	Rename    bool // Use a random code when ShortCode is taken or not allowed
+}
+
+// ImportInput starts an import of a user's file
+type ImportInput struct {
+	UserID     uint
+	Format     string // csv or ndjson
+	OnConflict string // skip (the default) or rename
+	File       io.Reader
+}
+
+// ImportService imports files of links as background jobs. Jobs run inside
+// the server process; FailInterrupted marks the ones a restart cut short.
+type ImportService interface {
+	StartImport(input ImportInput) (*model.ImportJob, error)
+	GetImport(id, userID uint) (*model.ImportJob, error)
+	FailInterrupted() (int64, error)
+}
+
+type importService struct {
+	jobs  repository.ImportJobRepository
+	urls  URLService
+	cfg   config.ImportConfig
+	slots chan struct{}
+
+	// Unfinished jobs, counted from upload to the end of the run; each keeps
+	// its file on disk and a goroutine waiting for a slot
+	mu          sync.Mutex
+	pending     map[uint]int
+	pendingJobs int
+}
+
+func NewImportService(jobs repository.ImportJobRepository, urls URLService, cfg config.ImportConfig) ImportService {
+	return &importService{
+		jobs:    jobs,
+		urls:    urls,
+		cfg:     cfg,
+		slots:   make(chan struct{}, cfg.Workers),
+		pending: make(map[uint]int),
+	}
+}
+
+// ImportShortURL creates an imported link, keeping its short code, click
+// count and creation time
+func (s *urlService) ImportShortURL(input ImportURLInput) (*model.URL, error) {
+	create := input.CreateURLInput
+	create.CustomAlias = input.ShortCode
+
+	urlEntry, err := s.prepareURL(create, nil)
+	if err != nil && input.Rename && create.CustomAlias != "" &&
+		(errors.Is(err, ErrAliasTaken) || errors.Is(err, ErrInvalidAlias) || errors.Is(err, ErrAliasReserved)) {
+		create.CustomAlias = ""
+		urlEntry, err = s.prepareURL(create, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Clicks > 0 {
+		urlEntry.Clicks = input.Clicks
+	}
+	urlEntry.Disabled = input.Disabled
+	if input.CreatedAt != nil {
+		urlEntry.CreatedAt = *input.CreatedAt
+	}
+
+	if err := s.repo.Create(urlEntry); err != nil {
+		return nil, s.insertError(urlEntry, create.CustomAlias, err)
+	}
+	return urlEntry, nil
+}
+
+// StartImport saves the uploaded file and queues a job to import it
+func (s *importService) StartImport(input ImportInput) (*model.ImportJob, error) {
+	if input.OnConflict == "" {
+		input.OnConflict = ImportConflictSkip
+	}
+	if input.OnConflict != ImportConflictSkip && input.OnConflict != ImportConflictRename {
+		return nil, fmt.Errorf("%w: on_conflict must be %s or %s", ErrInvalidImport, ImportConflictSkip, ImportConflictRename)
+	}
+	format, err := linkfile.ParseFormat(input.Format)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	// Reserve before reading the upload, so refused files never reach the disk
+	if err := s.reserve(input.UserID); err != nil {
+		return nil, err
+	}
+
+	path, size, err := s.saveUpload(input.File)
+	if err != nil {
+		s.release(input.UserID)
+		return nil, err
+	}
+
+	job := &model.ImportJob{
+		UserID:     input.UserID,
+		Status:     model.ImportStatusPending,
+		Format:     format,
+		OnConflict: input.OnConflict,
+		FileSize:   size,
+		Errors:     []model.ImportRowError{},
+	}
+	if err := s.jobs.Create(job); err != nil {
+		os.Remove(path)
+		s.release(input.UserID)
+		return nil, err
+	}
+
+	queued := *job
+	go s.run(&queued, path)
+	return job, nil
+}
+
+func (s *importService) GetImport(id, userID uint) (*model.ImportJob, error) {
+	job, err := s.jobs.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrImportNotFound
+		}
+		return nil, err
+	}
+	// Other users' jobs are reported as missing rather than forbidden
+	if job.UserID != userID {
+		return nil, ErrImportNotFound
+	}
+	return job, nil
+}
+
+func (s *importService) FailInterrupted() (int64, error) {
+	return s.jobs.FailUnfinished("interrupted by a server restart; start the import again")
+}
+
+// reserve counts a new unfinished job of the user, refusing it once the user
+// or the server has reached its limit
+func (s *importService) reserve(userID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cfg.MaxPendingPerUser > 0 && s.pending[userID] >= s.cfg.MaxPendingPerUser {
+		return fmt.Errorf("%w: wait for your %d unfinished imports to finish", ErrImportLimit, s.pending[userID])
+	}
+	if s.cfg.MaxPending > 0 && s.pendingJobs >= s.cfg.MaxPending {
+		return fmt.Errorf("%w: the server is busy with other imports, try again later", ErrImportLimit)
+	}
+	s.pending[userID]++
+	s.pendingJobs++
+	return nil
+}
+
+func (s *importService) release(userID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pendingJobs--
+	if s.pending[userID]--; s.pending[userID] <= 0 {
+		delete(s.pending, userID)
+	}
+}
+
+// saveUpload copies the file to the upload directory, enforcing the size limit
+func (s *importService) saveUpload(file io.Reader) (string, int64, error) {
+	if err := os.MkdirAll(s.cfg.UploadDir, 0o700); err != nil {
+		return "", 0, err
+	}
+	tmp, err := os.CreateTemp(s.cfg.UploadDir, "import-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, io.LimitReader(file, s.cfg.MaxFileSize+1))
+	if err == nil && size > s.cfg.MaxFileSize {
+		err = fmt.Errorf("%w: the limit is %d bytes", ErrImportTooLarge, s.cfg.MaxFileSize)
+	}
+	if err == nil && size == 0 {
+		err = fmt.Errorf("%w: the file is empty", ErrInvalidImport)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", 0, err
+	}
+	return tmp.Name(), size, nil
+}
+
+// run imports the file once a worker slot is free, then deletes it
+func (s *importService) run(job *model.ImportJob, path string) {
+	defer s.release(job.UserID)
+	defer os.Remove(path)
+
+	s.slots <- struct{}{}
+	defer func() { <-s.slots }()
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Import %d: panic: %v", job.ID, r)
+			s.finish(job, errors.New("internal error"))
+		}
+	}()
+
+	now := time.Now()
+	job.Status = model.ImportStatusRunning
+	job.StartedAt = &now
+	s.save(job)
+
+	file, err := os.Open(path)
+	if err != nil {
+		s.finish(job, err)
+		return
+	}
+	defer file.Close()
+
+	counter := &countingReader{r: file}
+	reader, err := linkfile.NewReader(counter, job.Format)
+	if err != nil {
+		s.finish(job, err)
+		return
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil && !errors.Is(err, linkfile.ErrInvalidRecord) {
+			s.finish(job, err)
+			return
+		}
+
+		if s.cfg.MaxRows > 0 && job.Rows >= s.cfg.MaxRows {
+			s.finish(job, fmt.Errorf("the file has more than %d rows; rows before line %d were imported", s.cfg.MaxRows, reader.Line()))
+			return
+		}
+		job.Rows++
+
+		if err == nil {
+			err = s.importRecord(job, record)
+		}
+		if err != nil {
+			s.recordError(job, reader.Line(), record.ShortCode, err)
+		}
+
+		if job.Rows%importProgressRows == 0 {
+			s.updateProgress(job, counter.n)
+			s.save(job)
+		}
+	}
+
+	job.BytesRead = job.FileSize
+	s.finish(job, nil)
+}
+
+// importRecord imports one row and counts it as imported or renamed
+func (s *importService) importRecord(job *model.ImportJob, record linkfile.Record) error {
+	if record.Protected && record.Password == "" {
+		return ErrImportPasswordMissing
+	}
+
+	urlEntry, err := s.urls.ImportShortURL(ImportURLInput{
+		CreateURLInput: CreateURLInput{
+			OriginalURL:  record.OriginalURL,
+			UserID:       &job.UserID,
+			ExpiresAt:    record.ExpiresAt,
+			MaxClicks:    record.MaxClicks,
+			Password:     record.Password,
+			RedirectType: record.RedirectType,
+			Tags:         record.Tags,
+		},
+		ShortCode: record.ShortCode,
+		Clicks:    record.Clicks,
+		Disabled:  record.Disabled,
+		CreatedAt: record.CreatedAt,
+		Rename:    job.OnConflict == ImportConflictRename,
+	})
+	if err != nil {
+		return err
+	}
+
+	if record.ShortCode != "" && urlEntry.ShortCode != record.ShortCode {
+		job.Renamed++
+	} else {
+		job.Imported++
+	}
+	return nil
+}
+
+// recordError counts a row that was not imported and keeps its details while
+// there is room
+func (s *importService) recordError(job *model.ImportJob, line int, shortCode string, err error) {
+	code := LinkErrorCode(err)
+	if code == "alias_taken" {
+		job.Conflicts++
+	} else {
+		job.Failed++
+	}
+
+	if len(job.Errors) >= s.cfg.MaxErrors {
+		return
+	}
+	message := err.Error()
+	if code == ErrorCodeInternal {
+		log.Printf("Import %d: line %d: %v", job.ID, line, err)
+		message = "Failed to import link"
+	}
+	job.Errors = append(job.Errors, model.ImportRowError{Line: line, ShortCode: shortCode, ErrorCode: code, Error: message})
+}
+
+// finish records the outcome of a job; a nil err means it completed
+func (s *importService) finish(job *model.ImportJob, err error) {
+	now := time.Now()
+	job.FinishedAt = &now
+	if err != nil {
+		job.Status = model.ImportStatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = model.ImportStatusCompleted
+	}
+	s.updateProgress(job, job.BytesRead)
+	s.save(job)
+
+	log.Printf("Import %d for user %d %s: %d imported, %d renamed, %d conflicts, %d failed",
+		job.ID, job.UserID, job.Status, job.Imported, job.Renamed, job.Conflicts, job.Failed)
+}
+
+func (s *importService) updateProgress(job *model.ImportJob, bytesRead int64) {
+	if bytesRead > job.FileSize {
+		bytesRead = job.FileSize
+	}
+	job.BytesRead = bytesRead
+	if job.FileSize > 0 {
+		job.Progress = int(bytesRead * 100 / job.FileSize)
+	}
+}
+
+func (s *importService) save(job *model.ImportJob) {
+	if err := s.jobs.Update(job); err != nil {
+		log.Printf("Import %d: failed to save progress: %v", job.ID, err)
+	}
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
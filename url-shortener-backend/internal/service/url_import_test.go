@@ -0,0 +1,294 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/config"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+)
+
+func testImportConfig(t *testing.T) config.ImportConfig {
+	return config.ImportConfig{
+		MaxFileSize:       1 << 20,
+		MaxRows:           100,
+		MaxErrors:         10,
+		Workers:           1,
+		MaxPendingPerUser: 2,
+		MaxPending:        3,
+		UploadDir:         t.TempDir(),
+	}
+}
+
+func newTestImportService(t *testing.T, cfg config.ImportConfig) (*importService, URLService) {
+	t.Helper()
+	urls, db := newTestURLService(t, testAnonymousConfig())
+	if err := db.AutoMigrate(&model.ImportJob{}); err != nil {
+		t.Fatal(err)
+	}
+	imports := NewImportService(repository.NewImportJobRepository(db), urls, cfg).(*importService)
+	return imports, urls
+}
+
+// startCSVImport starts an import of a CSV file for the user
+func startCSVImport(imports ImportService, userID uint, onConflict, file string) (*model.ImportJob, error) {
+	return imports.StartImport(ImportInput{
+		UserID:     userID,
+		Format:     "csv",
+		OnConflict: onConflict,
+		File:       strings.NewReader(file),
+	})
+}
+
+// waitForImport polls the job until it has finished
+func waitForImport(t *testing.T, imports ImportService, job *model.ImportJob) *model.ImportJob {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		current, err := imports.GetImport(job.ID, job.UserID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if current.Status == model.ImportStatusCompleted || current.Status == model.ImportStatusFailed {
+			return current
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("import %d did not finish", job.ID)
+	return nil
+}
+
+func TestImportConflicts(t *testing.T) {
+	file := "short_code,original_url\n" +
+		"taken,https://example.com/new\n" +
+		"fresh,https://example.com/fresh\n"
+
+	tests := []struct {
+		onConflict    string
+		wantImported  int
+		wantRenamed   int
+		wantConflicts int
+	}{
+		{"", 1, 0, 1}, // skip is the default
+		{ImportConflictSkip, 1, 0, 1},
+		{ImportConflictRename, 1, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run("on_conflict="+tt.onConflict, func(t *testing.T) {
+			imports, urls := newTestImportService(t, testImportConfig(t))
+			other := uint(2)
+			if _, err := urls.CreateShortURL(CreateURLInput{OriginalURL: "https://example.com/old", CustomAlias: "taken", UserID: &other}); err != nil {
+				t.Fatal(err)
+			}
+
+			job, err := startCSVImport(imports, 1, tt.onConflict, file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if job.Status != model.ImportStatusPending || job.FileSize != int64(len(file)) {
+				t.Errorf("started job: status %s, size %d", job.Status, job.FileSize)
+			}
+
+			job = waitForImport(t, imports, job)
+			if job.Status != model.ImportStatusCompleted || job.Rows != 2 || job.Progress != 100 {
+				t.Fatalf("job = %+v", job)
+			}
+			if job.Imported != tt.wantImported || job.Renamed != tt.wantRenamed || job.Conflicts != tt.wantConflicts {
+				t.Errorf("imported %d, renamed %d, conflicts %d, want %d, %d, %d",
+					job.Imported, job.Renamed, job.Conflicts, tt.wantImported, tt.wantRenamed, tt.wantConflicts)
+			}
+
+			// The taken code still points where it did
+			taken, err := urls.GetByShortCode("taken")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if taken.OriginalURL != "https://example.com/old" {
+				t.Errorf("taken code now points to %s", taken.OriginalURL)
+			}
+
+			if tt.wantConflicts > 0 {
+				if len(job.Errors) != 1 || job.Errors[0].Line != 2 || job.Errors[0].ShortCode != "taken" || job.Errors[0].ErrorCode != "alias_taken" {
+					t.Errorf("errors = %+v", job.Errors)
+				}
+			}
+		})
+	}
+}
+
+func TestImportRows(t *testing.T) {
+	cfg := testImportConfig(t)
+	cfg.MaxRows = 4
+	imports, urls := newTestImportService(t, cfg)
+
+	file := "short_code,original_url,clicks,password_protected,password\n" +
+		"kept,https://example.com/kept,42,,\n" +
+		"bad,https://example.com/bad,many,,\n" +
+		"locked,https://example.com/locked,,true,\n" +
+		"unlocked,https://example.com/unlocked,,true,s3cret\n"
+
+	job := waitForImport(t, imports, mustStart(t, imports, file))
+	if job.Status != model.ImportStatusCompleted || job.Imported != 2 || job.Failed != 2 {
+		t.Fatalf("job = %+v", job)
+	}
+	codes := map[string]string{}
+	for _, rowErr := range job.Errors {
+		codes[rowErr.ShortCode] = rowErr.ErrorCode
+	}
+	if codes["locked"] != "password_required" || codes[""] != "invalid_row" {
+		t.Errorf("row errors = %+v", job.Errors)
+	}
+
+	kept, err := urls.GetByShortCode("kept")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kept.Clicks != 42 || kept.UserID == nil || *kept.UserID != 1 {
+		t.Errorf("imported link = %+v", kept)
+	}
+	unlocked, err := urls.GetByShortCode("unlocked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !unlocked.Protected || unlocked.Password == "s3cret" {
+		t.Errorf("protected link: Protected %v, password stored as given", unlocked.Protected)
+	}
+
+	// A file over the row limit fails, keeping the rows before it
+	long := "original_url\n" + strings.Repeat("https://example.com/\n", 5)
+	job = waitForImport(t, imports, mustStart(t, imports, long))
+	if job.Status != model.ImportStatusFailed || job.Imported != 4 || !strings.Contains(job.Error, "more than 4 rows") {
+		t.Errorf("long file: %+v", job)
+	}
+}
+
+func mustStart(t *testing.T, imports ImportService, file string) *model.ImportJob {
+	t.Helper()
+	job, err := startCSVImport(imports, 1, "", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestStartImportValidation(t *testing.T) {
+	cfg := testImportConfig(t)
+	cfg.MaxFileSize = 64
+	imports, _ := newTestImportService(t, cfg)
+
+	tests := []struct {
+		name  string
+		input ImportInput
+		want  error
+	}{
+		{"unknown conflict mode", ImportInput{Format: "csv", OnConflict: "replace", File: strings.NewReader("x")}, ErrInvalidImport},
+		{"unknown format", ImportInput{Format: "xml", File: strings.NewReader("x")}, ErrInvalidImport},
+		{"empty file", ImportInput{Format: "csv", File: strings.NewReader("")}, ErrInvalidImport},
+		{"file too large", ImportInput{Format: "csv", File: strings.NewReader(strings.Repeat("x", 65))}, ErrImportTooLarge},
+	}
+	for _, tt := range tests {
+		tt.input.UserID = 1
+		if _, err := imports.StartImport(tt.input); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	// Refused uploads do not count as pending
+	if imports.pendingJobs != 0 || len(imports.pending) != 0 {
+		t.Errorf("pending after refused uploads: %d, %v", imports.pendingJobs, imports.pending)
+	}
+
+	// Jobs of other users are not found
+	job := mustStart(t, imports, "original_url\nhttps://example.com\n")
+	if _, err := imports.GetImport(job.ID, 2); !errors.Is(err, ErrImportNotFound) {
+		t.Errorf("job of another user: err = %v, want ErrImportNotFound", err)
+	}
+	waitForImport(t, imports, job)
+}
+
+func TestImportPendingLimits(t *testing.T) {
+	imports, _ := newTestImportService(t, testImportConfig(t))
+	file := "original_url\nhttps://example.com\n"
+
+	// Hold the only worker slot so jobs stay pending
+	imports.slots <- struct{}{}
+
+	var jobs []*model.ImportJob
+	start := func(userID uint) error {
+		job, err := startCSVImport(imports, userID, "", file)
+		if err == nil {
+			jobs = append(jobs, job)
+		}
+		return err
+	}
+
+	// Two unfinished imports per user
+	for i := 0; i < 2; i++ {
+		if err := start(1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := start(1); !errors.Is(err, ErrImportLimit) {
+		t.Fatalf("third import of a user: err = %v, want ErrImportLimit", err)
+	}
+
+	// Three for the whole server
+	if err := start(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := start(3); !errors.Is(err, ErrImportLimit) {
+		t.Fatalf("fourth import of the server: err = %v, want ErrImportLimit", err)
+	}
+
+	// Refused uploads leave no file behind
+	files, err := readUploadDir(imports)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Errorf("%d uploaded files, want 3", len(files))
+	}
+
+	// Finished jobs free their place and delete their file
+	<-imports.slots
+	for _, job := range jobs {
+		if job := waitForImport(t, imports, job); job.Status != model.ImportStatusCompleted {
+			t.Errorf("job %d: %+v", job.ID, job)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		imports.mu.Lock()
+		pending := imports.pendingJobs
+		imports.mu.Unlock()
+		if pending == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d imports still pending", pending)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if files, _ := readUploadDir(imports); len(files) != 0 {
+		t.Errorf("%d uploaded files left after the imports", len(files))
+	}
+	if err := start(1); err != nil {
+		t.Errorf("import after the others finished: %v", err)
+	}
+	waitForImport(t, imports, jobs[len(jobs)-1])
+}
+
+func readUploadDir(imports *importService) ([]string, error) {
+	entries, err := os.ReadDir(imports.cfg.UploadDir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	return names, nil
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+
+	exportBatchSize = 500
+)
+
+var (
+	ErrInvalidCursor    = errors.New("invalid cursor")
+	ErrInvalidListQuery = errors.New("invalid list query")
+)
+
+// URLListQuery selects a page of the caller's links
+type URLListQuery struct {
+	UserID      *uint
+	AnonymousID *string
+	Cursor      string // Opaque next_cursor from the previous page
+	Limit       int    // 0 uses DefaultPageSize
+	SortBy      string // created (default), updated or clicks
+	Ascending   bool
+	Domain      string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Status      string // active, disabled, expired, protected, deleted or taken_down
+	Search      string
+}
+
+// URLPage is one page of a link listing
+type URLPage struct {
+	Total      int64       `json:"total" example:"124"`
+	URLs       []model.URL `json:"urls"`
+	NextCursor string      `json:"next_cursor,omitempty" example:"eyJzIjoiY3JlYXRlZCIsInQiOiIyMDI2LTEwLTE3VDEwOjAwOjAwWiIsImlkIjo0Mn0"`
+	HasMore    bool        `json:"has_more" example:"true"`
+}
+
+// pageCursor is the decoded form of the opaque cursor handed to clients
+type pageCursor struct {
+	SortBy    string    `json:"s"`
+	Ascending bool      `json:"a,omitempty"`
+	Time      time.Time `json:"t,omitempty"`
+	Clicks    int64     `json:"c,omitempty"`
+	ID        uint      `json:"id"`
+}
+
+// ExportURLs passes all of the user's links to fn in batches, oldest first
+func (s *urlService) ExportURLs(userID uint, fn func([]model.URL) error) error {
+	return s.repo.EachByUserID(userID, exportBatchSize, fn)
+}
+
+// ListURLPage returns one page of the owner's links with keyset pagination
+func (s *urlService) ListURLPage(query URLListQuery) (*URLPage, error) {
+	filter, err := listFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := s.repo.Count(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	filter.Limit++
+	urls, err := s.repo.ListPage(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &URLPage{Total: total, URLs: urls}
+	if len(urls) == filter.Limit {
+		page.URLs = urls[:len(urls)-1]
+		page.HasMore = true
+		page.NextCursor = encodeCursor(filter, page.URLs[len(page.URLs)-1])
+	}
+	return page, nil
+}
+
+// listFilter validates a list query and translates it into a repository filter
+func listFilter(query URLListQuery) (repository.URLListFilter, error) {
+	filter := repository.URLListFilter{
+		UserID:      query.UserID,
+		AnonymousID: query.AnonymousID,
+		Domain:      query.Domain,
+		CreatedFrom: query.CreatedFrom,
+		CreatedTo:   query.CreatedTo,
+		Status:      query.Status,
+		Search:      query.Search,
+		SortBy:      query.SortBy,
+		Ascending:   query.Ascending,
+		Limit:       query.Limit,
+	}
+
+	switch filter.SortBy {
+	case "":
+		filter.SortBy = repository.SortCreated
+	case repository.SortCreated, repository.SortUpdated, repository.SortClicks:
+	default:
+		return filter, fmt.Errorf("%w: sort must be created, updated or clicks", ErrInvalidListQuery)
+	}
+
+	switch filter.Status {
+	case "", repository.StatusActive, repository.StatusDisabled, repository.StatusExpired,
+		repository.StatusProtected, repository.StatusDeleted, repository.StatusTakenDown:
+	default:
+		return filter, fmt.Errorf("%w: status must be active, disabled, expired, protected, deleted or taken_down", ErrInvalidListQuery)
+	}
+
+	switch {
+	case filter.Limit == 0:
+		filter.Limit = DefaultPageSize
+	case filter.Limit < 0 || filter.Limit > MaxPageSize:
+		return filter, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidListQuery, MaxPageSize)
+	}
+
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedFrom.Before(*filter.CreatedTo) {
+		return filter, fmt.Errorf("%w: created_from must be before created_to", ErrInvalidListQuery)
+	}
+
+	if query.Cursor != "" {
+		after, err := decodeCursor(query.Cursor, filter)
+		if err != nil {
+			return filter, err
+		}
+		filter.After = after
+	}
+
+	return filter, nil
+}
+
+func encodeCursor(filter repository.URLListFilter, last model.URL) string {
+	cursor := pageCursor{SortBy: filter.SortBy, Ascending: filter.Ascending, ID: last.ID}
+	switch filter.SortBy {
+	case repository.SortClicks:
+		cursor.Clicks = last.Clicks
+	case repository.SortUpdated:
+		cursor.Time = last.UpdatedAt
+	default:
+		cursor.Time = last.CreatedAt
+	}
+
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor parses a cursor and checks it belongs to the same ordering as the query
+func decodeCursor(value string, filter repository.URLListFilter) (*repository.URLCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor pageCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.SortBy != filter.SortBy || cursor.Ascending != filter.Ascending {
+		return nil, fmt.Errorf("%w: cursor was issued for a different sort order", ErrInvalidCursor)
+	}
+
+	return &repository.URLCursor{Time: cursor.Time, Clicks: cursor.Clicks, ID: cursor.ID}, nil
+}
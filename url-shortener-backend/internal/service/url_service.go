@@ -1,82 +1,394 @@
 package service
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"net/http"
 	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"url-shortener/config"
 	"url-shortener/internal/model"
 	"url-shortener/internal/repository"
 
 	gonanoid "github.com/matoous/go-nanoid/v2"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+const (
+	maxTags      = 10
+	maxTagLength = 32
+)
+
+var (
+	ErrInvalidURL    = errors.New("invalid URL format")
+	ErrInvalidAlias  = errors.New("invalid custom alias")
+	ErrAliasReserved = errors.New("custom alias is reserved")
+	ErrAliasTaken    = errors.New("custom alias is already taken")
+	ErrURLNotFound   = errors.New("short URL not found")
+	ErrNotURLOwner   = errors.New("you do not own this short URL")
+	ErrURLGone       = errors.New("short URL is no longer available")
+	ErrURLExpired    = errors.New("short URL has expired")
+	ErrInvalidExpiry = errors.New("invalid expiry")
+
+	ErrInvalidRedirectType = errors.New("redirect_type must be 301, 302, 307 or 308")
+	ErrInvalidTags         = fmt.Errorf("at most %d tags of up to %d characters are allowed", maxTags, maxTagLength)
+
+	ErrLinkPasswordRequired = errors.New("this link is password protected")
+	ErrInvalidLinkPassword  = errors.New("incorrect link password")
+
+	ErrAnonymousQuotaExceeded = errors.New("anonymous link limit reached, sign up or try again later")
+)
+
+// CreateURLInput describes a short link to be created
+type CreateURLInput struct {
+	OriginalURL  string
+	CustomAlias  string // Optional - a random code is generated when empty
+	UserID       *uint
+	AnonymousID  *string
+	ClientIP     string     // Counted against the anonymous per-IP quota
+	ExpiresAt    *time.Time // Optional - link stops redirecting after this time
+	MaxClicks    *int64     // Optional - link stops redirecting after this many clicks
+	Password     string     // Optional - visitors must enter it before being redirected
+	RedirectType int        // Optional - 0 uses the server default
+	Tags         []string   // Optional - lower-cased and de-duplicated
+}
+
+// UpdateURLInput holds the mutable fields of a short link; nil fields are left unchanged
+type UpdateURLInput struct {
+	OriginalURL  *string
+	Disabled     *bool
+	ExpiresAt    *time.Time
+	MaxClicks    *int64
+	Password     *string // Empty string removes the password
+	RedirectType *int    // 0 switches back to the server default
+	Tags         *[]string
+}
+
+// RedirectAccess carries the credentials a visitor presented for a password-protected link
+type RedirectAccess struct {
+	Password string // Typed into the unlock form or sent by an API client
+	// Unlock checks the visitor's unlock cookie against the link's current
+	// password hash; nil when the visitor has no cookie
+	Unlock func(passwordHash string) bool
+}
+
 type URLService interface {
-	CreateShortURL(originalURL string, userID *uint, anonymousID *string) (*model.URL, error)
+	CreateShortURL(input CreateURLInput) (*model.URL, error)
+	CreateShortURLs(userID uint, items []CreateURLInput, mode string) ([]BatchItemResult, error)
 	GetByShortCode(code string) (*model.URL, error)
-	RedirectAndCount(code string) (string, error)
-	ListURLs() ([]model.URL, error)
+	UpdateURL(code string, userID uint, input UpdateURLInput) (*model.URL, error)
+	DeleteURL(code string, userID uint) error
+	RestoreURL(code string, userID uint) (*model.URL, error)
+	RedirectAndCount(code string, access RedirectAccess) (*model.URL, error)
+	RedirectStatus(urlEntry *model.URL) int
+	TakedownURL(code, reason string) (*model.URL, error)
+	LiftTakedown(code string) (*model.URL, error)
 	ListUserURLs(userID uint) ([]model.URL, error)
 	ListAnonymousURLs(anonymousID string) ([]model.URL, error)
-	ClaimAnonymousURLs(userID uint, anonymousID string) error
+	ListURLPage(query URLListQuery) (*URLPage, error)
+	ExportURLs(userID uint, fn func([]model.URL) error) error
+	ImportShortURL(input ImportURLInput) (*model.URL, error)
+	MarkExpiredURLs() (int64, error)
+	PurgeExpiredURLs(retention time.Duration) (int64, error)
+	PurgeExpiredAnonymousURLs() (int64, error)
+	PreviewClaim(anonymousID string) (*ClaimPreview, error)
+	ClaimAnonymousURLs(input ClaimInput) (*ClaimResult, error)
+	ListLinkClaims(query LinkClaimQuery) (*LinkClaimPage, error)
 }
 
 type urlService struct {
-	repo repository.URLRepository
+	repo   repository.URLRepository
+	claims repository.LinkClaimRepository
+	cfg    config.URLConfig
+	anon   config.AnonymousConfig
+	clicks ClickCounter
 }
 
-func NewURLService(repo repository.URLRepository) URLService {
-	return &urlService{repo: repo}
+func NewURLService(repo repository.URLRepository, claims repository.LinkClaimRepository, cfg config.URLConfig, anon config.AnonymousConfig, clicks ClickCounter) URLService {
+	return &urlService{repo: repo, claims: claims, cfg: cfg, anon: anon, clicks: clicks}
 }
 
-func (s *urlService) CreateShortURL(originalURL string, userID *uint, anonymousID *string) (*model.URL, error) {
-	// Validate URL
-	if !isValidURL(originalURL) {
-		return nil, errors.New("invalid URL format")
+func (s *urlService) CreateShortURL(input CreateURLInput) (*model.URL, error) {
+	urlEntry, err := s.prepareURL(input, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	// Generate unique short code
-	shortCode, err := s.generateUniqueCode()
+	if err := s.repo.Create(urlEntry); err != nil {
+		return nil, s.insertError(urlEntry, input.CustomAlias, err)
+	}
+
+	return urlEntry, nil
+}
+
+// prepareURL validates a new link and picks its short code without saving it.
+// Codes in pending are treated as taken, so links created together cannot
+// collide with each other.
+func (s *urlService) prepareURL(input CreateURLInput, pending map[string]bool) (*model.URL, error) {
+	// Validate URL
+	if !isValidURL(input.OriginalURL) {
+		return nil, ErrInvalidURL
+	}
+	if err := validateExpiry(input.ExpiresAt, input.MaxClicks, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := validateRedirectType(input.RedirectType); err != nil {
+		return nil, err
+	}
+	tags, err := normalizeTags(input.Tags)
 	if err != nil {
 		return nil, err
 	}
 
+	anonymous := input.UserID == nil && input.AnonymousID != nil
+	var ipHash string
+	if anonymous {
+		ipHash = s.hashCreatorIP(input.ClientIP)
+		if err := s.checkAnonymousQuota(*input.AnonymousID, ipHash); err != nil {
+			return nil, err
+		}
+	}
+
+	var shortCode string
+	if input.CustomAlias != "" {
+		if err := s.validateAlias(input.CustomAlias); err != nil {
+			return nil, err
+		}
+		if pending[input.CustomAlias] {
+			return nil, ErrDuplicateAlias
+		}
+		if _, err := s.repo.FindByShortCodeWithDeleted(input.CustomAlias); err == nil {
+			return nil, ErrAliasTaken
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		shortCode = input.CustomAlias
+	} else {
+		// Generate unique short code
+		shortCode, err = s.generateUniqueCode(pending)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Create new URL entry with ownership
 	urlEntry := &model.URL{
-		ShortCode:   shortCode,
-		OriginalURL: originalURL,
-		UserID:      userID,
-		AnonymousID: anonymousID,
-		Clicks:      0,
+		ShortCode:    shortCode,
+		OriginalURL:  input.OriginalURL,
+		UserID:       input.UserID,
+		AnonymousID:  input.AnonymousID,
+		Clicks:       0,
+		ExpiresAt:    input.ExpiresAt,
+		MaxClicks:    input.MaxClicks,
+		RedirectType: input.RedirectType,
+		Tags:         tags,
 	}
-
-	if err := s.repo.Create(urlEntry); err != nil {
+	if anonymous {
+		urlEntry.CreatorIPHash = ipHash
+		urlEntry.CreatorAnonID = *input.AnonymousID
+		s.applyAnonymousTTL(urlEntry, time.Now())
+	}
+	if err := setLinkPassword(urlEntry, input.Password); err != nil {
 		return nil, err
 	}
 
 	return urlEntry, nil
 }
 
+// insertError explains a failed insert. Another request may have taken the
+// alias between the check and the insert.
+func (s *urlService) insertError(urlEntry *model.URL, customAlias string, err error) error {
+	if customAlias != "" {
+		if _, findErr := s.repo.FindByShortCodeWithDeleted(urlEntry.ShortCode); findErr == nil {
+			return ErrAliasTaken
+		}
+	}
+	return err
+}
+
 func (s *urlService) GetByShortCode(code string) (*model.URL, error) {
 	return s.repo.FindByShortCode(code)
 }
 
-func (s *urlService) RedirectAndCount(code string) (string, error) {
-	urlEntry, err := s.repo.FindByShortCode(code)
+func (s *urlService) UpdateURL(code string, userID uint, input UpdateURLInput) (*model.URL, error) {
+	urlEntry, err := s.findOwnedURL(code, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.OriginalURL != nil {
+		if !isValidURL(*input.OriginalURL) {
+			return nil, ErrInvalidURL
+		}
+		urlEntry.OriginalURL = *input.OriginalURL
+	}
+	if input.Disabled != nil {
+		urlEntry.Disabled = *input.Disabled
+	}
+	if input.ExpiresAt != nil || input.MaxClicks != nil {
+		now := time.Now()
+		if err := validateExpiry(input.ExpiresAt, input.MaxClicks, now); err != nil {
+			return nil, err
+		}
+		if input.ExpiresAt != nil {
+			urlEntry.ExpiresAt = input.ExpiresAt
+		}
+		if input.MaxClicks != nil {
+			urlEntry.MaxClicks = input.MaxClicks
+		}
+		// Extending an expired link brings it back to life
+		if !isExpired(urlEntry, now) {
+			urlEntry.ExpiredAt = nil
+		}
+	}
+	if input.Password != nil {
+		if err := setLinkPassword(urlEntry, *input.Password); err != nil {
+			return nil, err
+		}
+	}
+	if input.RedirectType != nil {
+		if err := validateRedirectType(*input.RedirectType); err != nil {
+			return nil, err
+		}
+		urlEntry.RedirectType = *input.RedirectType
+	}
+	if input.Tags != nil {
+		tags, err := normalizeTags(*input.Tags)
+		if err != nil {
+			return nil, err
+		}
+		urlEntry.Tags = tags
+	}
+
+	if err := s.repo.Update(urlEntry); err != nil {
+		return nil, err
+	}
+
+	return urlEntry, nil
+}
+
+func (s *urlService) DeleteURL(code string, userID uint) error {
+	urlEntry, err := s.findOwnedURL(code, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.Delete(urlEntry)
+}
+
+func (s *urlService) RestoreURL(code string, userID uint) (*model.URL, error) {
+	urlEntry, err := s.repo.FindByShortCodeWithDeleted(code)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", errors.New("short URL not found")
+			return nil, ErrURLNotFound
 		}
-		return "", err
+		return nil, err
 	}
 
-	// Increment click count asynchronously
-	go s.repo.IncrementClicks(code)
+	if !isOwner(urlEntry, userID) {
+		return nil, ErrNotURLOwner
+	}
+
+	if urlEntry.DeletedAt.Valid {
+		if err := s.repo.Restore(urlEntry); err != nil {
+			return nil, err
+		}
+	}
 
-	return urlEntry.OriginalURL, nil
+	return urlEntry, nil
 }
 
-func (s *urlService) ListURLs() ([]model.URL, error) {
-	return s.repo.List()
+func (s *urlService) RedirectAndCount(code string, access RedirectAccess) (*model.URL, error) {
+	// Deleted links are looked up too so retired codes can be told apart from typos
+	urlEntry, err := s.repo.FindByShortCodeWithDeleted(code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrURLNotFound
+		}
+		return nil, err
+	}
+
+	if urlEntry.DeletedAt.Valid || urlEntry.Disabled || urlEntry.TakenDownAt != nil {
+		return nil, ErrURLGone
+	}
+
+	now := time.Now()
+	if urlEntry.ExpiredAt != nil || isExpired(urlEntry, now) {
+		return nil, ErrURLExpired
+	}
+
+	// Locked visits are not counted as clicks
+	if urlEntry.Protected && (access.Unlock == nil || !access.Unlock(urlEntry.Password)) {
+		if access.Password == "" {
+			return nil, ErrLinkPasswordRequired
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(urlEntry.Password), []byte(access.Password)); err != nil {
+			return nil, ErrInvalidLinkPassword
+		}
+	}
+
+	// Links with a click budget must count the click and check the budget in
+	// one statement so concurrent visitors cannot overshoot it. Expiry dates,
+	// which every anonymous link has, were already checked above.
+	if urlEntry.MaxClicks != nil {
+		accepted, err := s.repo.ConsumeClick(code, now)
+		if err != nil {
+			return nil, err
+		}
+		if !accepted {
+			return nil, ErrURLExpired
+		}
+		return urlEntry, nil
+	}
+
+	// Other links are counted in batches by the click aggregator
+	s.clicks.Add(code)
+
+	return urlEntry, nil
+}
+
+// RedirectStatus returns the HTTP status used to redirect visitors of a link
+func (s *urlService) RedirectStatus(urlEntry *model.URL) int {
+	if urlEntry.RedirectType != 0 {
+		return urlEntry.RedirectType
+	}
+	return s.cfg.DefaultRedirectType
+}
+
+// TakedownURL blocks a link for every visitor, regardless of its owner's settings
+func (s *urlService) TakedownURL(code, reason string) (*model.URL, error) {
+	urlEntry, err := s.findURL(code)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	urlEntry.TakenDownAt = &now
+	urlEntry.TakedownReason = reason
+	if err := s.repo.Update(urlEntry); err != nil {
+		return nil, err
+	}
+	return urlEntry, nil
+}
+
+func (s *urlService) LiftTakedown(code string) (*model.URL, error) {
+	urlEntry, err := s.findURL(code)
+	if err != nil {
+		return nil, err
+	}
+
+	urlEntry.TakenDownAt = nil
+	urlEntry.TakedownReason = ""
+	if err := s.repo.Update(urlEntry); err != nil {
+		return nil, err
+	}
+	return urlEntry, nil
 }
 
 func (s *urlService) ListUserURLs(userID uint) ([]model.URL, error) {
@@ -87,21 +399,118 @@ func (s *urlService) ListAnonymousURLs(anonymousID string) ([]model.URL, error)
 	return s.repo.ListByAnonymousID(anonymousID)
 }
 
-func (s *urlService) ClaimAnonymousURLs(userID uint, anonymousID string) error {
-	return s.repo.ClaimAnonymousURLs(userID, anonymousID)
+func (s *urlService) MarkExpiredURLs() (int64, error) {
+	codes, err := s.repo.MarkExpired(time.Now())
+	return int64(len(codes)), err
+}
+
+func (s *urlService) PurgeExpiredURLs(retention time.Duration) (int64, error) {
+	codes, err := s.repo.PurgeExpired(time.Now().Add(-retention))
+	return int64(len(codes)), err
+}
+
+// PurgeExpiredAnonymousURLs deletes unclaimed anonymous links once they have
+// been expired for the configured grace period
+func (s *urlService) PurgeExpiredAnonymousURLs() (int64, error) {
+	codes, err := s.repo.PurgeExpiredAnonymous(time.Now().Add(-s.anon.PurgeAfter))
+	return int64(len(codes)), err
+}
+
+// checkAnonymousQuota rejects a new anonymous link once the identity or the
+// client IP created the configured number of links within the quota window
+func (s *urlService) checkAnonymousQuota(anonymousID, ipHash string) error {
+	since := time.Now().Add(-s.anon.QuotaWindow)
+
+	if s.anon.MaxLinksPerID > 0 {
+		count, err := s.repo.CountByAnonymousIDSince(anonymousID, since)
+		if err != nil {
+			return err
+		}
+		if count >= int64(s.anon.MaxLinksPerID) {
+			return ErrAnonymousQuotaExceeded
+		}
+	}
+
+	if s.anon.MaxLinksPerIP > 0 && ipHash != "" {
+		count, err := s.repo.CountByCreatorIPSince(ipHash, since)
+		if err != nil {
+			return err
+		}
+		if count >= int64(s.anon.MaxLinksPerIP) {
+			return ErrAnonymousQuotaExceeded
+		}
+	}
+	return nil
+}
+
+// applyAnonymousTTL caps the link's expiry at the anonymous link TTL. An
+// earlier expiry chosen by the visitor is kept and survives claiming.
+func (s *urlService) applyAnonymousTTL(urlEntry *model.URL, now time.Time) {
+	if s.anon.LinkTTL <= 0 {
+		return
+	}
+	deadline := now.Add(s.anon.LinkTTL)
+	if urlEntry.ExpiresAt == nil || urlEntry.ExpiresAt.After(deadline) {
+		urlEntry.ExpiresAt = &deadline
+		urlEntry.AnonymousTTL = true
+	}
+}
+
+// hashCreatorIP keys the IP hash with the server secret, so stored hashes
+// cannot be reversed by trying every address
+func (s *urlService) hashCreatorIP(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(s.anon.Secret))
+	mac.Write([]byte("creator-ip:" + ip))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// findURL loads a short link, mapping a missing row to ErrURLNotFound
+func (s *urlService) findURL(code string) (*model.URL, error) {
+	urlEntry, err := s.repo.FindByShortCode(code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrURLNotFound
+		}
+		return nil, err
+	}
+	return urlEntry, nil
+}
+
+// findOwnedURL loads a short link and checks that it belongs to the given user
+func (s *urlService) findOwnedURL(code string, userID uint) (*model.URL, error) {
+	urlEntry, err := s.findURL(code)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isOwner(urlEntry, userID) {
+		return nil, ErrNotURLOwner
+	}
+
+	return urlEntry, nil
+}
+
+func isOwner(urlEntry *model.URL, userID uint) bool {
+	return urlEntry.UserID != nil && *urlEntry.UserID == userID
 }
 
-// generateUniqueCode generates a unique short code
-func (s *urlService) generateUniqueCode() (string, error) {
+// generateUniqueCode generates a unique short code that is not in pending
+func (s *urlService) generateUniqueCode(pending map[string]bool) (string, error) {
 	maxRetries := 5
 	for i := 0; i < maxRetries; i++ {
 		code, err := gonanoid.New(8)
 		if err != nil {
 			return "", err
 		}
+		if pending[code] {
+			continue
+		}
 
-		// Check if code already exists
-		_, err = s.repo.FindByShortCode(code)
+		// Check if code already exists, including deleted links which keep their code
+		_, err = s.repo.FindByShortCodeWithDeleted(code)
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return code, nil
 		}
@@ -109,6 +518,102 @@ func (s *urlService) generateUniqueCode() (string, error) {
 	return "", errors.New("failed to generate unique code")
 }
 
+// validateAlias checks a custom alias against the configured length, charset and reserved words
+func (s *urlService) validateAlias(alias string) error {
+	if len(alias) < s.cfg.AliasMinLength || len(alias) > s.cfg.AliasMaxLength {
+		return fmt.Errorf("%w: must be between %d and %d characters", ErrInvalidAlias, s.cfg.AliasMinLength, s.cfg.AliasMaxLength)
+	}
+
+	for _, r := range alias {
+		if !strings.ContainsRune(s.cfg.AliasCharset, r) {
+			return fmt.Errorf("%w: character %q is not allowed", ErrInvalidAlias, r)
+		}
+	}
+
+	for _, reserved := range s.cfg.ReservedAliases {
+		if strings.EqualFold(alias, reserved) {
+			return ErrAliasReserved
+		}
+	}
+
+	return nil
+}
+
+// setLinkPassword hashes and stores a link password; an empty password makes the link public
+func setLinkPassword(urlEntry *model.URL, password string) error {
+	if password == "" {
+		urlEntry.Password = ""
+		urlEntry.Protected = false
+		return nil
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	urlEntry.Password = string(hashedPassword)
+	urlEntry.Protected = true
+	return nil
+}
+
+// validateRedirectType accepts the redirect statuses a link may use; 0 means the server default
+func validateRedirectType(status int) error {
+	switch status {
+	case 0, http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return nil
+	default:
+		return ErrInvalidRedirectType
+	}
+}
+
+// validateExpiry checks optional expiry settings
+func validateExpiry(expiresAt *time.Time, maxClicks *int64, now time.Time) error {
+	if expiresAt != nil && !expiresAt.After(now) {
+		return fmt.Errorf("%w: expires_at must be in the future", ErrInvalidExpiry)
+	}
+	if maxClicks != nil && *maxClicks <= 0 {
+		return fmt.Errorf("%w: max_clicks must be positive", ErrInvalidExpiry)
+	}
+	return nil
+}
+
+// normalizeTags lower-cases and de-duplicates tags, dropping empty ones
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, ErrInvalidTags
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxTags {
+		return nil, ErrInvalidTags
+	}
+	if len(normalized) == 0 {
+		return nil, nil
+	}
+	return normalized, nil
+}
+
+// isExpired reports whether a link is past its expiry date or click budget
+func isExpired(urlEntry *model.URL, now time.Time) bool {
+	if urlEntry.ExpiresAt != nil && !urlEntry.ExpiresAt.After(now) {
+		return true
+	}
+	return urlEntry.MaxClicks != nil && urlEntry.Clicks >= *urlEntry.MaxClicks
+}
+
 // isValidURL validates if the string is a valid URL
 func isValidURL(str string) bool {
 	u, err := url.Parse(str)
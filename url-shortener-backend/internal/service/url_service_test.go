@@ -0,0 +1,225 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"url-shortener/config"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&model.URL{}, &model.ClickEvent{}, &model.LinkClaim{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// noClicks drops counted clicks
+type noClicks struct{}
+
+func (noClicks) Add(string) {}
+
+func testAnonymousConfig() config.AnonymousConfig {
+	return config.AnonymousConfig{
+		Secret:        "test-secret",
+		IdentityTTL:   time.Hour,
+		QuotaWindow:   time.Hour,
+		MaxLinksPerID: 2,
+		MaxLinksPerIP: 3,
+		LinkTTL:       24 * time.Hour,
+		PurgeAfter:    time.Hour,
+	}
+}
+
+func newTestURLService(t *testing.T, anon config.AnonymousConfig) (URLService, *gorm.DB) {
+	t.Helper()
+	db := newTestDB(t)
+	urls := NewURLService(repository.NewURLRepository(db), repository.NewLinkClaimRepository(db),
+		config.LoadURLConfig(), anon, noClicks{})
+	return urls, db
+}
+
+func createAnonymousLink(urls URLService, anonymousID, clientIP string) (*model.URL, error) {
+	return urls.CreateShortURL(CreateURLInput{
+		OriginalURL: "https://example.com/" + anonymousID,
+		AnonymousID: &anonymousID,
+		ClientIP:    clientIP,
+	})
+}
+
+func TestAnonymousQuotas(t *testing.T) {
+	urls, db := newTestURLService(t, testAnonymousConfig())
+
+	// Two links per identity
+	for i := 0; i < 2; i++ {
+		if _, err := createAnonymousLink(urls, "anon_a", "10.0.0.1"); err != nil {
+			t.Fatalf("link %d: %v", i+1, err)
+		}
+	}
+	if _, err := createAnonymousLink(urls, "anon_a", "10.0.0.2"); !errors.Is(err, ErrAnonymousQuotaExceeded) {
+		t.Fatalf("third link of an identity: err = %v, want ErrAnonymousQuotaExceeded", err)
+	}
+
+	// Three links per IP, whichever identity creates them
+	if _, err := createAnonymousLink(urls, "anon_b", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createAnonymousLink(urls, "anon_c", "10.0.0.1"); !errors.Is(err, ErrAnonymousQuotaExceeded) {
+		t.Fatalf("fourth link of an IP: err = %v, want ErrAnonymousQuotaExceeded", err)
+	}
+
+	// Deleting a link does not give its quota back
+	db.Where("anonymous_id = ?", "anon_b").Delete(&model.URL{})
+	if _, err := createAnonymousLink(urls, "anon_c", "10.0.0.1"); !errors.Is(err, ErrAnonymousQuotaExceeded) {
+		t.Fatalf("after a delete: err = %v, want ErrAnonymousQuotaExceeded", err)
+	}
+
+	// Links created before the window no longer count
+	db.Unscoped().Model(&model.URL{}).Where("1 = 1").Update("created_at", time.Now().Add(-2*time.Hour))
+	if _, err := createAnonymousLink(urls, "anon_a", "10.0.0.1"); err != nil {
+		t.Fatalf("after the window: %v", err)
+	}
+
+	// Signed-in users are not limited
+	owner := uint(1)
+	for i := 0; i < 4; i++ {
+		if _, err := urls.CreateShortURL(CreateURLInput{OriginalURL: "https://example.com/", UserID: &owner, ClientIP: "10.0.0.1"}); err != nil {
+			t.Fatalf("user link %d: %v", i+1, err)
+		}
+	}
+}
+
+func TestAnonymousQuotasDisabled(t *testing.T) {
+	anon := testAnonymousConfig()
+	anon.MaxLinksPerID = 0
+	anon.MaxLinksPerIP = 0
+	urls, _ := newTestURLService(t, anon)
+
+	for i := 0; i < 5; i++ {
+		if _, err := createAnonymousLink(urls, "anon_a", "10.0.0.1"); err != nil {
+			t.Fatalf("link %d: %v", i+1, err)
+		}
+	}
+}
+
+func TestAnonymousLinkTTL(t *testing.T) {
+	anon := testAnonymousConfig()
+	anon.MaxLinksPerID = 0
+	urls, _ := newTestURLService(t, anon)
+	anonymousID := "anon_a"
+
+	// Without an expiry the link gets the TTL
+	before := time.Now()
+	link, err := createAnonymousLink(urls, anonymousID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.ExpiresAt == nil || !link.AnonymousTTL {
+		t.Fatalf("ExpiresAt = %v, AnonymousTTL = %v", link.ExpiresAt, link.AnonymousTTL)
+	}
+	if got := link.ExpiresAt.Sub(before); got < 24*time.Hour || got > 24*time.Hour+time.Minute {
+		t.Errorf("expires %s after creation, want 24h", got)
+	}
+
+	// A later expiry is capped at the TTL
+	later := time.Now().Add(48 * time.Hour)
+	link, err = urls.CreateShortURL(CreateURLInput{OriginalURL: "https://example.com/", AnonymousID: &anonymousID, ExpiresAt: &later})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !link.ExpiresAt.Before(later) || !link.AnonymousTTL {
+		t.Errorf("later expiry: ExpiresAt = %v, AnonymousTTL = %v", link.ExpiresAt, link.AnonymousTTL)
+	}
+
+	// An earlier expiry is kept and is not the TTL
+	sooner := time.Now().Add(time.Hour).Truncate(time.Second)
+	link, err = urls.CreateShortURL(CreateURLInput{OriginalURL: "https://example.com/", AnonymousID: &anonymousID, ExpiresAt: &sooner})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !link.ExpiresAt.Equal(sooner) || link.AnonymousTTL {
+		t.Errorf("earlier expiry: ExpiresAt = %v, AnonymousTTL = %v", link.ExpiresAt, link.AnonymousTTL)
+	}
+
+	// Links of signed-in users get no TTL
+	owner := uint(1)
+	link, err = urls.CreateShortURL(CreateURLInput{OriginalURL: "https://example.com/", UserID: &owner})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.ExpiresAt != nil || link.AnonymousTTL {
+		t.Errorf("user link: ExpiresAt = %v, AnonymousTTL = %v", link.ExpiresAt, link.AnonymousTTL)
+	}
+}
+
+func TestPurgeExpiredAnonymousURLs(t *testing.T) {
+	urls, db := newTestURLService(t, testAnonymousConfig())
+	now := time.Now()
+	anonymousID := "anon_a"
+	owner := uint(1)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	links := []model.URL{
+		{ShortCode: "purged", AnonymousID: &anonymousID, ExpiresAt: at(-2 * time.Hour)},
+		{ShortCode: "marked", AnonymousID: &anonymousID, ExpiresAt: at(time.Hour), ExpiredAt: at(-2 * time.Hour)},
+		{ShortCode: "in-grace", AnonymousID: &anonymousID, ExpiresAt: at(-30 * time.Minute)},
+		{ShortCode: "active", AnonymousID: &anonymousID, ExpiresAt: at(time.Hour)},
+		{ShortCode: "claimed", AnonymousID: &anonymousID, UserID: &owner, ExpiresAt: at(-2 * time.Hour)},
+		{ShortCode: "user", UserID: &owner, ExpiresAt: at(-2 * time.Hour)},
+	}
+	for i := range links {
+		links[i].OriginalURL = "https://example.com/"
+		if err := db.Create(&links[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Create(&model.ClickEvent{ShortCode: "purged", CreatedAt: now})
+	db.Create(&model.ClickEvent{ShortCode: "active", CreatedAt: now})
+
+	purged, err := urls.PurgeExpiredAnonymousURLs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 2 {
+		t.Errorf("purged %d links, want 2", purged)
+	}
+
+	var codes []string
+	db.Unscoped().Model(&model.URL{}).Order("short_code").Pluck("short_code", &codes)
+	want := []string{"active", "claimed", "in-grace", "user"}
+	if len(codes) != len(want) {
+		t.Fatalf("remaining links = %v, want %v", codes, want)
+	}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Fatalf("remaining links = %v, want %v", codes, want)
+		}
+	}
+
+	var events []string
+	db.Model(&model.ClickEvent{}).Pluck("short_code", &events)
+	if len(events) != 1 || events[0] != "active" {
+		t.Errorf("remaining click events = %v, want [active]", events)
+	}
+}
@@ -0,0 +1,89 @@
+package service
+
+import "strings"
+
+// userAgentInfo is the coarse classification of a User-Agent header used for analytics
+type userAgentInfo struct {
+	Browser    string
+	OS         string
+	DeviceType string
+}
+
+var botMarkers = []string{"bot", "crawler", "spider", "slurp", "facebookexternalhit", "preview", "curl", "wget", "python-requests", "go-http-client"}
+
+// parseUserAgent classifies a User-Agent string. Order matters: many browsers
+// include the tokens of the engines they are derived from.
+func parseUserAgent(ua string) userAgentInfo {
+	lower := strings.ToLower(ua)
+	if lower == "" {
+		return userAgentInfo{Browser: "Unknown", OS: "Unknown", DeviceType: "unknown"}
+	}
+
+	info := userAgentInfo{
+		Browser:    parseBrowser(lower),
+		OS:         parseOS(lower),
+		DeviceType: "desktop",
+	}
+
+	switch {
+	case containsAny(lower, botMarkers...):
+		info.DeviceType = "bot"
+	case containsAny(lower, "ipad", "tablet") || (strings.Contains(lower, "android") && !strings.Contains(lower, "mobile")):
+		info.DeviceType = "tablet"
+	case containsAny(lower, "mobile", "iphone", "ipod", "windows phone"):
+		info.DeviceType = "mobile"
+	}
+
+	return info
+}
+
+func parseBrowser(ua string) string {
+	switch {
+	case containsAny(ua, botMarkers...):
+		return "Bot"
+	case strings.Contains(ua, "edg/"), strings.Contains(ua, "edga/"), strings.Contains(ua, "edgios/"):
+		return "Edge"
+	case strings.Contains(ua, "opr/"), strings.Contains(ua, "opera"):
+		return "Opera"
+	case strings.Contains(ua, "samsungbrowser/"):
+		return "Samsung Internet"
+	case strings.Contains(ua, "firefox/"), strings.Contains(ua, "fxios/"):
+		return "Firefox"
+	case strings.Contains(ua, "chrome/"), strings.Contains(ua, "crios/"):
+		return "Chrome"
+	case strings.Contains(ua, "safari/"):
+		return "Safari"
+	case strings.Contains(ua, "msie "), strings.Contains(ua, "trident/"):
+		return "Internet Explorer"
+	default:
+		return "Other"
+	}
+}
+
+func parseOS(ua string) string {
+	switch {
+	case strings.Contains(ua, "windows"):
+		return "Windows"
+	case containsAny(ua, "iphone", "ipad", "ipod"):
+		return "iOS"
+	case strings.Contains(ua, "android"):
+		return "Android"
+	case strings.Contains(ua, "cros"):
+		return "ChromeOS"
+	case strings.Contains(ua, "mac os x"), strings.Contains(ua, "macintosh"):
+		return "macOS"
+	case strings.Contains(ua, "linux"):
+		return "Linux"
+	default:
+		return "Other"
+	}
+}
+
+func containsAny(s string, substrs ...string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
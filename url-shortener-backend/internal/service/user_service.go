@@ -2,6 +2,9 @@ package service
 
 import (
 	"errors"
+	"fmt"
+	"log"
+	"url-shortener/config"
 	"url-shortener/internal/model"
 	"url-shortener/internal/repository"
 
@@ -9,11 +12,43 @@ import (
 	"gorm.io/gorm"
 )
 
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrAccountDisabled    = errors.New("this account has been disabled")
+	ErrInvalidRole        = errors.New("role must be user or admin")
+	ErrCannotModifySelf   = errors.New("admins cannot disable or demote their own account")
+)
+
+// UserListQuery selects a page of users for the admin API
+type UserListQuery struct {
+	Search   string
+	Role     string
+	Disabled *bool
+	Limit    int // 0 uses DefaultPageSize
+	Offset   int
+}
+
+// UserPage is one page of a user listing
+type UserPage struct {
+	Total int64        `json:"total" example:"42"`
+	Users []model.User `json:"users"`
+}
+
+// UpdateUserAccessInput changes what a user may do; nil fields are left unchanged
+type UpdateUserAccessInput struct {
+	Role     *string
+	Disabled *bool
+}
+
 type UserService interface {
 	Register(username, email, password string) (*model.User, error)
 	Login(username, password string) (*model.User, error)
 	GetByID(id uint) (*model.User, error)
 	ValidateCredentials(username, password string) (*model.User, error)
+	ListUsers(query UserListQuery) (*UserPage, error)
+	UpdateUserAccess(actorID, userID uint, input UpdateUserAccessInput) (*model.User, error)
+	BootstrapAdmin(cfg config.AdminConfig) error
 }
 
 type userService struct {
@@ -28,7 +63,7 @@ func (s *userService) Register(username, email, password string) (*model.User, e
 	// Check if user already exists
 	_, err := s.repo.FindByUsername(username)
 	if err == nil {
-		return nil, errors.New("username already exists")
+		return nil, ErrUsernameTaken
 	}
 
 	// Hash password
@@ -41,6 +76,7 @@ func (s *userService) Register(username, email, password string) (*model.User, e
 		Username: username,
 		Email:    email,
 		Password: string(hashedPassword),
+		Role:     model.RoleUser,
 	}
 
 	if err := s.repo.Create(user); err != nil {
@@ -54,14 +90,18 @@ func (s *userService) Login(email, password string) (*model.User, error) {
 	user, err := s.repo.FindByEmail(email)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("invalid credentials")
+			return nil, ErrInvalidCredentials
 		}
 		return nil, err
 	}
 
 	// Compare password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, ErrInvalidCredentials
+	}
+
+	if user.Disabled {
+		return nil, ErrAccountDisabled
 	}
 
 	return user, nil
@@ -74,3 +114,113 @@ func (s *userService) GetByID(id uint) (*model.User, error) {
 func (s *userService) ValidateCredentials(username, password string) (*model.User, error) {
 	return s.Login(username, password)
 }
+
+func (s *userService) ListUsers(query UserListQuery) (*UserPage, error) {
+	switch {
+	case query.Limit == 0:
+		query.Limit = DefaultPageSize
+	case query.Limit < 0 || query.Limit > MaxPageSize:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidListQuery, MaxPageSize)
+	}
+	if query.Offset < 0 {
+		return nil, fmt.Errorf("%w: offset must not be negative", ErrInvalidListQuery)
+	}
+	if query.Role != "" && !isValidRole(query.Role) {
+		return nil, ErrInvalidRole
+	}
+
+	users, total, err := s.repo.List(repository.UserListFilter{
+		Search:   query.Search,
+		Role:     query.Role,
+		Disabled: query.Disabled,
+		Limit:    query.Limit,
+		Offset:   query.Offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &UserPage{Total: total, Users: users}, nil
+}
+
+// UpdateUserAccess changes a user's role or disables the account. actorID is
+// the admin making the change, who cannot lock themselves out.
+func (s *userService) UpdateUserAccess(actorID, userID uint, input UpdateUserAccessInput) (*model.User, error) {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	fields := map[string]interface{}{}
+	if input.Role != nil {
+		if !isValidRole(*input.Role) {
+			return nil, ErrInvalidRole
+		}
+		if actorID == userID && *input.Role != model.RoleAdmin {
+			return nil, ErrCannotModifySelf
+		}
+		user.Role = *input.Role
+		fields["role"] = user.Role
+	}
+	if input.Disabled != nil {
+		if actorID == userID && *input.Disabled {
+			return nil, ErrCannotModifySelf
+		}
+		user.Disabled = *input.Disabled
+		fields["disabled"] = user.Disabled
+	}
+
+	if len(fields) == 0 {
+		return user, nil
+	}
+	if err := s.repo.Update(user.ID, fields); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// BootstrapAdmin makes sure the configured account exists and is an admin.
+// It does nothing when no admin email is configured.
+func (s *userService) BootstrapAdmin(cfg config.AdminConfig) error {
+	if cfg.Email == "" {
+		return nil
+	}
+
+	user, err := s.repo.FindByEmail(cfg.Email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if user == nil {
+		if cfg.Password == "" {
+			log.Printf("Admin bootstrap: no user with email %s yet, set ADMIN_PASSWORD to create it", cfg.Email)
+			return nil
+		}
+		if user, err = s.Register(cfg.Username, cfg.Email, cfg.Password); err != nil {
+			return fmt.Errorf("creating admin account: %w", err)
+		}
+		user.Role = model.RoleAdmin
+		if err := s.repo.Update(user.ID, map[string]interface{}{"role": user.Role}); err != nil {
+			return err
+		}
+		log.Printf("Admin bootstrap: created admin account %s", cfg.Email)
+		return nil
+	}
+
+	if user.Role == model.RoleAdmin && !user.Disabled {
+		return nil
+	}
+	user.Role = model.RoleAdmin
+	user.Disabled = false
+	if err := s.repo.Update(user.ID, map[string]interface{}{"role": user.Role, "disabled": user.Disabled}); err != nil {
+		return err
+	}
+	log.Printf("Admin bootstrap: %s is now an admin", cfg.Email)
+	return nil
+}
+
+func isValidRole(role string) bool {
+	return role == model.RoleUser || role == model.RoleAdmin
+}